@@ -4,9 +4,12 @@ require (
 	github.com/gobuffalo/packr/v2 v2.0.0-rc.13
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/magefile/mage v1.7.1
+	github.com/mattn/go-sqlite3 v1.10.0
+	github.com/montanaflynn/stats v0.5.0
 	github.com/oklog/ulid v1.3.1
 	github.com/pkg/errors v0.8.0
 	go.opencensus.io v0.18.0
+	golang.org/x/crypto v0.0.0-20181203042331-505ab145d0a9
 	golang.org/x/net v0.0.0-20181220203305-927f97764cc3 // indirect
 	golang.org/x/sync v0.0.0-20181221193216-37e7f081c4d4 // indirect
 	golang.org/x/sys v0.0.0-20181221143128-b4a75ba826a6 // indirect
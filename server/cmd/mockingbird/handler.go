@@ -2,27 +2,85 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
 
 	"github.com/unders/mockingbird/server/domain/mockingbird"
 
 	"github.com/pkg/errors"
+	"github.com/unders/mockingbird/server/pkg/auth"
+	"github.com/unders/mockingbird/server/pkg/errs"
+	mw "github.com/unders/mockingbird/server/pkg/handler"
 	"github.com/unders/mockingbird/server/pkg/rest"
+	"github.com/unders/mockingbird/server/pkg/signature"
 )
 
 type handler struct {
-	Favicon       func(r *http.Request) (http.Handler, bool)
-	notAuthorized func(w http.ResponseWriter, r *http.Request) bool
-	Assets        http.FileSystem
-	AssetsPrefix  string
-	HTML          mockingbird.HTMLAdapter
-	Log           mockingbird.Log
+	Favicon      func(r *http.Request) (http.Handler, bool)
+	Assets       http.FileSystem
+	AssetsPrefix string
+	HTML         mockingbird.HTMLAdapter
+	JSON         mockingbird.JSONAdapter
+	Logs         mockingbird.LogStream
+	Log          mockingbird.Log
+
+	// Auth authenticates the users with Basic auth and Limiter rate limits
+	// their failed logins; all requests are allowed when Auth is nil, e.g:
+	// on a local dev server
+	Auth    auth.Authenticator
+	Limiter *auth.Limiter
+
+	// DeploySecret is the shared secret of the deploy webhook; the webhook
+	// is disabled when it is empty
+	DeploySecret []byte
+
+	// Metrics serves the metrics in the Prometheus text format; GET /metrics
+	// is not found when it is nil
+	Metrics http.Handler
 }
 
+// adapter is implemented by mockingbird.HTMLAdapter and mockingbird.JSONAdapter
+type adapter interface {
+	Dashboard() (code int, body []byte, err error)
+	ListTests(q mockingbird.ListTestsQuery) (code int, body []byte, err error)
+	ShowTest(id mockingbird.ULID) (code int, body []byte, err error)
+	ShowTestSuites() (code int, body []byte, err error)
+
+	RunTest(testSuite mockingbird.TestSuite, user string) (id mockingbird.ULID, code int, body []byte, err error)
+	CancelTest(id mockingbird.ULID) (code int, body []byte, err error)
+
+	ShowDeliveries() (code int, body []byte, err error)
+
+	ErrorNotFound() (body []byte)
+	InvalidURL() (body []byte)
+	InternalError() (body []byte)
+}
+
+// createHandler returns the routes of h in the middleware chain: each request
+// gets a request ID, its latency is measured, it is written to the access log
+// and a panic renders the internal server error page
 func createHandler(h handler) http.Handler {
+	return mw.Chain(routes(h),
+		mw.RequestID,
+		mw.Timer,
+		mw.AccessLog(h.logRequest),
+		mw.Recover(h.internalError),
+	)
+}
+
+// routes returns the handler that serves the assets and routes the requests
+func routes(h handler) http.Handler {
 	router := rest.Router{}
 
 	assets := "/public/"
@@ -30,33 +88,31 @@ func createHandler(h handler) http.Handler {
 		assets = h.AssetsPrefix
 	}
 
-	if h.notAuthorized == nil {
-		h.notAuthorized = notAuthorized
-	}
-
 	fs := http.StripPrefix(assets, http.FileServer(h.Assets))
 
 	f := func(w http.ResponseWriter, req *http.Request) {
-		if h.isJSON(req) {
-			h.jsonNotImplemented(w, req)
-			return
-		}
 		if strings.HasPrefix(req.URL.Path, assets) {
 			fs.ServeHTTP(w, req)
 			return
 		}
 
-		if h.notAuthorized(w, req) {
-			return
+		// webhooks are authenticated by their signature, and the metrics are
+		// scraped without credentials
+		if !isHook(req) && !isMetrics(req) {
+			var ok bool
+			if req, ok = h.authenticate(w, req); !ok {
+				return
+			}
 		}
 
 		//
-		// All other Content-Type's are treated as HTML.
+		// Requests that accept application/json are answered by the JSON
+		// adapter; all other Content-Type's are treated as HTML.
 		//
 
 		path, route, err := router.New(req)
 		if err != nil {
-			h.write(w, req, http.StatusBadRequest, h.HTML.InvalidURL(), err)
+			h.write(w, req, http.StatusBadRequest, h.adapter(req).InvalidURL(), err)
 			return
 		}
 
@@ -75,20 +131,39 @@ func createHandler(h handler) http.Handler {
 			h.showTest(w, req, path)
 		case rest.Route{Method: http.MethodGet, Path: "/tests/*/suites"}:
 			h.showTestSuites(w, req, path)
+		case rest.Route{Method: http.MethodGet, Path: "/tests/*/notifications"}:
+			h.showDeliveries(w, req)
+		case rest.Route{Method: http.MethodGet, Path: "/metrics"}:
+			h.showMetrics(w, req)
+		case rest.Route{Method: http.MethodGet, Path: "/tests/*/log/*"}:
+			if path.String(3, "") != "stream" {
+				err := errors.New("route not found")
+				h.write(w, req, http.StatusNotFound, h.adapter(req).ErrorNotFound(), err)
+				return
+			}
+			h.streamLog(w, req, path)
 			//
 			// POST
 			//
 		case rest.Route{Method: http.MethodPost, Path: "/tests"}:
 			h.runTest(w, req)
+		case rest.Route{Method: http.MethodPost, Path: "/tests/*/cancel"}:
+			h.cancelTest(w, req, path)
+		case rest.Route{Method: http.MethodPost, Path: "/hooks/*"}:
+			if path.String(1, "") != "deploy" {
+				err := errors.New("route not found")
+				h.write(w, req, http.StatusNotFound, h.adapter(req).ErrorNotFound(), err)
+				return
+			}
+			h.runDeploy(w, req)
 		default:
 			if favicon, found := h.Favicon(req); found {
-				h.logRequest(req, http.StatusOK, nil)
 				favicon.ServeHTTP(w, req)
 				return
 			}
 
 			err := errors.New("route not found")
-			h.write(w, req, http.StatusNotFound, h.HTML.ErrorNotFound(), err)
+			h.write(w, req, http.StatusNotFound, h.adapter(req).ErrorNotFound(), err)
 		}
 	}
 
@@ -96,62 +171,279 @@ func createHandler(h handler) http.Handler {
 }
 
 //
-// HTML Handlers
+// Handlers
 //
 
-func notAuthorized(w http.ResponseWriter, r *http.Request) bool {
-	user, pass, _ := r.BasicAuth()
+// authenticate returns the request with the username of its Basic auth in
+// the context; it writes 401 Unauthorized, or 429 Too Many Requests when the
+// IP address has failed to log in too many times, and returns false when the
+// user is not authenticated
+func (h *handler) authenticate(w http.ResponseWriter, req *http.Request) (*http.Request, bool) {
+	if h.Auth == nil {
+		return req, true
+	}
+
+	ip := clientIP(req)
+	if h.Limiter != nil {
+		if ok, wait := h.Limiter.Allow(ip); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too Many Requests.", http.StatusTooManyRequests)
+			mw.SetError(req, errors.Errorf("too many failed logins from %s", ip))
+			return req, false
+		}
+	}
 
-	if "user" != user || "password" != pass {
+	user, pass, ok := req.BasicAuth()
+	if !ok || !h.Auth.Authenticate(user, pass) {
+		if ok && h.Limiter != nil {
+			h.Limiter.Fail(ip)
+		}
 		w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
 		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
-		return true
+		if ok {
+			mw.SetError(req, errors.Errorf("invalid password of user %s", user))
+		}
+		return req, false
 	}
-	return false
+
+	if h.Limiter != nil {
+		h.Limiter.Reset(ip)
+	}
+	return req.WithContext(auth.WithUser(req.Context(), user)), true
+}
+
+// clientIP returns the IP address of the client that sent the request; the
+// X-Forwarded-For header is not used, since any client can set it
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
 }
 
 //
 func (h *handler) showDashboard(w http.ResponseWriter, req *http.Request) {
-	code, b, err := h.HTML.Dashboard()
+	code, b, err := h.adapter(req).Dashboard()
 	h.write(w, req, code, b, err)
 }
 
 func (h *handler) listTests(w http.ResponseWriter, req *http.Request) {
-	code, b, err := h.HTML.ListTests(req.URL.Query().Get("page_token"))
+	q, err := mockingbird.ParseListTestsQuery(req.URL.Query())
+	if err != nil {
+		h.write(w, req, http.StatusBadRequest, h.adapter(req).InvalidURL(), err)
+		return
+	}
+
+	code, b, err := h.adapter(req).ListTests(q)
 	h.write(w, req, code, b, err)
 }
 
+// showTest returns the test result; JSON responses have an ETag, so a
+// poller that sends it back in If-None-Match gets 304 Not Modified until
+// the test result changes
 func (h *handler) showTest(w http.ResponseWriter, req *http.Request, path rest.Path) {
 	id := path.String(1, "")
-	code, b, err := h.HTML.ShowTest(mockingbird.ULID(id))
+	code, b, err := h.adapter(req).ShowTest(mockingbird.ULID(id))
+
+	if err == nil && h.isJSON(req) {
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(b))
+		w.Header().Set("ETag", etag)
+		if matchETag(req.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
 
 	h.write(w, req, code, b, err)
 }
 
 func (h *handler) showTestSuites(w http.ResponseWriter, req *http.Request, path rest.Path) {
-	code, b, err := h.HTML.ShowTestSuites()
+	code, b, err := h.adapter(req).ShowTestSuites()
 	h.write(w, req, code, b, err)
 }
 
+func (h *handler) showDeliveries(w http.ResponseWriter, req *http.Request) {
+	code, b, err := h.adapter(req).ShowDeliveries()
+	h.write(w, req, code, b, err)
+}
+
+func (h *handler) showMetrics(w http.ResponseWriter, req *http.Request) {
+	if h.Metrics == nil {
+		err := errors.New("metrics are not enabled")
+		h.write(w, req, http.StatusNotFound, h.adapter(req).ErrorNotFound(), err)
+		return
+	}
+
+	h.Metrics.ServeHTTP(w, req)
+}
+
 func (h *handler) runTest(w http.ResponseWriter, req *http.Request) {
-	ts := req.FormValue("test_suite")
-	id, code, body, err := h.HTML.RunTest(mockingbird.TestSuite(ts))
+	ts := h.testSuite(req)
+	id, code, body, err := h.adapter(req).RunTest(mockingbird.TestSuite(ts), auth.UserFrom(req.Context()))
 	if err != nil {
 		h.write(w, req, code, body, err)
 		return
 	}
 
+	location := fmt.Sprintf("/tests/%s", id)
+	if h.isJSON(req) {
+		w.Header().Set("Location", location)
+		w.Header().Set("Retry-After", retryAfter)
+		h.write(w, req, code, body, nil)
+		return
+	}
+	http.Redirect(w, req, location, http.StatusSeeOther)
+}
+
+func (h *handler) cancelTest(w http.ResponseWriter, req *http.Request, path rest.Path) {
+	id := path.String(1, "")
+	code, body, err := h.adapter(req).CancelTest(mockingbird.ULID(id))
+	if err != nil || h.isJSON(req) {
+		h.write(w, req, code, body, err)
+		return
+	}
+
 	http.Redirect(w, req, fmt.Sprintf("/tests/%s", id), http.StatusSeeOther)
 }
 
 //
-// HTML Writer
+// Webhooks
+//
+
+// runDeploy runs the test suites of a deployment
+//
+// Note:
+//
+//        The unix time and the body must be signed with HMAC-SHA256 and
+//        the shared secret, see package signature. A request that was
+//        signed more than signature.Tolerance ago is rejected, so it can
+//        not be replayed later, e.g:
+//
+//            X-Mockingbird-Timestamp: 1546398245
+//            X-Mockingbird-Signature: sha256=5d2c...
+//
+func (h *handler) runDeploy(w http.ResponseWriter, req *http.Request) {
+	if len(h.DeploySecret) == 0 {
+		err := errors.New("deploy webhook is disabled")
+		h.write(w, req, http.StatusNotFound, h.JSON.ErrorNotFound(), err)
+		return
+	}
+
+	payload, err := ioutil.ReadAll(io.LimitReader(req.Body, maxHookPayload))
+	if err != nil {
+		h.write(w, req, http.StatusBadRequest, h.JSON.InvalidURL(), errors.Wrap(err, "read payload failed"))
+		return
+	}
+
+	timestamp, sig := req.Header.Get(signature.TimestampHeader), req.Header.Get(signature.Header)
+	if !signature.Valid(h.DeploySecret, payload, timestamp, sig, time.Now()) {
+		http.Error(w, "Unauthorized.", http.StatusUnauthorized)
+		mw.SetError(req, errors.New("invalid signature"))
+		return
+	}
+
+	code, body, err := h.JSON.RunDeploy(payload)
+	h.write(w, req, code, body, err)
+}
+
+const (
+	hooksPrefix = "/hooks/"
+
+	// maxHookPayload is the max size of a webhook payload in bytes
+	maxHookPayload = 1 << 20
+)
+
+func isHook(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, hooksPrefix)
+}
+
+func isMetrics(req *http.Request) bool {
+	return req.Method == http.MethodGet && req.URL.Path == "/metrics"
+}
+
+//
+// Server-Sent Events
+//
+
+// streamLog streams the log of a test suite as text/event-stream
+//
+// Note:
+//
+//        Each event carries complete log lines, one per data field, and
+//        its id is the byte offset after them; a reconnecting client
+//        sends it back in the Last-Event-ID header and resumes there.
+//        The stream ends with a "done" event.
+//
+func (h *handler) streamLog(w http.ResponseWriter, req *http.Request, path rest.Path) {
+	id := mockingbird.ULID(path.String(1, ""))
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		err := errors.New("streaming not supported")
+		h.write(w, req, http.StatusInternalServerError, h.adapter(req).InternalError(), err)
+		return
+	}
+
+	offset, _ := strconv.Atoi(req.Header.Get("Last-Event-ID"))
+
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	err := h.Logs.FollowLog(req.Context(), id, offset, func(chunk string, offset int) error {
+		start()
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "id: %d\n", offset)
+		for _, line := range strings.Split(strings.TrimSuffix(chunk, "\n"), "\n") {
+			fmt.Fprintf(&b, "data: %s\n", line)
+		}
+		b.WriteString("\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return errors.Wrap(err, "io.WriteString(w, event) failed")
+		}
+		flusher.Flush()
+		return nil
+	})
+
+	if !started && err != nil {
+		if errs.IsNotFound(err) {
+			h.write(w, req, http.StatusNotFound, h.adapter(req).ErrorNotFound(), err)
+			return
+		}
+		h.write(w, req, http.StatusInternalServerError, h.adapter(req).InternalError(), err)
+		return
+	}
+	if err == nil {
+		start()
+		_, err = io.WriteString(w, "event: done\ndata: done\n\n")
+		flusher.Flush()
+	}
+
+	if err != nil && req.Context().Err() == nil {
+		h.logResponseFailure(req, http.StatusOK, err)
+	}
+}
+
+//
+// Writer
 //
 
 func (h *handler) write(w http.ResponseWriter, req *http.Request, code int, buf []byte, err error) {
 	body := bytes.NewReader(buf)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if h.isJSON(req) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
 	if err != nil {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 	}
@@ -163,72 +455,108 @@ func (h *handler) write(w http.ResponseWriter, req *http.Request, code int, buf
 		return
 	}
 
-	h.logRequest(req, code, err)
+	if err != nil {
+		mw.SetError(req, err)
+	}
+}
+
+// internalError writes the internal server error page of a panic
+func (h *handler) internalError(w http.ResponseWriter, req *http.Request, err error) {
+	h.write(w, req, http.StatusInternalServerError, h.adapter(req).InternalError(), err)
 }
 
 //
-// JSON
+// Content negotiation
 //
 
+const jsonFormat = "application/json"
+
+// retryAfter is the seconds an API client should wait before it polls a queued test result
+const retryAfter = "5"
+
+// isJSON returns true if the request wants JSON; webhooks are always answered with JSON
 func (h *handler) isJSON(req *http.Request) bool {
-	const jsonFormat = "application/json"
 	accept := req.Header.Get("Accept")
 	ct := req.Header.Get("Content-Type")
 
-	return ct == jsonFormat || accept == jsonFormat
+	return strings.HasPrefix(ct, jsonFormat) || strings.Contains(accept, jsonFormat) || isHook(req)
 }
 
-func (h *handler) jsonNotImplemented(w http.ResponseWriter, req *http.Request) {
-	const code = http.StatusNotImplemented
-	var status = http.StatusText(code)
-	const msg = "JSON API not implemented by the server."
-
-	const format = `{"error": { "code": %d, "status": "%s", "message": "%s" } }`
-	b := []byte(fmt.Sprintf(format, code, status, msg))
-	err := errors.Errorf(format, code, status, msg)
-	h.writeJSON(w, req, code, b, err)
+func (h *handler) adapter(req *http.Request) adapter {
+	if h.isJSON(req) {
+		return h.JSON
+	}
+	return h.HTML
 }
 
-func (h *handler) writeJSON(w http.ResponseWriter, req *http.Request, code int, buf []byte, err error) {
-	body := bytes.NewReader(buf)
-
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	if err != nil {
-		w.Header().Set("X-Content-Type-Options", "nosniff")
+// matchETag returns true if the If-None-Match header has the etag
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
 	}
+	return false
+}
 
-	w.WriteHeader(code)
-	if _, err := io.Copy(w, body); err != nil {
-		err = errors.Wrap(err, "io.Copy(w, body) failed")
-		h.logResponseFailure(req, code, err)
-		return
+// testSuite returns the test suite from a JSON body, e.g: {"test_suite": "all:test"},
+// or from the query string or form
+func (h *handler) testSuite(req *http.Request) string {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), jsonFormat) {
+		return req.FormValue("test_suite")
 	}
 
-	h.logRequest(req, code, err)
+	body := struct {
+		TestSuite string `json:"test_suite"`
+	}{}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		return req.URL.Query().Get("test_suite")
+	}
+	return body.TestSuite
 }
 
 //
 // General request logging
 //
 func (h *handler) logResponseFailure(req *http.Request, code int, err error) {
-	msg := http.StatusText(code)
-	const format = "%s %s    response error=%s    [%d %s]"
-	h.Log.Error(fmt.Sprintf(format, req.Method, req.URL.String(), err, code, msg))
+	fields := append(requestFields(req, code), mockingbird.KV("error", err))
+	h.Log.Error("response failed", fields...)
 }
 
-func (h *handler) logRequest(req *http.Request, code int, err error) {
-	msg := http.StatusText(code)
-	if err != nil {
-		if code < http.StatusInternalServerError {
-			const format = "%s %s    <-    %d %s    notice=%s"
-			h.Log.Notice(fmt.Sprintf(format, req.Method, req.URL.String(), code, msg, err))
+// logRequest writes the request to the access log
+func (h *handler) logRequest(req *http.Request, resp mw.Response) {
+	const format = "%s %s    <-    %d %s"
+	msg := fmt.Sprintf(format, req.Method, req.URL.Path, resp.Code, http.StatusText(resp.Code))
+	fields := append(requestFields(req, resp.Code),
+		mockingbird.KV("size", resp.Size),
+		mockingbird.KV("latency", resp.Latency),
+	)
+
+	if resp.Err != nil {
+		if resp.Code < http.StatusInternalServerError {
+			h.Log.Notice(msg, append(fields, mockingbird.KV("notice", resp.Err))...)
 			return
 		}
 
-		const format = "%s %s    <-    %d %s    error=%s"
-		h.Log.Error(fmt.Sprintf(format, req.Method, req.URL.String(), code, msg, err))
+		h.Log.Error(msg, append(fields, mockingbird.KV("error", resp.Err))...)
 		return
 	}
-	const format = "%s %s    <-    %d %s"
-	h.Log.Info(fmt.Sprintf(format, req.Method, req.URL.String(), code, msg))
+	h.Log.Info(msg, fields...)
+}
+
+// requestFields returns the log fields of the request and its response code
+func requestFields(req *http.Request, code int) []mockingbird.Field {
+	fields := make([]mockingbird.Field, 0, 8)
+	if id := mw.RequestIDFrom(req.Context()); id != "" {
+		fields = append(fields, mockingbird.KV("request_id", id))
+	}
+	if span := trace.FromContext(req.Context()); span != nil {
+		fields = append(fields, mockingbird.KV("trace_id", span.SpanContext().TraceID.String()))
+	}
+	fields = append(fields, mockingbird.KV("method", req.Method), mockingbird.KV("path", req.URL.Path))
+	if req.URL.RawQuery != "" {
+		fields = append(fields, mockingbird.KV("query", req.URL.RawQuery))
+	}
+	return append(fields, mockingbird.KV("status", code))
 }
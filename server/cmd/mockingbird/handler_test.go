@@ -1,19 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/unders/mockingbird/server/domain/mockingbird/mock"
 
+	"github.com/unders/mockingbird/server/pkg/auth"
+	mw "github.com/unders/mockingbird/server/pkg/handler"
+	"github.com/unders/mockingbird/server/pkg/signature"
 	"github.com/unders/mockingbird/server/pkg/testdata"
 
 	"github.com/unders/mockingbird/server/domain/mockingbird"
@@ -21,31 +28,57 @@ import (
 
 func TestAPI(t *testing.T) {
 	// TODO: Add test for favicons
-	t.Run("GET  /    When Content-Type=application/json    ReturnsError", getRootAsJSON)
+	t.Run("GET  /    When Accept=application/json    RedirectsTo  /dashboard", getRootAsJSON)
 	t.Run("GET  /    RedirectsTo  /dashboard", rootPathRedirectsToDashboard)
 	t.Run("GET  /dashboard    ReturnsDashboardPage", getDashboard)
 	t.Run("POST  /dashboard    ReturnsErrorRouteNotFound", postDashboard)
 
 	t.Run("POST  /tests    StartsATestSuite    Redirects", postTests)
 	t.Run("POST  /tests    WhenServerError    ReturnsError", postTestsWhenServerError)
+	t.Run("POST  /tests/{id}/cancel    CancelsATestSuite    Redirects", postCancelTest)
 
 	t.Run("GET  /tests/{id}    ReturnsTestResultPage", getTest)
 	t.Run("GET  /tests/    ReturnsTestResultListPage", getTestResults)
 	t.Run("GET  /tests/-/suites    ReturnsTestSuitesPage", getTestSuites)
+	t.Run("GET  /tests/-/notifications    ReturnsTheDeliveryLog", getDeliveries)
+	t.Run("GET  /tests/{id}/log/stream    ReturnsEventStream", getLogStream)
+	t.Run("GET  /metrics    ReturnsTheMetrics", getMetrics)
+	t.Run("GET  /metrics    When basic auth is enabled    ReturnsTheMetricsWithoutCredentials", getMetricsWithoutCredentials)
+	t.Run("GET  /tests    When X-Request-ID    LogsTheRequestFields", getTestsLogsTheRequestFields)
+	t.Run("GET  /tests    When the handler panics    ReturnsInternalError", getTestsWhenPanic)
+
+	t.Run("GET  /tests/{id}    When Accept=application/json    ReturnsTestResult", getTestAsJSON)
+	t.Run("GET  /tests/{id}    When If-None-Match=ETag    ReturnsNotModified", getTestAsJSONWhenNotModified)
+	t.Run("POST  /tests    When Content-Type=application/json    ReturnsTestResult", postTestsAsJSON)
+
+	t.Run("POST  /hooks/deploy    VerifiesTheSignature    RunsTheDeployTestSuites", postDeployHook)
+
+	t.Run("POST  /tests    AuthenticatesTheUser    RunsTheTestSuiteAsTheUser", postTestsAuthenticated)
 }
 
 func testServer(html mockingbird.HTMLAdapter) *httptest.Server {
 	h := createHandler(handler{
-		Favicon:       func(r *http.Request) (http.Handler, bool) { return nil, false },
-		notAuthorized: func(w http.ResponseWriter, r *http.Request) bool { return false },
-		HTML:          html,
-		Log:           &mock.Log{},
+		Favicon:      func(r *http.Request) (http.Handler, bool) { return nil, false },
+		HTML:         html,
+		JSON:         mock.JSONAdapter{Code: http.StatusOK},
+		Logs:         mock.LogStream{Chunks: []string{"=== RUN TestSearch\n", "--- PASS: TestSearch\nPASS\n"}},
+		Log:          &mock.Log{},
+		DeploySecret: []byte(testDeploySecret),
+		Metrics:      http.HandlerFunc(serveTestMetrics),
 	})
 
 	ts := httptest.NewServer(h)
 	return ts
 }
 
+const testMetrics = "# TYPE mockingbird_queue_depth gauge\nmockingbird_queue_depth 2\n"
+
+func serveTestMetrics(w http.ResponseWriter, req *http.Request) {
+	_, _ = io.WriteString(w, testMetrics)
+}
+
+const testDeploySecret = "secret"
+
 func getRootAsJSON(t *testing.T) {
 	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("")})
 	defer ts.Close()
@@ -58,9 +91,9 @@ func getRootAsJSON(t *testing.T) {
 	}{
 		{
 			URL:            ts.URL + "/",
-			wantCode:       http.StatusNotImplemented,
-			wantBody:       []byte(`{"error": { "code": 501, "status": "Not Implemented", "message": "JSON API not implemented by the server." } }`),
-			wantRequestURL: "/",
+			wantCode:       http.StatusOK,
+			wantBody:       []byte(`{"mock":"dashboard"}`),
+			wantRequestURL: "/dashboard",
 		},
 	}
 
@@ -324,6 +357,48 @@ func postTestsWhenServerError(t *testing.T) {
 	}
 }
 
+func postCancelTest(t *testing.T) {
+	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("Redirects to ")})
+	defer ts.Close()
+
+	testCases := []struct {
+		URL            string
+		wantCode       int
+		wantBody       []byte
+		wantRequestURL string
+	}{
+		{
+			URL:            ts.URL + "/tests/an-test-suite-id/cancel",
+			wantCode:       http.StatusOK,
+			wantBody:       []byte("Redirects to test result page for id=an-test-suite-id"),
+			wantRequestURL: "/tests/an-test-suite-id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			resp, err := http.Post(tc.URL, "text/html", strings.NewReader(""))
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d", tc.wantCode, resp.StatusCode)
+			}
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if !reflect.DeepEqual(tc.wantBody, b) {
+				t.Errorf("\nWant: %s\n Got: %s\n", string(tc.wantBody), string(b))
+			}
+
+			got := resp.Request.URL.RequestURI()
+			if tc.wantRequestURL != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantRequestURL, got)
+			}
+		})
+	}
+}
+
 func getTest(t *testing.T) {
 	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
 	defer ts.Close()
@@ -388,6 +463,18 @@ func getTestResults(t *testing.T) {
 			wantBody:       []byte("body: list test result page"),
 			wantRequestURL: "/tests/",
 		},
+		{
+			URL:            ts.URL + "/tests/?suite=google:test&state=failed&from=2019-01-28",
+			wantCode:       http.StatusOK,
+			wantBody:       []byte("body: list test result page"),
+			wantRequestURL: "/tests/?suite=google:test&state=failed&from=2019-01-28",
+		},
+		{
+			URL:            ts.URL + "/tests/?page_size=ten",
+			wantCode:       http.StatusBadRequest,
+			wantBody:       []byte("body: Invalid URL page"),
+			wantRequestURL: "/tests/?page_size=ten",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -461,3 +548,551 @@ func getTestSuites(t *testing.T) {
 		})
 	}
 }
+
+func getDeliveries(t *testing.T) {
+	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
+	defer ts.Close()
+
+	testCases := []struct {
+		accept   string
+		wantCode int
+		wantBody string
+	}{
+		{accept: "text/html", wantCode: http.StatusOK, wantBody: "body: notifications page"},
+		{accept: "application/json", wantCode: http.StatusOK, wantBody: `{"mock":"notifications"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.accept, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/tests/-/notifications/", nil)
+			testdata.AssertNil(t, err)
+			req.Header.Set("Accept", tc.accept)
+
+			resp, err := http.DefaultClient.Do(req)
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d\n", tc.wantCode, resp.StatusCode)
+			}
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if got := string(b); tc.wantBody != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func getMetrics(t *testing.T) {
+	enabled := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
+	defer enabled.Close()
+
+	disabled := httptest.NewServer(createHandler(handler{
+		Favicon: func(r *http.Request) (http.Handler, bool) { return nil, false },
+		HTML:    mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")},
+		JSON:    mock.JSONAdapter{Code: http.StatusOK},
+		Log:     &mock.Log{},
+	}))
+	defer disabled.Close()
+
+	testCases := []struct {
+		name     string
+		URL      string
+		wantCode int
+		wantBody string
+	}{
+		{name: "enabled", URL: enabled.URL + "/metrics", wantCode: http.StatusOK, wantBody: testMetrics},
+		{name: "disabled", URL: disabled.URL + "/metrics", wantCode: http.StatusNotFound, wantBody: "body: Not Found"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(tc.URL)
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d\n", tc.wantCode, resp.StatusCode)
+			}
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if got := string(b); tc.wantBody != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+func getMetricsWithoutCredentials(t *testing.T) {
+	ts := httptest.NewServer(createHandler(handler{
+		Favicon: func(r *http.Request) (http.Handler, bool) { return nil, false },
+		HTML:    mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")},
+		JSON:    mock.JSONAdapter{Code: http.StatusOK},
+		Log:     &mock.Log{},
+		Auth:    users{"alice": "password"},
+		Limiter: auth.NewLimiter(1, time.Minute),
+		Metrics: http.HandlerFunc(serveTestMetrics),
+	}))
+	defer ts.Close()
+
+	// a scraper with wrong credentials must not lock itself out
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/metrics", nil)
+		testdata.AssertNil(t, err)
+		if i > 0 {
+			req.SetBasicAuth("alice", "guess")
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		testdata.AssertNil(t, err)
+
+		b, err := ioutil.ReadAll(resp.Body)
+		testdata.AssertNil(t, err)
+		testdata.AssertNil(t, resp.Body.Close())
+
+		if http.StatusOK != resp.StatusCode {
+			t.Errorf("\nWant: %d\n Got: %d\n", http.StatusOK, resp.StatusCode)
+		}
+		if got := string(b); testMetrics != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", testMetrics, got)
+		}
+	}
+
+	// the other pages still require credentials
+	resp, err := http.Get(ts.URL + "/tests")
+	testdata.AssertNil(t, err)
+	testdata.AssertNil(t, resp.Body.Close())
+	if http.StatusUnauthorized != resp.StatusCode {
+		t.Errorf("\nWant: %d\n Got: %d\n", http.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func getTestsLogsTheRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	ts := httptest.NewServer(createHandler(handler{
+		Favicon: func(r *http.Request) (http.Handler, bool) { return nil, false },
+		HTML:    mock.HTMLAdapter{Code: http.StatusInternalServerError, Body: []byte("body: "), Err: errors.New("store failed")},
+		JSON:    mock.JSONAdapter{Code: http.StatusOK},
+		Log:     &mockingbird.JSONLogger{Log: log.New(&buf, "", 0)},
+	}))
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/tests?page=2", nil)
+	testdata.AssertNil(t, err)
+	req.Header.Set("X-Request-ID", "8a7f")
+	resp, err := http.DefaultClient.Do(req)
+	testdata.AssertNil(t, err)
+	testdata.AssertNil(t, resp.Body.Close())
+	ts.Close() // waits for the handler, so the request is logged
+
+	if id := resp.Header.Get("X-Request-ID"); "8a7f" != id {
+		t.Errorf("\nWant: 8a7f\n Got: %s\n", id)
+	}
+
+	got := map[string]interface{}{}
+	testdata.AssertNil(t, json.Unmarshal(buf.Bytes(), &got))
+
+	want := map[string]interface{}{
+		"severity":   "ERROR",
+		"message":    "GET /tests    <-    500 Internal Server Error",
+		"request_id": "8a7f",
+		"method":     "GET",
+		"path":       "/tests",
+		"query":      "page=2",
+		"status":     float64(500),
+		"size":       float64(len("body: list test result page with error")),
+		"error":      "store failed",
+	}
+	for key, w := range want {
+		if got[key] != w {
+			t.Errorf("%s\nWant: %v\n Got: %v\n", key, w, got[key])
+		}
+	}
+	if _, ok := got["latency"].(string); !ok {
+		t.Errorf("\nWant: latency\n Got: %v\n", got)
+	}
+}
+
+func getTestsWhenPanic(t *testing.T) {
+	h := handler{
+		HTML: mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")},
+		JSON: mock.JSONAdapter{Code: http.StatusOK},
+		Log:  &mock.Log{},
+	}
+	panics := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { panic("boom") })
+	ts := httptest.NewServer(mw.Chain(panics, mw.RequestID, mw.AccessLog(h.logRequest), mw.Recover(h.internalError)))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/tests")
+	testdata.AssertNil(t, err)
+	defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+	if http.StatusInternalServerError != resp.StatusCode {
+		t.Errorf("\nWant: %d\n Got: %d\n", http.StatusInternalServerError, resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	testdata.AssertNil(t, err)
+	if want, got := "body: Internal Error page", string(b); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	if id := resp.Header.Get("X-Request-ID"); len(id) != 36 {
+		t.Errorf("\nWant: a UUID\n Got: %s\n", id)
+	}
+}
+
+func getLogStream(t *testing.T) {
+	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
+	defer ts.Close()
+
+	testCases := []struct {
+		URL             string
+		wantCode        int
+		wantContentType string
+		wantBody        []byte
+	}{
+		{
+			URL:             ts.URL + "/tests/an-test-suite-id/log/stream",
+			wantCode:        http.StatusOK,
+			wantContentType: "text/event-stream",
+			wantBody: []byte("id: 19\ndata: === RUN TestSearch\n\n" +
+				"id: 45\ndata: --- PASS: TestSearch\ndata: PASS\n\n" +
+				"event: done\ndata: done\n\n"),
+		},
+		{
+			URL:             ts.URL + "/tests/an-test-suite-id/log/other",
+			wantCode:        http.StatusNotFound,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        []byte("body: Not Found"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			resp, err := http.Get(tc.URL)
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d", tc.wantCode, resp.StatusCode)
+			}
+
+			got := resp.Header.Get("Content-Type")
+			if tc.wantContentType != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantContentType, got)
+			}
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if !reflect.DeepEqual(tc.wantBody, b) {
+				t.Errorf("\nWant: %s\n Got: %s\n", string(tc.wantBody), string(b))
+			}
+		})
+	}
+}
+
+func getTestAsJSON(t *testing.T) {
+	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
+	defer ts.Close()
+
+	testCases := []struct {
+		URL             string
+		wantCode        int
+		wantContentType string
+		wantBody        []byte
+	}{
+		{
+			URL:             ts.URL + "/tests/an-test-suite-id",
+			wantCode:        http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        []byte(`{"mock":"test result for id=an-test-suite-id"}`),
+		},
+		{
+			URL:             ts.URL + "/tests/an-test-suite-id/unknown",
+			wantCode:        http.StatusNotFound,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        []byte(`{"mock":"Not Found"}`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.URL, nil)
+			testdata.AssertNil(t, err)
+
+			req.Header.Set("Accept", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d", tc.wantCode, resp.StatusCode)
+			}
+
+			got := resp.Header.Get("Content-Type")
+			if tc.wantContentType != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantContentType, got)
+			}
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if !reflect.DeepEqual(tc.wantBody, b) {
+				t.Errorf("\nWant: %s\n Got: %s\n", string(tc.wantBody), string(b))
+			}
+		})
+	}
+}
+
+func getTestAsJSONWhenNotModified(t *testing.T) {
+	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
+	defer ts.Close()
+
+	get := func(etag string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/tests/an-test-suite-id", nil)
+		testdata.AssertNil(t, err)
+
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("If-None-Match", etag)
+		resp, err := http.DefaultClient.Do(req)
+		testdata.AssertNil(t, err)
+		testdata.AssertNil(t, resp.Body.Close())
+		return resp
+	}
+
+	first := get("")
+	if want, got := http.StatusOK, first.StatusCode; want != got {
+		t.Errorf("\nWant: %d\n Got: %d", want, got)
+	}
+
+	etag := first.Header.Get("ETag")
+	testdata.AssertTrue(t, etag != "")
+
+	second := get(etag)
+	if want, got := http.StatusNotModified, second.StatusCode; want != got {
+		t.Errorf("\nWant: %d\n Got: %d", want, got)
+	}
+}
+
+func postTestsAsJSON(t *testing.T) {
+	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
+	defer ts.Close()
+
+	testCases := []struct {
+		URL          string
+		body         io.Reader
+		wantCode     int
+		wantBody     []byte
+		wantLocation string
+		wantRetry    string
+	}{
+		{
+			URL:          ts.URL + "/tests",
+			body:         strings.NewReader(`{"test_suite": "test:all"}`),
+			wantCode:     http.StatusOK,
+			wantBody:     []byte(`{"mock":"run test test:all"}`),
+			wantLocation: "/tests/test-suite-id",
+			wantRetry:    "5",
+		},
+		{
+			URL:          ts.URL + "/tests",
+			body:         strings.NewReader(`{}`),
+			wantCode:     http.StatusBadRequest,
+			wantBody:     []byte(`{"mock":"RunTest failed with 400 bad request"}`),
+			wantLocation: "",
+			wantRetry:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run("", func(t *testing.T) {
+			resp, err := http.Post(tc.URL, "application/json", tc.body)
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d", tc.wantCode, resp.StatusCode)
+			}
+
+			got := resp.Header.Get("Location")
+			if tc.wantLocation != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantLocation, got)
+			}
+
+			got = resp.Header.Get("Retry-After")
+			if tc.wantRetry != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantRetry, got)
+			}
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if !reflect.DeepEqual(tc.wantBody, b) {
+				t.Errorf("\nWant: %s\n Got: %s\n", string(tc.wantBody), string(b))
+			}
+		})
+	}
+}
+
+func postDeployHook(t *testing.T) {
+	ts := testServer(mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")})
+	defer ts.Close()
+
+	const payload = `{"service":"api","version":"1.4.0","environment":"staging","commit":"9f2c1e7"}`
+	now := signature.Timestamp(time.Now())
+	sign := func(secret, timestamp, payload string) string {
+		return signature.Sign([]byte(secret), timestamp, []byte(payload))
+	}
+
+	testCases := []struct {
+		name      string
+		URL       string
+		timestamp string
+		signature string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			name:      "valid signature",
+			URL:       ts.URL + "/hooks/deploy",
+			timestamp: now,
+			signature: sign(testDeploySecret, now, payload),
+			wantCode:  http.StatusOK,
+			wantBody:  `{"mock":"run deploy ` + strings.Replace(payload, `"`, `\"`, -1) + `"}`,
+		},
+		{
+			name:      "wrong secret",
+			URL:       ts.URL + "/hooks/deploy",
+			timestamp: now,
+			signature: sign("guess", now, payload),
+			wantCode:  http.StatusUnauthorized,
+			wantBody:  "Unauthorized.\n",
+		},
+		{
+			name:      "replayed after the tolerance",
+			URL:       ts.URL + "/hooks/deploy",
+			timestamp: "1546398245",
+			signature: sign(testDeploySecret, "1546398245", payload),
+			wantCode:  http.StatusUnauthorized,
+			wantBody:  "Unauthorized.\n",
+		},
+		{
+			name:      "no signature",
+			URL:       ts.URL + "/hooks/deploy",
+			timestamp: now,
+			signature: "",
+			wantCode:  http.StatusUnauthorized,
+			wantBody:  "Unauthorized.\n",
+		},
+		{
+			name:      "unknown hook",
+			URL:       ts.URL + "/hooks/release",
+			timestamp: now,
+			signature: sign(testDeploySecret, now, payload),
+			wantCode:  http.StatusNotFound,
+			wantBody:  `{"mock":"Not Found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// no Content-Type, a webhook is answered with JSON anyway
+			req, err := http.NewRequest(http.MethodPost, tc.URL, strings.NewReader(payload))
+			testdata.AssertNil(t, err)
+			req.Header.Set(signature.TimestampHeader, tc.timestamp)
+			req.Header.Set(signature.Header, tc.signature)
+
+			resp, err := http.DefaultClient.Do(req)
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d\n", tc.wantCode, resp.StatusCode)
+			}
+
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if got := string(b); tc.wantBody != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantBody, got)
+			}
+		})
+	}
+}
+
+// users authenticates the users of the map by their password
+type users map[string]string
+
+func (u users) Authenticate(username, password string) bool {
+	p, ok := u[username]
+	return ok && p == password
+}
+
+func postTestsAuthenticated(t *testing.T) {
+	ts := httptest.NewServer(createHandler(handler{
+		Favicon: func(r *http.Request) (http.Handler, bool) { return nil, false },
+		HTML:    mock.HTMLAdapter{Code: http.StatusOK, Body: []byte("body: ")},
+		JSON:    mock.JSONAdapter{Code: http.StatusOK},
+		Log:     &mock.Log{},
+		Auth:    users{"alice": "password"},
+		Limiter: auth.NewLimiter(2, time.Minute),
+
+		DeploySecret: []byte(testDeploySecret),
+	}))
+	defer ts.Close()
+
+	testCases := []struct {
+		name      string
+		username  string
+		password  string
+		wantCode  int
+		wantBody  string
+		wantRetry string
+	}{
+		{name: "no credentials", wantCode: http.StatusUnauthorized, wantBody: "Unauthorized.\n"},
+		{name: "valid", username: "alice", password: "password", wantCode: http.StatusOK, wantBody: `{"mock":"run test all:test by alice"}`, wantRetry: "5"},
+		{name: "invalid password", username: "alice", password: "secret", wantCode: http.StatusUnauthorized, wantBody: "Unauthorized.\n"},
+		{name: "unknown user", username: "bob", password: "password", wantCode: http.StatusUnauthorized, wantBody: "Unauthorized.\n"},
+		{name: "locked out", username: "alice", password: "password", wantCode: http.StatusTooManyRequests, wantBody: "Too Many Requests.\n", wantRetry: "60"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/tests", strings.NewReader(`{"test_suite": "all:test"}`))
+			testdata.AssertNil(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json")
+			if tc.username != "" {
+				req.SetBasicAuth(tc.username, tc.password)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			testdata.AssertNil(t, err)
+			defer func() { testdata.AssertNil(t, resp.Body.Close()) }()
+
+			if tc.wantCode != resp.StatusCode {
+				t.Errorf("\nWant: %d\n Got: %d\n", tc.wantCode, resp.StatusCode)
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			testdata.AssertNil(t, err)
+			if got := string(b); tc.wantBody != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantBody, got)
+			}
+			if retry := resp.Header.Get("Retry-After"); tc.wantRetry != retry {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.wantRetry, retry)
+			}
+		})
+	}
+
+	// webhooks are authenticated by their signature
+	payload := []byte(`{"service": "api", "version": "v1.2.3", "environment": "staging"}`)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/hooks/deploy", bytes.NewReader(payload))
+	testdata.AssertNil(t, err)
+	timestamp := signature.Timestamp(time.Now())
+	req.Header.Set(signature.TimestampHeader, timestamp)
+	req.Header.Set(signature.Header, signature.Sign([]byte(testDeploySecret), timestamp, payload))
+	resp, err := http.DefaultClient.Do(req)
+	testdata.AssertNil(t, err)
+	testdata.AssertNil(t, resp.Body.Close())
+	if http.StatusOK != resp.StatusCode {
+		t.Errorf("\nWant: %d\n Got: %d\n", http.StatusOK, resp.StatusCode)
+	}
+}
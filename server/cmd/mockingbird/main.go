@@ -7,17 +7,26 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/unders/mockingbird/server/domain/mockingbird/app"
+	"github.com/unders/mockingbird/server/domain/mockingbird/notify"
 
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/plugin/ochttp/propagation/b3"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
 
 	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/auth"
+	"github.com/unders/mockingbird/server/pkg/metrics"
+	"github.com/unders/mockingbird/server/pkg/s3"
 	"github.com/unders/mockingbird/server/pkg/signal"
+	"github.com/unders/mockingbird/server/pkg/tracing"
 )
 
 //
@@ -26,11 +35,69 @@ import (
 
 func options() Options {
 	var (
-		addr  = ":8080"
-		local = false
+		addr     = ":8080"
+		local    = false
+		store    = string(mockingbird.MemoryStore)
+		storeDir = "../data/mockingbird"
+		dbPath   = "../data/mockingbird.db"
+
+		s3Endpoint  = os.Getenv("S3_ENDPOINT")
+		s3Region    = os.Getenv("AWS_REGION")
+		s3Bucket    = os.Getenv("S3_BUCKET")
+		s3PathStyle = false
+
+		workers          = 2
+		suiteConcurrency = suiteLimits{mockingbird.FullTestSuite: 1}
+		timeout          = 30 * time.Minute
+		suiteTimeout     = suiteTimeouts{}
+		suiteSchedule    = suiteSchedules{}
+
+		statsWindows = windows(mockingbird.DefaultWindows)
+
+		deployRules = ""
+
+		notifyWebhooks = webhooks{}
+		slackRules     = ""
+		emailConfig    = ""
+		baseURL        = "http://localhost:8080"
+
+		traceExporter = ""
+		traceSample   = 1.0
+
+		logFormat = os.Getenv("LOG_FORMAT")
+		logLevel  = os.Getenv("LOG_LEVEL")
+
+		htpasswd        = ""
+		authMaxFailures = 5
+		authLockout     = 15 * time.Minute
 	)
 	flag.StringVar(&addr, "http.addr", addr, "HTTP address.")
 	flag.BoolVar(&local, "l", local, "if app is running on a local dev server")
+	flag.StringVar(&store, "store", store, "where test results are stored: memory|file|s3|sqlite")
+	flag.StringVar(&storeDir, "store.dir", storeDir, "root directory of the file store.")
+	flag.StringVar(&dbPath, "store.db", dbPath, "database file of the sqlite store.")
+	flag.StringVar(&s3Endpoint, "s3.endpoint", s3Endpoint, "S3 endpoint, defaults to AWS S3 in s3.region.")
+	flag.StringVar(&s3Region, "s3.region", s3Region, "S3 region.")
+	flag.StringVar(&s3Bucket, "s3.bucket", s3Bucket, "S3 bucket of the s3 store.")
+	flag.BoolVar(&s3PathStyle, "s3.path-style", s3PathStyle, "use path-style S3 addressing (e.g: for minio).")
+	flag.IntVar(&workers, "workers", workers, "number of test suites that can run at the same time.")
+	flag.Var(suiteConcurrency, "suite.concurrency", "max concurrent runs per test suite (e.g: all:test=1,google:test=2).")
+	flag.DurationVar(&timeout, "timeout", timeout, "default test suite timeout.")
+	flag.Var(suiteTimeout, "suite.timeout", "timeout per test suite (e.g: all:test=1h,google:test=5m).")
+	flag.Var(suiteSchedule, "suite.schedule", "cron expression of a scheduled test suite, repeat per suite (e.g: 'google:test=*/5 * * * *').")
+	flag.StringVar(&deployRules, "deploy.rules", deployRules, "JSON file of the rules that map deployments to test suites.")
+	flag.Var(&notifyWebhooks, "notify.webhook", "webhook that is notified when a test suite is done, repeat per webhook (e.g: failures=https://example.com/hook); the filter is failures|recoveries|alerts|all.")
+	flag.StringVar(&slackRules, "notify.slack", slackRules, "JSON file of the rules that send chat messages to Slack-compatible incoming webhooks.")
+	flag.StringVar(&emailConfig, "notify.email", emailConfig, "JSON file of the SMTP server and the recipients of the email reports of failed and recovered runs.")
+	flag.StringVar(&baseURL, "notify.base-url", baseURL, "URL of the server in the links of the notifications.")
+	flag.StringVar(&traceExporter, "trace.exporter", traceExporter, "exporter of the trace spans: "+strings.Join(exporterNames(), "|")+"; tracing is off when empty.")
+	flag.Float64Var(&traceSample, "trace.sample", traceSample, "fraction of the traces that are sampled, from 0 to 1.")
+	flag.StringVar(&logFormat, "log.format", logFormat, "format of the log: text|json; defaults to text with -l and to json otherwise.")
+	flag.StringVar(&logLevel, "log.level", logLevel, "lowest level that is logged: debug|info|notice|error; defaults to info.")
+	flag.StringVar(&htpasswd, "auth.htpasswd", htpasswd, "htpasswd file of the users and their bcrypt hashes (e.g: htpasswd -B); it is read again when it changes, and it is required unless -l is set.")
+	flag.IntVar(&authMaxFailures, "auth.max-failures", authMaxFailures, "failed logins of an IP address before it is locked out.")
+	flag.DurationVar(&authLockout, "auth.lockout", authLockout, "window of the failed logins of an IP address, and how long it is locked out.")
+	flag.Var(&statsWindows, "stats.windows", "rolling windows of the dashboard stats (e.g: 24h,7d,30d,50runs).")
 	flag.Parse()
 
 	env := mockingbird.Env(os.Getenv("ENVIRONMENT"))
@@ -38,7 +105,13 @@ func options() Options {
 		env = mockingbird.DEV
 	}
 
-	l := log.New(os.Stderr, "", 0)
+	l, err := newLog(logFormat, logLevel, env)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	return Options{
 		Env: env,
 
@@ -53,9 +126,50 @@ func options() Options {
 		TemplateDir: "../web/mockingbird/tmpl",
 		AssetDir:    "../web/mockingbird/public",
 
+		Store:    mockingbird.StoreKind(store),
+		StoreDir: storeDir,
+		DBPath:   dbPath,
+		S3: s3.Config{
+			Endpoint:        s3Endpoint,
+			Region:          s3Region,
+			Bucket:          s3Bucket,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			PathStyle:       s3PathStyle,
+		},
+
+		Workers:          workers,
+		SuiteConcurrency: suiteConcurrency,
+		Timeout:          timeout,
+		SuiteTimeout:     suiteTimeout,
+		SuiteSchedule:    suiteSchedule,
+
+		Windows: statsWindows,
+
+		DeployRulesFile: deployRules,
+		DeploySecret:    secret(os.Getenv("MOCKINGBIRD_DEPLOY_SECRET")),
+
+		NotifyWebhooks:  notifyWebhooks,
+		NotifySecret:    secret(os.Getenv("MOCKINGBIRD_NOTIFY_SECRET")),
+		SlackRulesFile:  slackRules,
+		EmailConfigFile: emailConfig,
+		SMTPPassword:    secret(os.Getenv("MOCKINGBIRD_SMTP_PASSWORD")),
+		BaseURL:         baseURL,
+
+		TraceExporter: traceExporter,
+		TraceSample:   traceSample,
+
+		LogFormat: logFormat,
+		LogLevel:  logLevel,
+
+		HtpasswdFile:    htpasswd,
+		AuthMaxFailures: authMaxFailures,
+		AuthLockout:     authLockout,
+
 		StartTime: time.Now().UTC(),
-		Log:       &mockingbird.Logger{Log: l},
-		ErrorLog:  l,
+		Log:       l,
+		ErrorLog:  log.New(errorWriter{log: l}, "", 0),
 	}
 }
 
@@ -65,31 +179,167 @@ func main() {
 	}
 }
 
+// subscriptions returns the notification subscriptions of the options
+func subscriptions(o Options) ([]mockingbird.Subscription, error) {
+	subs := make([]mockingbird.Subscription, 0, len(o.NotifyWebhooks))
+	for _, hook := range o.NotifyWebhooks {
+		subs = append(subs, mockingbird.Subscription{
+			Filter:   hook.filter,
+			Notifier: notify.Webhook{URL: hook.url, Secret: []byte(o.NotifySecret)},
+		})
+	}
+
+	slack, err := loadSlackRules(o.SlackRulesFile, o.Env)
+	if err != nil {
+		return nil, err
+	}
+	return append(subs, slack...), nil
+}
+
+// traceExporters are the exporters of the trace spans by name
+var traceExporters = map[string]func() trace.Exporter{
+	"stdout": func() trace.Exporter { return tracing.NewJSONExporter(os.Stdout) },
+}
+
+func exporterNames() []string {
+	names := make([]string, 0, len(traceExporters))
+	for name := range traceExporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerTracing registers the trace exporter and the sampler; tracing is
+// off when no exporter is set
+func registerTracing(exporter string, sample float64) error {
+	if exporter == "" {
+		return nil
+	}
+
+	newExporter, ok := traceExporters[exporter]
+	if !ok {
+		return errors.Errorf("trace exporter %q must be one of %s", exporter, strings.Join(exporterNames(), ", "))
+	}
+	if sample < 0 || sample > 1 {
+		return errors.Errorf("trace sample %v must be from 0 to 1", sample)
+	}
+
+	trace.RegisterExporter(newExporter())
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(sample)})
+	return nil
+}
+
+// serverViews are the ochttp views of the HTTP server that are exported
+var serverViews = []*view.View{
+	ochttp.ServerRequestCountView,
+	ochttp.ServerRequestBytesView,
+	ochttp.ServerResponseBytesView,
+	ochttp.ServerLatencyView,
+	ochttp.ServerRequestCountByMethod,
+	ochttp.ServerResponseCountByStatusCode,
+}
+
+// registerMetrics registers the views of the app and the HTTP server and
+// returns the exporter that serves them
+func registerMetrics() (*metrics.Exporter, error) {
+	views := append(append([]*view.View(nil), app.Views...), serverViews...)
+	if err := view.Register(views...); err != nil {
+		return nil, errors.Wrap(err, "view.Register() failed")
+	}
+
+	e := metrics.NewExporter("mockingbird")
+	view.RegisterExporter(e)
+	return e, nil
+}
+
+// logOptions logs the options; the String methods of their types hide the
+// secrets and the tokens of the webhook URLs
+func logOptions(l mockingbird.Log, o Options) {
+	l.Info(fmt.Sprintf("Options%+v", o))
+}
+
 func run(o Options) error {
 	l := o.Log
 
-	format := "mockingbird server is starting  start-time=%s "
-	l.Info(fmt.Sprintf(format, o.StartTime.Format(time.RFC3339)))
-	format = "Options%+v"
-	l.Info(fmt.Sprintf(format, o))
+	l.Info("mockingbird server is starting", mockingbird.KV("start_time", o.StartTime.Format(time.RFC3339)))
+	logOptions(l, o)
+
+	rules, err := loadDeployRules(o.DeployRulesFile)
+	if err != nil {
+		return errors.Wrap(err, "loadDeployRules() failed")
+	}
+
+	subs, err := subscriptions(o)
+	if err != nil {
+		return errors.Wrap(err, "subscriptions() failed")
+	}
+
+	email, err := loadEmailConfig(o.EmailConfigFile, o.SMTPPassword)
+	if err != nil {
+		return errors.Wrap(err, "loadEmailConfig() failed")
+	}
+
+	// registered before app.Create, so the measurements of the recovered queue are recorded
+	exporter, err := registerMetrics()
+	if err != nil {
+		return errors.Wrap(err, "registerMetrics() failed")
+	}
 
 	builder, err := app.Create(app.Options{
 		Env:         o.Env,
-		Logger:      o.ErrorLog,
+		Log:         o.Log,
 		FaviconDir:  o.FaviconDir,
 		TemplateDir: o.TemplateDir,
 		AssetDir:    o.AssetDir,
+		Store:       o.Store,
+		StoreDir:    o.StoreDir,
+		DBPath:      o.DBPath,
+		S3:          o.S3,
+
+		Workers:          o.Workers,
+		SuiteConcurrency: o.SuiteConcurrency,
+		Timeout:          o.Timeout,
+		SuiteTimeout:     o.SuiteTimeout,
+		SuiteSchedule:    o.SuiteSchedule,
+
+		Windows:     o.Windows,
+		DeployRules: rules,
+
+		Subscriptions: subs,
+		Email:         email,
+		BaseURL:       o.BaseURL,
 	})
 	if err != nil {
 		return errors.Wrap(err, "app.Create() failed")
 	}
 
+	if err := registerTracing(o.TraceExporter, o.TraceSample); err != nil {
+		return errors.Wrap(err, "registerTracing() failed")
+	}
+
+	authenticator, err := loadHtpasswd(o.HtpasswdFile, o.Env)
+	if err != nil {
+		return errors.Wrap(err, "loadHtpasswd() failed")
+	}
+	if authenticator == nil {
+		l.Notice("authentication is off on the local dev server, set -auth.htpasswd to turn it on")
+	}
+
 	h := &ochttp.Handler{
 		Handler: createHandler(handler{
 			Favicon: builder.Favicon(),
 			Assets:  builder.Assets(),
 			HTML:    builder.HTMLAdapter(),
+			JSON:    builder.JSONAdapter(),
+			Logs:    builder.LogStream(),
 			Log:     builder.Log(),
+
+			Auth:    authenticator,
+			Limiter: auth.NewLimiter(o.AuthMaxFailures, o.AuthLockout),
+
+			DeploySecret: []byte(o.DeploySecret),
+			Metrics:      exporter,
 		}),
 
 		Propagation: &b3.HTTPFormat{},
@@ -105,34 +355,36 @@ func run(o Options) error {
 		ErrorLog:          o.ErrorLog,
 	}
 
-	format = "mockingbird listens on addr %s run-time=%s"
-	l.Info(fmt.Sprintf(format, o.ServerAddr, time.Since(o.StartTime)))
+	l.Info("mockingbird listens", mockingbird.KV("addr", o.ServerAddr), mockingbird.KV("run_time", time.Since(o.StartTime)))
 
 	errCh := make(chan error, 1)
 	go func() { errCh <- srv.ListenAndServe() }()
 
 	select {
 	case err = <-errCh:
-		const format = "server error=%s run-time=%s"
-		l.Error(fmt.Sprintf(format, err, time.Since(o.StartTime)))
+		l.Error("server failed", mockingbird.KV("error", err), mockingbird.KV("run_time", time.Since(o.StartTime)))
 	case sig := <-signal.Interrupt():
-		const format = "got interrupt signal=%s run-time=%s"
-		l.Info(fmt.Sprintf(format, sig, time.Since(o.StartTime)))
+		l.Info("got interrupt", mockingbird.KV("signal", sig.String()), mockingbird.KV("run_time", time.Since(o.StartTime)))
 	}
 
 	stopTime := time.Now().UTC()
+	builder.StopScheduler()
+
 	waitTimeout := o.ServerShutdownTimeout
-	format = "shutting down the http server wait-timeout=%s run-time=%s"
-	l.Info(fmt.Sprintf(format, waitTimeout, time.Since(o.StartTime)))
+	l.Info("shutting down the http server", mockingbird.KV("wait_timeout", waitTimeout), mockingbird.KV("run_time", time.Since(o.StartTime)))
 	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		const format = "server shutdown error=%s shutdown-time=%s"
-		l.Error(fmt.Sprintf(format, err, time.Since(stopTime)))
+		l.Error("server shutdown failed", mockingbird.KV("error", err), mockingbird.KV("shutdown_time", time.Since(stopTime)))
+	}
+
+	// the running test suites and then the notifications get what is left
+	// of the shutdown timeout, then the store is closed
+	if err := builder.Close(ctx); err != nil {
+		l.Error("app close failed", mockingbird.KV("error", err), mockingbird.KV("shutdown_time", time.Since(stopTime)))
 	}
 
-	format = "mockingbird server is stopped shutdown-time=%s run-time=%s"
-	l.Info(fmt.Sprintf(format, time.Since(stopTime), time.Since(o.StartTime)))
+	l.Info("mockingbird server is stopped", mockingbird.KV("shutdown_time", time.Since(stopTime)), mockingbird.KV("run_time", time.Since(o.StartTime)))
 
 	return err
 }
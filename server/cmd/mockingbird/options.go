@@ -1,10 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/notify"
+	"github.com/unders/mockingbird/server/pkg/auth"
+	"github.com/unders/mockingbird/server/pkg/cron"
+	"github.com/unders/mockingbird/server/pkg/s3"
 )
 
 // Options defined required fields for mockingbird
@@ -26,6 +38,349 @@ type Options struct {
 	TemplateDir string
 	AssetDir    string
 
+	// Storage
+	Store    mockingbird.StoreKind
+	StoreDir string
+	DBPath   string
+	S3       s3.Config
+
+	// Worker pool
+	Workers          int
+	SuiteConcurrency suiteLimits
+	Timeout          time.Duration
+	SuiteTimeout     suiteTimeouts
+	SuiteSchedule    suiteSchedules
+
+	// Dashboard
+	Windows windows
+
+	// Deploy webhook
+	DeployRulesFile string
+	DeploySecret    secret
+
+	// Notifications
+	NotifyWebhooks  webhooks
+	NotifySecret    secret
+	SlackRulesFile  string
+	EmailConfigFile string
+	SMTPPassword    secret
+	BaseURL         string
+
+	// Tracing
+	TraceExporter string
+	TraceSample   float64
+
+	// Logging
+	LogFormat string
+	LogLevel  string
+
+	// Authentication
+	HtpasswdFile    string
+	AuthMaxFailures int
+	AuthLockout     time.Duration
+
 	Log      mockingbird.Log
 	ErrorLog *log.Logger
 }
+
+// suiteLimits implements flag.Value for a comma separated list of
+// suite=limit pairs, e.g: all:test=1,google:test=2
+type suiteLimits map[mockingbird.TestSuite]int
+
+func (l suiteLimits) String() string {
+	pairs := make([]string, 0, len(l))
+	for s, n := range l {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", s, n))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set replaces the limits with the pairs in value
+func (l suiteLimits) Set(value string) error {
+	for s := range l {
+		delete(l, s)
+	}
+
+	return eachPair(value, func(s mockingbird.TestSuite, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("%s=%s must have a limit of 1 or more", s, v)
+		}
+		l[s] = n
+		return nil
+	})
+}
+
+// suiteTimeouts implements flag.Value for a comma separated list of
+// suite=duration pairs, e.g: all:test=1h,google:test=5m
+type suiteTimeouts map[mockingbird.TestSuite]time.Duration
+
+func (t suiteTimeouts) String() string {
+	pairs := make([]string, 0, len(t))
+	for s, d := range t {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", s, d))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set replaces the timeouts with the pairs in value
+func (t suiteTimeouts) Set(value string) error {
+	for s := range t {
+		delete(t, s)
+	}
+
+	return eachPair(value, func(s mockingbird.TestSuite, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%s=%s must have a positive duration", s, v)
+		}
+		t[s] = d
+		return nil
+	})
+}
+
+// secret is not shown when the options are logged
+type secret string
+
+func (s secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// loadDeployRules returns the deploy rules in the JSON file; no rules when file is empty
+func loadDeployRules(file string) (mockingbird.DeployRules, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "os.Open(%s) failed", file)
+	}
+	defer f.Close()
+
+	rules, err := mockingbird.ParseDeployRules(f)
+	return rules, errors.Wrapf(err, "mockingbird.ParseDeployRules(%s) failed", file)
+}
+
+// loadSlackRules returns the subscriptions of the Slack rules in the JSON
+// file that are of the environment; none when file is empty
+func loadSlackRules(file string, env mockingbird.Env) ([]mockingbird.Subscription, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "os.Open(%s) failed", file)
+	}
+	defer f.Close()
+
+	subs, err := notify.ParseSlackRules(f, env)
+	return subs, errors.Wrapf(err, "notify.ParseSlackRules(%s) failed", file)
+}
+
+// loadEmailConfig returns the email config in the JSON file with the
+// SMTP password; nil when file is empty
+func loadEmailConfig(file string, password secret) (*notify.EmailConfig, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "os.Open(%s) failed", file)
+	}
+	defer f.Close()
+
+	c, err := notify.ParseEmailConfig(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "notify.ParseEmailConfig(%s) failed", file)
+	}
+	c.SMTP.Password = string(password)
+	return &c, nil
+}
+
+// loadHtpasswd returns the authenticator of the users in the htpasswd file;
+// nil on a local dev server when file is empty, since all requests are
+// allowed there
+func loadHtpasswd(file string, env mockingbird.Env) (auth.Authenticator, error) {
+	if file == "" {
+		if mockingbird.DEV == env {
+			return nil, nil
+		}
+		return nil, errors.New("an htpasswd file is required, set -auth.htpasswd")
+	}
+
+	f, err := auth.NewHtpasswdFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "auth.NewHtpasswdFile(%s) failed", file)
+	}
+	return f, nil
+}
+
+// newLog returns the log of the format, text or json, that logs the
+// messages of level and above; an empty format is text in dev and json
+// in the other environments, and an empty level is info
+func newLog(format, level string, env mockingbird.Env) (mockingbird.Log, error) {
+	if level == "" {
+		level = string(mockingbird.INFO)
+	}
+	l, err := mockingbird.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = "json"
+		if mockingbird.DEV == env {
+			format = "text"
+		}
+	}
+
+	w := log.New(os.Stderr, "", 0)
+	switch format {
+	case "text":
+		return &mockingbird.Logger{Log: w, Level: l}, nil
+	case "json":
+		return &mockingbird.JSONLogger{Log: w, Level: l}, nil
+	default:
+		return nil, fmt.Errorf("log format %q must be one of text, json", format)
+	}
+}
+
+// errorWriter writes the lines of a log.Logger as errors to the log, e.g:
+// the errors of the http.Server
+type errorWriter struct {
+	log mockingbird.Log
+}
+
+func (w errorWriter) Write(p []byte) (int, error) {
+	w.log.Error(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+// webhooks implements flag.Value for a filter=URL pair; the flag is
+// repeated for each webhook, e.g: -notify.webhook failures=https://example.com/hook
+type webhooks []webhook
+
+type webhook struct {
+	filter mockingbird.NotifyFilter
+	url    string
+}
+
+// String shows the host of each URL, the rest of it may have a token; it
+// has a value receiver, so the Options that are logged show it too
+func (w webhooks) String() string {
+	pairs := make([]string, 0, len(w))
+	for _, hook := range w {
+		host := "(invalid URL)"
+		if u, err := url.Parse(hook.url); err == nil {
+			host = u.Host
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", hook.filter, host))
+	}
+	return strings.Join(pairs, ";")
+}
+
+// Set adds the pair in value
+func (w *webhooks) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 1 {
+		return fmt.Errorf("%q is not a filter=URL pair", value)
+	}
+
+	filter, err := mockingbird.ParseNotifyFilter(strings.TrimSpace(value[:i]))
+	if err != nil {
+		return err
+	}
+
+	rawURL := strings.TrimSpace(value[i+1:])
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook %q must be an http or https URL", rawURL)
+	}
+
+	*w = append(*w, webhook{filter: filter, url: rawURL})
+	return nil
+}
+
+// suiteSchedules implements flag.Value for a suite=cron-expression pair;
+// the flag is repeated for each test suite, since an expression can have
+// commas, e.g: -suite.schedule 'google:test=0,30 * * * *'
+type suiteSchedules map[mockingbird.TestSuite]string
+
+func (s suiteSchedules) String() string {
+	pairs := make([]string, 0, len(s))
+	for suite, expr := range s {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", suite, expr))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+// Set adds the pair in value
+func (s suiteSchedules) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 1 {
+		return fmt.Errorf("%q is not a suite=cron-expression pair", value)
+	}
+
+	suite, expr := mockingbird.TestSuite(strings.TrimSpace(value[:i])), strings.TrimSpace(value[i+1:])
+	if _, err := cron.Parse(expr); err != nil {
+		return err
+	}
+	s[suite] = expr
+	return nil
+}
+
+// windows implements flag.Value for a comma separated list of
+// rolling windows, e.g: 24h,7d,30d,50runs
+type windows []mockingbird.Window
+
+func (w *windows) String() string {
+	list := make([]string, 0, len(*w))
+	for _, window := range *w {
+		list = append(list, window.String())
+	}
+	return strings.Join(list, ",")
+}
+
+// Set replaces the windows with the windows in value
+func (w *windows) Set(value string) error {
+	*w = nil
+
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+
+		window, err := mockingbird.ParseWindow(s)
+		if err != nil {
+			return err
+		}
+		*w = append(*w, window)
+	}
+	return nil
+}
+
+// eachPair calls fn for each suite=value pair in the comma separated list
+func eachPair(list string, fn func(s mockingbird.TestSuite, value string) error) error {
+	for _, pair := range strings.Split(list, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		i := strings.LastIndex(pair, "=")
+		if i < 1 {
+			return fmt.Errorf("%q is not a suite=value pair", pair)
+		}
+		if err := fn(mockingbird.TestSuite(pair[:i]), pair[i+1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
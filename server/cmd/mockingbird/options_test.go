@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestLogOptions_HidesTheSecretsAndTheWebhookTokens(t *testing.T) {
+	o := Options{DeploySecret: "deploy-secret", NotifySecret: "notify-secret", SMTPPassword: "smtp-password"}
+	testdata.AssertNil(t, o.NotifyWebhooks.Set("failures=https://hooks.slack.com/services/T000/B000/XXXX?token=abc"))
+
+	var buf bytes.Buffer
+	logOptions(&mockingbird.Logger{Log: log.New(&buf, "", 0)}, o)
+	line := buf.String()
+
+	for _, leak := range []string{"/services/", "XXXX", "token=abc", "deploy-secret", "notify-secret", "smtp-password"} {
+		if strings.Contains(line, leak) {
+			t.Errorf("\nWant: no %s\n Got: %s\n", leak, line)
+		}
+	}
+	if want := "failures=hooks.slack.com"; !strings.Contains(line, want) {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, line)
+	}
+}
@@ -2,6 +2,7 @@ package mockingbird
 
 import (
 	"fmt"
+	"sort"
 	"time"
 )
 
@@ -9,6 +10,7 @@ type ULID string
 type TestSuite string
 type Status string
 type State string
+type Trigger string
 
 const (
 	FullTestSuite TestSuite = "all:test"
@@ -26,11 +28,28 @@ const (
 	PENDING    State = "pending"
 	SUCCESSFUL       = "successful"
 	FAILED           = "failed"
+
+	// INTERRUPTED is set on test results that were running when the server stopped
+	INTERRUPTED = "interrupted"
+
+	// TIMED_OUT is set on test results that ran past the test suite timeout
+	TIMED_OUT = "timed_out"
+
+	// CANCELLED is set on test results that were cancelled while queued or running
+	CANCELLED = "cancelled"
+)
+
+// The possible triggers that start a test suite
+const (
+	MANUAL    Trigger = "manual"
+	SCHEDULED         = "scheduled"
+	DEPLOY            = "deploy"
 )
 
 // Dashboard shows the cumulative test suite state and possible test suites to run
 type Dashboard struct {
-	Stats Stats
+	Stats   Stats
+	Windows []WindowStats
 }
 
 // Stats shows the cumulative stats for the test suites
@@ -48,13 +67,54 @@ type Stats struct {
 	TestSuiteSuccessCounter float64
 	TestSuiteSuccessRate    float64
 	TestSuiteRunCounter     float64
+	TestSuiteTimeoutCounter float64
 
 	FullTestSuiteSuccessCounter float64
 	FullTestSuiteSuccessRate    float64
 	FullTestSuiteRunCounter     float64
+	FullTestSuiteTimeoutCounter float64
 
 	SlowestTestSuiteRunTime time.Duration
 	SlowestTestSuiteName    TestSuite
+
+	Suites map[TestSuite]SuiteStats
+}
+
+// SuiteStats shows the cumulative stats for one test suite
+//
+// Note:
+//
+//        The percentiles are computed from RunTimes, the run times of
+//        the latest runs; MaxRunTime is the slowest run ever.
+//
+type SuiteStats struct {
+	TestSuite TestSuite
+
+	RunCounter     float64
+	SuccessCounter float64
+	TimeoutCounter float64
+	SuccessRate    float64
+
+	LatestDoneID      ULID
+	LatestDoneState   State
+	LatestDoneRunTime time.Duration
+
+	P50RunTime time.Duration
+	P90RunTime time.Duration
+	P99RunTime time.Duration
+	MaxRunTime time.Duration
+
+	RunTimes []time.Duration // oldest first
+}
+
+// SuiteList returns the stats of each test suite, sorted by test suite name
+func (s Stats) SuiteList() []SuiteStats {
+	suites := make([]SuiteStats, 0, len(s.Suites))
+	for _, ss := range s.Suites {
+		suites = append(suites, ss)
+	}
+	sort.Slice(suites, func(i, j int) bool { return suites[i].TestSuite < suites[j].TestSuite })
+	return suites
 }
 
 // TestResults contains a list of test results
@@ -72,10 +132,40 @@ type TestResult struct {
 	TestSuite TestSuite
 
 	Log    string
-	LogURL string // URL to S3 fil
+	LogURL string // URL to the stored log
+
+	// Packages is parsed from go test -json output, it is empty for other test suites
+	Packages []TestPackage
 
 	StartTime time.Time
 	RunTime   time.Duration
+	Worker    int // the worker slot that ran the test suite, starts at 1
+
+	// Trigger is empty on test results stored before triggers were recorded
+	Trigger Trigger
+
+	// TriggeredBy is the user that started a manual run; it is empty for
+	// the other triggers
+	TriggeredBy string `json:",omitempty"`
+
+	// Deployment is set when the deploy webhook started the test suite
+	Deployment *Deployment `json:",omitempty"`
+
+	// Progress is set by App.ShowTest on pending test results, it is never stored
+	Progress Progress `json:"-"`
+}
+
+// Schedule shows when the scheduler runs a test suite next
+type Schedule struct {
+	TestSuite  TestSuite
+	Expression string    // a cron expression, e.g: */5 * * * *
+	Next       time.Time // zero when the expression never matches again
+}
+
+// Progress shows where a pending test suite is in the queue and when it is expected to be done
+type Progress struct {
+	QueuePosition int       // 1 is next to run, 0 when it is running
+	ETA           time.Time // zero when the test suite has no past run times
 }
 
 func (tr TestResult) TestPath(path string) string {
@@ -96,6 +186,21 @@ func (tr TestResult) IsSuccessful() bool {
 func (tr TestResult) IsFailed() bool {
 	return tr.State == FAILED
 }
+func (tr TestResult) IsInterrupted() bool {
+	return tr.State == INTERRUPTED
+}
+func (tr TestResult) IsTimedOut() bool {
+	return tr.State == TIMED_OUT
+}
+func (tr TestResult) IsCancelled() bool {
+	return tr.State == CANCELLED
+}
+func (tr TestResult) IsQueued() bool {
+	return tr.Status == QUEUED
+}
+func (tr TestResult) IsScheduled() bool {
+	return tr.Trigger == SCHEDULED
+}
 
 // App defines the interface for the mockingbird application
 //
@@ -106,12 +211,20 @@ type App interface {
 	// Fetches test suite results
 	//
 	Dashboard() (Dashboard, error)
-	ListTests(pageToken string) (*TestResults, error)
+	ListTests(q ListTestsQuery) (*TestResults, error)
 	ShowTest(id ULID) (TestResult, error)
 	ShowTestSuites() []TestSuite
+	ShowSchedules() []Schedule
 
 	//
 	// Executes given test suite
 	//
-	RunTest(s TestSuite) (ULID, error)
+	RunTest(s TestSuite, user string) (ULID, error)
+	RunDeploy(d Deployment) ([]ULID, error)
+	CancelTest(id ULID) error
+
+	//
+	// Fetches the notification delivery log
+	//
+	ShowDeliveries() []Delivery
 }
@@ -1,25 +1,66 @@
 package app
 
 import (
-	"log"
+	"context"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/unders/mockingbird/server/pkg/handler"
+	"github.com/unders/mockingbird/server/pkg/s3"
 
 	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/bucket"
 	"github.com/unders/mockingbird/server/domain/mockingbird/html"
+	"github.com/unders/mockingbird/server/domain/mockingbird/json"
+	"github.com/unders/mockingbird/server/domain/mockingbird/memory"
 	"github.com/unders/mockingbird/server/domain/mockingbird/mock"
+	"github.com/unders/mockingbird/server/domain/mockingbird/notify"
+	"github.com/unders/mockingbird/server/domain/mockingbird/sqlite"
 )
 
 // Options defines the required input to function app.Create
 type Options struct {
 	Env         mockingbird.Env
-	Logger      *log.Logger
+	Log         mockingbird.Log
 	FaviconDir  string
 	TemplateDir string
 	AssetDir    string
+
+	Store    mockingbird.StoreKind
+	StoreDir string
+	DBPath   string
+	S3       s3.Config
+
+	// Workers is the size of the worker pool; SuiteConcurrency caps
+	// the concurrent runs of a test suite, e.g: {"all:test": 1}
+	Workers          int
+	SuiteConcurrency map[mockingbird.TestSuite]int
+
+	// Timeout is the default test suite timeout; SuiteTimeout
+	// overrides it per test suite, e.g: {"all:test": time.Hour}
+	Timeout      time.Duration
+	SuiteTimeout map[mockingbird.TestSuite]time.Duration
+
+	// SuiteSchedule is the cron expression of each scheduled test
+	// suite, e.g: {"google:test": "*/5 * * * *"}
+	SuiteSchedule map[mockingbird.TestSuite]string
+
+	// Windows are the rolling windows of the dashboard stats
+	Windows []mockingbird.Window
+
+	// DeployRules map the deployments sent to the deploy webhook to test suites
+	DeployRules mockingbird.DeployRules
+
+	// Subscriptions are notified when a test suite is done; BaseURL is
+	// the URL of the server in the links they get
+	Subscriptions []mockingbird.Subscription
+	BaseURL       string
+
+	// Email sends email reports of failed and recovered runs when set
+	Email *notify.EmailConfig
 }
 
 // Create creates the application
@@ -29,9 +70,42 @@ func Create(o Options) (*Builder, error) {
 		return nil, err
 	}
 
+	store, err := newStore(o)
+	if err != nil {
+		return nil, err
+	}
+
+	l := o.Log
+	ts := []mockingbird.TestSuite{"all:test", "google:test"}
+
+	p := pool{
+		workers:  o.Workers,
+		limits:   o.SuiteConcurrency,
+		timeout:  o.Timeout,
+		timeouts: o.SuiteTimeout,
+
+		schedules: o.SuiteSchedule,
+	}
+	subs := o.Subscriptions
+	if o.Email != nil {
+		subs = append(subs[:len(subs):len(subs)], o.Email.Subscriptions(tmpl)...)
+	}
+	var n *notifier
+	if len(subs) > 0 {
+		n = newNotifier(subs, o.BaseURL, l)
+	}
+	app, err := build(ts, p, o.Windows, o.DeployRules, n, store, l)
+	if err != nil {
+		if cerr := closeStore(store); cerr != nil {
+			l.Error("close store failed", mockingbird.KV("error", cerr))
+		}
+		return nil, err
+	}
+
 	b := Builder{
-		log:     o.Logger,
-		app:     build([]mockingbird.TestSuite{"all:test", "google:test"}),
+		log:     o.Log,
+		app:     app,
+		store:   store,
 		favicon: handler.Favicons(o.FaviconDir),
 		assets:  http.Dir(o.AssetDir),
 		tmpl:    tmpl,
@@ -42,8 +116,9 @@ func Create(o Options) (*Builder, error) {
 // Builder builds the application
 type Builder struct {
 	favicon func(*http.Request) (http.Handler, bool)
-	app     mockingbird.App
-	log     *log.Logger
+	app     *Mockingbird
+	store   mockingbird.Store
+	log     mockingbird.Log
 	tmpl    *html.Template
 	assets  http.FileSystem
 }
@@ -68,9 +143,51 @@ func (b *Builder) HTMLAdapter() mockingbird.HTMLAdapter {
 	return &html.Adapter{App: b.app, Tmpl: b.tmpl}
 }
 
+// JSONAdapter returns the mockingbird.JSONAdapter
+func (b *Builder) JSONAdapter() mockingbird.JSONAdapter {
+	return &json.Adapter{App: b.app}
+}
+
+// LogStream returns the mockingbird.LogStream
+func (b *Builder) LogStream() mockingbird.LogStream {
+	return b.app
+}
+
 // Log returns the mockingbird.Log
 func (b *Builder) Log() mockingbird.Log {
-	return &mockingbird.Logger{Log: b.log}
+	return b.log
+}
+
+// StopScheduler stops the scheduled runs; the runs that are queued or running are kept
+func (b *Builder) StopScheduler() {
+	if b.app.scheduler != nil {
+		b.app.scheduler.stop()
+	}
+}
+
+// Close stops the worker pool and waits until the running test suites are
+// done or ctx is done, then they are saved as interrupted; it then waits
+// until the notifications that are in progress are delivered or ctx is
+// done, and closes the store, e.g: so the sqlite store checkpoints its WAL
+//
+// Note:
+//
+//        The queued test suites are kept in the store and run when the
+//        server is started again; the test suites are no longer queued
+//        or run after Close.
+//
+func (b *Builder) Close(ctx context.Context) error {
+	err := errors.Wrap(b.app.worker.stop(ctx), "worker.stop() failed")
+
+	if nerr := b.app.notifier.close(ctx); nerr != nil && err == nil {
+		err = errors.Wrap(nerr, "notifier.close() failed")
+	}
+
+	// closed even when the notifications are not done, the process exits next
+	if cerr := closeStore(b.store); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
 }
 
 //
@@ -87,3 +204,38 @@ func newTemplate(env mockingbird.Env, templateDir string) (tmpl *html.Template,
 	err = errors.Wrapf(err, "html.NewsTemplate(%s) failed", templateDir)
 	return tmpl, err
 }
+
+func newStore(o Options) (mockingbird.Store, error) {
+	switch o.Store {
+	case mockingbird.MemoryStore, "":
+		return memory.NewStore(), nil
+	case mockingbird.FileStore:
+		dir, err := bucket.NewDir(o.StoreDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bucket.NewDir(%s) failed", o.StoreDir)
+		}
+		return bucket.NewStore(dir), nil
+	case mockingbird.S3Store:
+		c, err := s3.New(o.S3)
+		if err != nil {
+			return nil, errors.Wrapf(err, "s3.New(%s) failed", o.S3)
+		}
+		return bucket.NewStore(c), nil
+	case mockingbird.SQLiteStore:
+		s, err := sqlite.Open(o.DBPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "sqlite.Open(%s) failed", o.DBPath)
+		}
+		return s, nil
+	default:
+		return nil, errors.Errorf("unknown store %q", o.Store)
+	}
+}
+
+// closeStore closes the store, if it has to be closed
+func closeStore(store mockingbird.Store) error {
+	if c, ok := store.(io.Closer); ok {
+		return errors.Wrap(c.Close(), "store.Close() failed")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/mock"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestCreate_WhenItFails_ClosesTheStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockingbird-app-")
+	testdata.AssertNil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "mockingbird.db")
+	_, err = Create(Options{
+		Env:           mockingbird.PROD,
+		Log:           &mock.Log{},
+		TemplateDir:   "../../../../web/mockingbird/tmpl",
+		Store:         mockingbird.SQLiteStore,
+		DBPath:        path,
+		SuiteSchedule: map[mockingbird.TestSuite]string{"google:test": "not a cron expression"},
+	})
+	testdata.AssertErr(t, err)
+
+	// the write-ahead log is removed when the last connection is closed
+	_, err = os.Stat(path + "-wal")
+	testdata.AssertTrue(t, os.IsNotExist(err))
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// runTimeSamples is the number of past run times of a test suite used for an ETA
+const runTimeSamples = 10
+
+// runTimes keeps the latest run times of each test suite
+type runTimes map[mockingbird.TestSuite][]time.Duration
+
+// add adds the run time of a test suite that ran to completion
+func (r runTimes) add(tr mockingbird.TestResult) {
+	if tr.State != mockingbird.SUCCESSFUL && tr.State != mockingbird.FAILED {
+		return
+	}
+
+	samples := append(r[tr.TestSuite], tr.RunTime)
+	if len(samples) > runTimeSamples {
+		samples = samples[len(samples)-runTimeSamples:]
+	}
+	r[tr.TestSuite] = samples
+}
+
+// mean returns the mean run time of the test suite; false when it has no run times
+func (r runTimes) mean(s mockingbird.TestSuite) (time.Duration, bool) {
+	samples := r[s]
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	var sum time.Duration
+	for _, d := range samples {
+		sum += d
+	}
+	return sum / time.Duration(len(samples)), true
+}
+
+// loadRunTimes returns the run times of the latest test results in the store
+func loadRunTimes(store mockingbird.Store, limit int) (runTimes, error) {
+	var done []mockingbird.TestResult
+	q := mockingbird.ListTestsQuery{Status: mockingbird.DONE, PageSize: mockingbird.MaxPageSize}
+	for len(done) < limit {
+		trs, err := store.ListTestResults(q)
+		if err != nil {
+			return nil, errors.Wrapf(err, "store.ListTestResults(%s) failed", q.PageToken)
+		}
+		done = append(done, trs.TestResults...)
+
+		if trs.NextPageToken == "" {
+			break
+		}
+		q.PageToken = trs.NextPageToken
+	}
+
+	// add the oldest first, so the newest are kept
+	r := runTimes{}
+	for i := len(done) - 1; i >= 0; i-- {
+		r.add(done[i])
+	}
+	return r, nil
+}
+
+// progress returns the queue position and the ETA of a pending test result
+//
+// Note:
+//
+//        The ETA replays the queue on the worker slots: a slot is free when
+//        its running test suite is expected to be done, and each queued test
+//        suite ahead takes the slot that is free first. Past run times make
+//        the ETA stable between calls; it is only moved to now when a test
+//        suite runs longer than expected.
+//
+func (w *worker) progress(tr mockingbird.TestResult) mockingbird.Progress {
+	w.Lock()
+	defer w.Unlock()
+
+	now := time.Now().UTC()
+	p := mockingbird.Progress{}
+
+	if tr.Status == mockingbird.RUNNING {
+		if mean, ok := w.runTimes.mean(tr.TestSuite); ok {
+			p.ETA = latest(tr.StartTime.Add(mean), now)
+		}
+		return p
+	}
+
+	pending := w.queue.snapshot()
+	for i, j := range pending {
+		if j.id == tr.ID {
+			p.QueuePosition = i + 1
+			pending = pending[:i+1]
+			break
+		}
+	}
+	if p.QueuePosition == 0 {
+		return p
+	}
+
+	// the time each worker slot is free
+	var free []time.Time
+	for _, t := range w.running {
+		mean, ok := w.runTimes.mean(t.suite)
+		if !ok {
+			return p
+		}
+		free = append(free, t.start.Add(mean))
+	}
+	for len(free) < w.workers {
+		free = append(free, now)
+	}
+
+	var end time.Time
+	for _, j := range pending {
+		mean, ok := w.runTimes.mean(j.suite)
+		if !ok {
+			return p
+		}
+
+		first := 0
+		for i := range free {
+			if free[i].Before(free[first]) {
+				first = i
+			}
+		}
+		end = free[first].Add(mean)
+		free[first] = end
+	}
+
+	p.ETA = latest(end, now)
+	return p
+}
+
+func latest(t, now time.Time) time.Time {
+	if t.Before(now) {
+		return now
+	}
+	return t
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/memory"
+)
+
+func TestWorker_Progress_ReplaysTheQueueOnTheWorkerSlots(t *testing.T) {
+	w := newTestWorker(memory.NewStore())
+	w.workers = 2
+	w.runTimes = runTimes{
+		"google:test":             {10 * time.Minute},
+		mockingbird.FullTestSuite: {20 * time.Minute, 40 * time.Minute},
+	}
+
+	start := time.Now().UTC()
+	w.running["1"] = &task{suite: "google:test", start: start}
+	w.running["2"] = &task{suite: mockingbird.FullTestSuite, start: start}
+	w.queue.push(job{id: "3", suite: "google:test"})
+	w.queue.push(job{id: "4", suite: mockingbird.FullTestSuite})
+
+	testCases := []struct {
+		tr   mockingbird.TestResult
+		want mockingbird.Progress
+	}{
+		{
+			tr:   mockingbird.TestResult{ID: "1", Status: mockingbird.RUNNING, TestSuite: "google:test", StartTime: start},
+			want: mockingbird.Progress{ETA: start.Add(10 * time.Minute)},
+		},
+		{
+			tr:   mockingbird.TestResult{ID: "3", Status: mockingbird.QUEUED, TestSuite: "google:test"},
+			want: mockingbird.Progress{QueuePosition: 1, ETA: start.Add(20 * time.Minute)},
+		},
+		{
+			tr:   mockingbird.TestResult{ID: "4", Status: mockingbird.QUEUED, TestSuite: mockingbird.FullTestSuite},
+			want: mockingbird.Progress{QueuePosition: 2, ETA: start.Add(50 * time.Minute)},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := w.progress(tc.tr)
+		if tc.want != got {
+			t.Errorf("%s\nWant: %+v\n Got: %+v\n", tc.tr.ID, tc.want, got)
+		}
+	}
+}
+
+func TestWorker_Progress_WhenNoRunTimes_HasNoETA(t *testing.T) {
+	w := newTestWorker(memory.NewStore())
+	w.queue.push(job{id: "1", suite: "google:test"})
+
+	got := w.progress(mockingbird.TestResult{ID: "1", Status: mockingbird.QUEUED, TestSuite: "google:test"})
+	if want := (mockingbird.Progress{QueuePosition: 1}); want != got {
+		t.Errorf("\nWant: %+v\n Got: %+v\n", want, got)
+	}
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// The tag keys of the test suite metrics
+var (
+	suiteKey, _ = tag.NewKey("suite")
+	stateKey, _ = tag.NewKey("state")
+)
+
+// The measures of the test suite runs and the work queue
+var (
+	runsMeasure        = stats.Int64("mockingbird/runs", "Finished test suite runs", stats.UnitDimensionless)
+	runTimeMeasure     = stats.Float64("mockingbird/run_time", "Run time of a test suite", "s")
+	lastSuccessMeasure = stats.Float64("mockingbird/last_success", "Unix time of the latest successful run", "s")
+	lastFailureMeasure = stats.Float64("mockingbird/last_failure", "Unix time of the latest failed or timed out run", "s")
+	queueDepthMeasure  = stats.Int64("mockingbird/queue_depth", "Queued test suites", stats.UnitDimensionless)
+	runningMeasure     = stats.Int64("mockingbird/running", "Running test suites", stats.UnitDimensionless)
+)
+
+// Views are the views of the test suite runs and the work queue; register
+// them with view.Register to export them
+var Views = []*view.View{
+	{
+		Name:        "test_suite_runs_total",
+		Description: "Finished test suite runs by test suite and state",
+		TagKeys:     []tag.Key{suiteKey, stateKey},
+		Measure:     runsMeasure,
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "test_suite_run_time_seconds",
+		Description: "Run time of the test suites",
+		TagKeys:     []tag.Key{suiteKey},
+		Measure:     runTimeMeasure,
+		Aggregation: view.Distribution(1, 5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600),
+	},
+	{
+		Name:        "test_suite_last_success_timestamp_seconds",
+		Description: "Unix time of the latest successful run of the test suite",
+		TagKeys:     []tag.Key{suiteKey},
+		Measure:     lastSuccessMeasure,
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "test_suite_last_failure_timestamp_seconds",
+		Description: "Unix time of the latest failed or timed out run of the test suite",
+		TagKeys:     []tag.Key{suiteKey},
+		Measure:     lastFailureMeasure,
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "queue_depth",
+		Description: "Test suites that are queued",
+		Measure:     queueDepthMeasure,
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "test_suites_running",
+		Description: "Test suites that are running",
+		Measure:     runningMeasure,
+		Aggregation: view.LastValue(),
+	},
+}
+
+// recordRun records a finished test suite run
+func recordRun(tr mockingbird.TestResult) {
+	ctx, err := tag.New(context.Background(),
+		tag.Upsert(suiteKey, string(tr.TestSuite)),
+		tag.Upsert(stateKey, string(tr.State)),
+	)
+	if err != nil {
+		return
+	}
+
+	done := float64(tr.StartTime.Add(tr.RunTime).Unix())
+	ms := []stats.Measurement{runsMeasure.M(1), runTimeMeasure.M(tr.RunTime.Seconds())}
+	switch tr.State {
+	case mockingbird.SUCCESSFUL:
+		ms = append(ms, lastSuccessMeasure.M(done))
+	case mockingbird.FAILED, mockingbird.TIMED_OUT:
+		ms = append(ms, lastFailureMeasure.M(done))
+	}
+	stats.Record(ctx, ms...)
+}
+
+// recordQueue records the number of queued and running test suites
+func recordQueue(queued, running int) {
+	stats.Record(context.Background(), queueDepthMeasure.M(int64(queued)), runningMeasure.M(int64(running)))
+}
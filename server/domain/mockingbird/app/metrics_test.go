@@ -0,0 +1,89 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestRecordRun_And_RecordQueue(t *testing.T) {
+	testdata.AssertNil(t, view.Register(Views...))
+	defer view.Unregister(Views...)
+
+	start := time.Date(2019, 1, 2, 3, 4, 0, 0, time.UTC)
+	recordRun(mockingbird.TestResult{TestSuite: "all:test", State: mockingbird.FAILED, StartTime: start, RunTime: 5 * time.Second})
+	recordRun(mockingbird.TestResult{TestSuite: "all:test", State: mockingbird.FAILED, StartTime: start, RunTime: 50 * time.Second})
+	recordRun(mockingbird.TestResult{TestSuite: "all:test", State: mockingbird.SUCCESSFUL, StartTime: start, RunTime: 20 * time.Second})
+
+	q := newQueue(nil)
+	q.push(job{id: "1", suite: "all:test"})
+	q.push(job{id: "2", suite: "all:test"})
+	_, _ = q.next()
+
+	testCases := []struct {
+		view string
+		want map[string]float64 // by the tag values of the row, sorted by tag key
+	}{
+		{
+			view: "test_suite_runs_total",
+			want: map[string]float64{"failed all:test": 2, "successful all:test": 1},
+		},
+		{
+			view: "test_suite_last_failure_timestamp_seconds",
+			want: map[string]float64{"all:test": float64(start.Add(50 * time.Second).Unix())},
+		},
+		{
+			view: "test_suite_last_success_timestamp_seconds",
+			want: map[string]float64{"all:test": float64(start.Add(20 * time.Second).Unix())},
+		},
+		{view: "queue_depth", want: map[string]float64{"": 1}},
+		{view: "test_suites_running", want: map[string]float64{"": 1}},
+	}
+
+	for _, tc := range testCases {
+		rows, err := view.RetrieveData(tc.view)
+		testdata.AssertNil(t, err)
+
+		got := map[string]float64{}
+		for _, row := range rows {
+			var key string
+			for i, tag := range row.Tags {
+				if i > 0 {
+					key += " "
+				}
+				key += tag.Value
+			}
+
+			switch data := row.Data.(type) {
+			case *view.CountData:
+				got[key] = float64(data.Value)
+			case *view.LastValueData:
+				got[key] = data.Value
+			}
+		}
+
+		if len(tc.want) != len(got) {
+			t.Errorf("%s\nWant: %v\n Got: %v\n", tc.view, tc.want, got)
+			continue
+		}
+		for key, want := range tc.want {
+			if got[key] != want {
+				t.Errorf("%s %s\nWant: %v\n Got: %v\n", tc.view, key, want, got[key])
+			}
+		}
+	}
+
+	rows, err := view.RetrieveData("test_suite_run_time_seconds")
+	testdata.AssertNil(t, err)
+	if len(rows) != 1 {
+		t.Fatalf("\nWant: 1 row\n Got: %d\n", len(rows))
+	}
+	d := rows[0].Data.(*view.DistributionData)
+	if d.Count != 3 || d.Sum() != 75 {
+		t.Errorf("\nWant: count=3 sum=75\n Got: count=%d sum=%v\n", d.Count, d.Sum())
+	}
+}
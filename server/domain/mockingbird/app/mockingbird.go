@@ -1,12 +1,19 @@
 package app
 
 import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/unders/mockingbird/server/pkg/uuid"
+	"github.com/oklog/ulid"
 
 	"github.com/pkg/errors"
 
+	"github.com/unders/mockingbird/server/pkg/errs"
+
 	"github.com/unders/mockingbird/server/domain/mockingbird"
 )
 
@@ -18,44 +25,182 @@ import (
 //
 //
 type Mockingbird struct {
-	worker     *worker
-	testSuites []mockingbird.TestSuite
+	worker      *worker
+	history     *history
+	scheduler   *scheduler
+	notifier    *notifier
+	testSuites  []mockingbird.TestSuite
+	deployRules mockingbird.DeployRules
+}
+
+// pool defines the size of the worker pool, the max number of
+// concurrent runs per test suite, the test suite timeouts and the
+// cron expressions of the scheduled test suites
+type pool struct {
+	workers   int
+	limits    map[mockingbird.TestSuite]int
+	timeout   time.Duration
+	timeouts  map[mockingbird.TestSuite]time.Duration
+	schedules map[mockingbird.TestSuite]string
 }
 
-func build(ts []mockingbird.TestSuite) *Mockingbird {
+func build(ts []mockingbird.TestSuite, p pool, windows []mockingbird.Window, rules mockingbird.DeployRules, n *notifier, store mockingbird.Store, l mockingbird.Log) (*Mockingbird, error) {
+	workers := p.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	if err := validateDeployRules(ts, rules); err != nil {
+		return nil, err
+	}
+
+	rt, err := loadRunTimes(store, 10*runTimeSamples)
+	if err != nil {
+		return nil, errors.Wrap(err, "loadRunTimes() failed")
+	}
+
+	h, err := loadHistory(store, windows, time.Now().UTC())
+	if err != nil {
+		return nil, errors.Wrap(err, "loadHistory() failed")
+	}
+
 	w := worker{
-		work: make(chan mockingbird.ULID, 100),
+		queue:    newQueue(p.limits),
+		workers:  workers,
+		running:  map[mockingbird.ULID]*task{},
+		runTimes: rt,
+		history:  h,
+
+		timeout:  p.timeout,
+		timeouts: p.timeouts,
+		notifier: n,
 
-		RWMutex:     &sync.RWMutex{},
-		testResults: make(map[mockingbird.ULID]testResult),
+		Mutex: &sync.Mutex{},
+		store: store,
+		log:   l,
 	}
 
-	// start worker queue in the background
-	go func() { w.loop() }()
+	queued, err := w.recover()
+	if err != nil {
+		return nil, errors.Wrap(err, "w.recover() failed")
+	}
 
-	return &Mockingbird{worker: &w, testSuites: ts}
+	sched, err := newScheduler(&w, ts, p.schedules, queued)
+	if err != nil {
+		return nil, errors.Wrap(err, "newScheduler() failed")
+	}
+
+	// re-enqueue test results that were queued when the server stopped
+	for _, tr := range queued {
+		w.queue.push(job{id: tr.ID, suite: tr.TestSuite, span: startRunSpan(tr)})
+	}
+
+	// start the worker pool and the scheduler in the background
+	w.loops.Add(workers)
+	for slot := 1; slot <= workers; slot++ {
+		go w.loop(slot)
+	}
+	sched.start()
+
+	m := &Mockingbird{worker: &w, history: h, scheduler: sched, notifier: n, testSuites: ts, deployRules: rules}
+	return m, nil
+}
+
+// validateDeployRules returns an error if a rule has a test suite that does not exist
+func validateDeployRules(ts []mockingbird.TestSuite, rules mockingbird.DeployRules) error {
+	known := map[mockingbird.TestSuite]bool{}
+	for _, s := range ts {
+		known[s] = true
+	}
+
+	for i, r := range rules {
+		for _, s := range r.TestSuites {
+			if !known[s] {
+				return errors.Errorf("deploy rule %d has unknown test suite %s", i+1, s)
+			}
+		}
+	}
+	return nil
 }
 
 // Verifies that *Mockingbird implements mockingbird.App interface
 var _ mockingbird.App = &Mockingbird{}
 
+// Verifies that *Mockingbird implements mockingbird.LogStream interface
+var _ mockingbird.LogStream = &Mockingbird{}
+
+// logPollInterval is how often FollowLog checks the store for new log lines
+const logPollInterval = 500 * time.Millisecond
+
 //
 //  Fetches test suite results
 //
 
 // Dashboard returns the Dashboard
 func (m *Mockingbird) Dashboard() (mockingbird.Dashboard, error) {
-	return mockingbird.Dashboard{Stats: m.worker.getStats()}, nil
+	s, err := m.worker.getStats()
+	if err != nil {
+		return mockingbird.Dashboard{}, err
+	}
+
+	return mockingbird.Dashboard{Stats: s, Windows: m.history.windowStats()}, nil
 }
 
-// ListTests returns a list of test results
-func (m *Mockingbird) ListTests(pageToken string) (*mockingbird.TestResults, error) {
-	return m.worker.getTestResults(pageToken)
+// ListTests returns a page of the test results that match the query
+func (m *Mockingbird) ListTests(q mockingbird.ListTestsQuery) (*mockingbird.TestResults, error) {
+	return m.worker.getTestResults(q)
 }
 
-// ShowTest returns the test result for the given id
+// ShowTest returns the test result for the given id; a pending test
+// result has its queue position and ETA set
 func (m *Mockingbird) ShowTest(id mockingbird.ULID) (mockingbird.TestResult, error) {
-	return m.worker.getTestResult(id)
+	tr, err := m.worker.getTestResult(id)
+	if err != nil || !tr.IsPending() {
+		return tr, err
+	}
+
+	tr.Progress = m.worker.progress(tr)
+	return tr, nil
+}
+
+// FollowLog calls fn with new log lines until the test suite is done
+func (m *Mockingbird) FollowLog(ctx context.Context, id mockingbird.ULID, offset int, fn func(chunk string, offset int) error) error {
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+
+	for {
+		tr, err := m.worker.getTestResult(id)
+		if err != nil {
+			return err
+		}
+
+		done := tr.Status == mockingbird.DONE
+		if offset > len(tr.Log) {
+			offset = len(tr.Log)
+		}
+
+		// send complete lines only, unless the log is final
+		end := len(tr.Log)
+		if !done {
+			end = offset + strings.LastIndex(tr.Log[offset:], "\n") + 1
+		}
+		if end > offset {
+			if err := fn(tr.Log[offset:end], end); err != nil {
+				return err
+			}
+			offset = end
+		}
+
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
 // ShowTestSuites returns the test suites
@@ -63,26 +208,97 @@ func (m *Mockingbird) ShowTestSuites() []mockingbird.TestSuite {
 	return m.testSuites
 }
 
+// ShowSchedules returns the scheduled test suites with their next run
+func (m *Mockingbird) ShowSchedules() []mockingbird.Schedule {
+	if m.scheduler == nil {
+		return nil
+	}
+	return m.scheduler.schedules()
+}
+
 //
 // Executes a test suite
 //
 
-// RunTest executes the given test suite
-func (m *Mockingbird) RunTest(s mockingbird.TestSuite) (mockingbird.ULID, error) {
+// RunTest executes the given test suite; user is the user that started it.
+// It returns an errs.NotFound error when the test suite does not exist
+func (m *Mockingbird) RunTest(s mockingbird.TestSuite, user string) (mockingbird.ULID, error) {
+	if !m.hasTestSuite(s) {
+		return "", errs.NotFound(fmt.Sprintf("test suite %s not found", s))
+	}
+
 	id, err := newID()
 	if err != nil {
 		return "", errors.Wrap(err, "newID() failed")
 	}
-	m.worker.add(id, s)
+	if err := m.worker.add(mockingbird.TestResult{ID: id, TestSuite: s, Trigger: mockingbird.MANUAL, TriggeredBy: user}); err != nil {
+		return "", errors.Wrap(err, "m.worker.add() failed")
+	}
 
 	return id, nil
 }
 
+// RunDeploy executes the test suites of the deploy rules that match the
+// deployment; no test suite is executed when no rule matches
+func (m *Mockingbird) RunDeploy(d mockingbird.Deployment) ([]mockingbird.ULID, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	var ids []mockingbird.ULID
+	for _, s := range m.deployRules.TestSuites(d) {
+		id, err := newID()
+		if err != nil {
+			return ids, errors.Wrap(err, "newID() failed")
+		}
+
+		deployment := d
+		tr := mockingbird.TestResult{ID: id, TestSuite: s, Trigger: mockingbird.DEPLOY, Deployment: &deployment}
+		if err := m.worker.add(tr); err != nil {
+			return ids, errors.Wrap(err, "m.worker.add() failed")
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// CancelTest cancels a queued or running test suite
+//
+// Note:
+//
+//        A running test suite is interrupted and CancelTest waits for
+//        it to exit; cancelling a test suite that is done is a no-op.
+//
+func (m *Mockingbird) CancelTest(id mockingbird.ULID) error {
+	return m.worker.cancel(id)
+}
+
+//
+// Fetches the notification delivery log
+//
+
+// ShowDeliveries returns the latest notification deliveries, newest first
+func (m *Mockingbird) ShowDeliveries() []mockingbird.Delivery {
+	return m.notifier.list()
+}
+
+// hasTestSuite returns true if the test suite exists
+func (m *Mockingbird) hasTestSuite(s mockingbird.TestSuite) bool {
+	for _, ts := range m.testSuites {
+		if ts == s {
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a ULID, so test results sort in the order they were created
 func newID() (mockingbird.ULID, error) {
-	id, err := uuid.NewV4()
+	id, err := ulid.New(ulid.Now(), rand.Reader)
 	if err != nil {
-		return "", errors.Wrap(err, "uuid.NewV4() failed")
+		return "", errors.Wrap(err, "ulid.New() failed")
 	}
 
-	return mockingbird.ULID(id), nil
+	return mockingbird.ULID(id.String()), nil
 }
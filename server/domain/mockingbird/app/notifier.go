@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// Notification delivery settings
+const (
+	notifyAttempts  = 4                // the first attempt and 3 retries
+	notifyBackoff   = 2 * time.Second  // doubled after each failed attempt
+	notifyTimeout   = 10 * time.Second // of each attempt
+	deliveryLogSize = 100              // the number of deliveries that are kept
+)
+
+// notifier sends a notification to each subscription whose filter passes a finished test result
+//
+// Note:
+//
+//        Notifications are sent in the background, so a slow receiver
+//        never holds up the worker. A failed attempt is retried with
+//        exponential backoff. The delivery log is kept in memory; it is
+//        lost on restart.
+//
+type notifier struct {
+	subscriptions []mockingbird.Subscription
+	baseURL       string // of the links to the test result pages, e.g: https://mockingbird.example.com
+	attempts      int
+	backoff       time.Duration
+	log           mockingbird.Log
+
+	sync.Mutex
+	deliveries []mockingbird.Delivery // newest first
+	closed     bool                   // is set by close; no notifications are sent after it
+
+	wg sync.WaitGroup // of the deliveries that are in progress
+}
+
+func newNotifier(subscriptions []mockingbird.Subscription, baseURL string, l mockingbird.Log) *notifier {
+	return &notifier{
+		subscriptions: subscriptions,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		attempts:      notifyAttempts,
+		backoff:       notifyBackoff,
+		log:           l,
+	}
+}
+
+// notify sends the notifications of a finished test result; previous is
+// the state of the previous run of its test suite
+func (n *notifier) notify(tr mockingbird.TestResult, previous mockingbird.State) {
+	if n == nil {
+		return
+	}
+
+	notification := mockingbird.Notification{
+		Result:   tr,
+		Previous: previous,
+		URL:      fmt.Sprintf("%s/tests/%s", n.baseURL, tr.ID),
+	}
+
+	n.Lock()
+	defer n.Unlock()
+	if n.closed {
+		n.log.Notice("notify skipped, the notifier is closed", mockingbird.KV("test_result_id", tr.ID))
+		return
+	}
+	for _, s := range n.subscriptions {
+		if !s.Match(notification) {
+			continue
+		}
+
+		n.wg.Add(1)
+		go func(s mockingbird.Subscription) {
+			defer n.wg.Done()
+			n.deliver(s.Notifier, notification)
+		}(s)
+	}
+}
+
+// close stops sending notifications and waits for the deliveries that are
+// in progress; it returns an error if ctx is done before them
+func (n *notifier) close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+
+	n.Lock()
+	n.closed = true
+	n.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "deliveries are in progress")
+	}
+}
+
+// list returns the delivery log, newest first
+func (n *notifier) list() []mockingbird.Delivery {
+	if n == nil {
+		return nil
+	}
+
+	n.Lock()
+	defer n.Unlock()
+	return append([]mockingbird.Delivery(nil), n.deliveries...)
+}
+
+//
+// PRIVATE
+//
+
+// deliver sends the notification until it succeeds or all attempts have failed
+func (n *notifier) deliver(to mockingbird.Notifier, notification mockingbird.Notification) {
+	d := mockingbird.Delivery{
+		TestResultID: notification.Result.ID,
+		TestSuite:    notification.Result.TestSuite,
+		Event:        notification.Event(),
+		Notifier:     to.String(),
+	}
+
+	backoff := n.backoff
+	for d.Attempts < n.attempts {
+		if d.Attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		d.Attempts++
+
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		err := to.Notify(ctx, notification)
+		cancel()
+		if err == nil {
+			d.Error = ""
+			break
+		}
+
+		d.Error = err.Error()
+		n.log.Notice("notify failed", mockingbird.KV("test_result_id", d.TestResultID), mockingbird.KV("notifier", d.Notifier),
+			mockingbird.KV("attempt", d.Attempts), mockingbird.KV("error", err))
+	}
+
+	if !d.IsDelivered() {
+		n.log.Error("notify gave up", mockingbird.KV("test_result_id", d.TestResultID), mockingbird.KV("notifier", d.Notifier),
+			mockingbird.KV("attempts", d.Attempts))
+	}
+
+	d.Time = time.Now().UTC()
+	n.record(d)
+}
+
+func (n *notifier) record(d mockingbird.Delivery) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.deliveries = append([]mockingbird.Delivery{d}, n.deliveries...)
+	if len(n.deliveries) > deliveryLogSize {
+		n.deliveries = n.deliveries[:deliveryLogSize]
+	}
+}
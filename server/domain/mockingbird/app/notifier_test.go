@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/memory"
+	"github.com/unders/mockingbird/server/domain/mockingbird/mock"
+	"github.com/unders/mockingbird/server/domain/mockingbird/notify"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestNotifier_Notify_RetriesUntilDelivered(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		posts int
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		posts++
+		if posts < 3 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	n := newTestNotifier(receiver.URL, mockingbird.FAILURES)
+	n.notify(mockingbird.TestResult{ID: "01CZ0000000000000000000001", TestSuite: "google:test", State: mockingbird.FAILED}, mockingbird.SUCCESSFUL)
+	testdata.AssertNil(t, n.close(context.Background()))
+
+	deliveries := n.list()
+	if len(deliveries) != 1 {
+		t.Fatalf("\nWant: 1 delivery\n Got: %+v\n", deliveries)
+	}
+	d := deliveries[0]
+	testdata.AssertTrue(t, d.IsDelivered())
+	if want, got := 3, d.Attempts; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := "failure", d.Event; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestNotifier_Notify_WhenAllAttemptsFail_RecordsTheError(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	n := newTestNotifier(receiver.URL, mockingbird.ALL_RUNS)
+	n.notify(mockingbird.TestResult{ID: "01CZ0000000000000000000001", State: mockingbird.SUCCESSFUL}, "")
+	testdata.AssertNil(t, n.close(context.Background()))
+
+	deliveries := n.list()
+	if len(deliveries) != 1 {
+		t.Fatalf("\nWant: 1 delivery\n Got: %+v\n", deliveries)
+	}
+	d := deliveries[0]
+	testdata.AssertTrue(t, !d.IsDelivered())
+	if want, got := notifyAttempts, d.Attempts; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := "POST "+receiver.URL+" returned 503 down", d.Error; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestNotifier_Notify_SkipsTheTestResultsThatAreFilteredOut(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("\nWant: no request\n Got: %s %s\n", req.Method, req.URL)
+	}))
+	defer receiver.Close()
+
+	n := newTestNotifier(receiver.URL, mockingbird.RECOVERIES)
+	n.notify(mockingbird.TestResult{ID: "01CZ0000000000000000000001", State: mockingbird.SUCCESSFUL}, mockingbird.SUCCESSFUL)
+	n.notify(mockingbird.TestResult{ID: "01CZ0000000000000000000002", State: mockingbird.FAILED}, mockingbird.SUCCESSFUL)
+	testdata.AssertNil(t, n.close(context.Background()))
+
+	testdata.AssertTrue(t, len(n.list()) == 0)
+}
+
+func TestNotifier_Close_StopsSendingNotifications(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("\nWant: no request\n Got: %s %s\n", req.Method, req.URL)
+	}))
+	defer receiver.Close()
+
+	n := newTestNotifier(receiver.URL, mockingbird.ALL_RUNS)
+	testdata.AssertNil(t, n.close(context.Background()))
+	n.notify(mockingbird.TestResult{ID: "01CZ0000000000000000000001", State: mockingbird.FAILED}, "")
+	testdata.AssertNil(t, n.close(context.Background()))
+
+	testdata.AssertTrue(t, len(n.list()) == 0)
+}
+
+func TestNotifier_Close_WhenTheDeliveriesAreNotDone_ReturnsError(t *testing.T) {
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { <-release }))
+	defer receiver.Close()
+
+	n := newTestNotifier(receiver.URL, mockingbird.ALL_RUNS)
+	n.notify(mockingbird.TestResult{ID: "01CZ0000000000000000000001", State: mockingbird.FAILED}, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	testdata.AssertErr(t, n.close(ctx))
+
+	close(release)
+	testdata.AssertNil(t, n.close(context.Background()))
+	testdata.AssertTrue(t, len(n.list()) == 1)
+}
+
+func TestWorker_UpdateStats_ReturnsThePreviousState(t *testing.T) {
+	w := newTestWorker(memory.NewStore())
+
+	testCases := []struct {
+		state mockingbird.State
+		want  mockingbird.State
+	}{
+		{state: mockingbird.FAILED, want: ""},
+		{state: mockingbird.SUCCESSFUL, want: mockingbird.FAILED},
+	}
+	for _, tc := range testCases {
+		got, err := w.updateStats(mockingbird.TestResult{TestSuite: "google:test", State: tc.state})
+		testdata.AssertNil(t, err)
+		if tc.want != got {
+			t.Errorf("\nWant: %q\n Got: %q\n", tc.want, got)
+		}
+	}
+}
+
+func newTestNotifier(url string, filter mockingbird.NotifyFilter) *notifier {
+	subs := []mockingbird.Subscription{{Filter: filter, Notifier: notify.Webhook{URL: url}}}
+	n := newNotifier(subs, "https://mockingbird.example.com/", &mock.Log{})
+	n.backoff = time.Millisecond
+	return n
+}
@@ -0,0 +1,23 @@
+// +build !windows
+
+package app
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the command and all its child processes
+func killProcessGroup(cmd *exec.Cmd) {
+	// a negative pid signals the process group
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// interruptProcessGroup asks the command and all its child processes to stop
+func interruptProcessGroup(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
@@ -0,0 +1,17 @@
+package app
+
+import (
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command; child processes are not killed on windows
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
+
+// interruptProcessGroup kills the command; windows has no SIGTERM
+func interruptProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"sync"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+type job struct {
+	id    mockingbird.ULID
+	suite mockingbird.TestSuite
+	span  *runSpan // nil when the run is not traced
+}
+
+// queue hands out queued test results to the worker pool
+//
+// Note:
+//
+//        next returns the oldest job whose test suite is below its
+//        concurrency limit, so a slow test suite at its limit does
+//        not block the other test suites behind it.
+//
+type queue struct {
+	*sync.Cond
+
+	pending []job
+	running map[mockingbird.TestSuite]int
+	limits  map[mockingbird.TestSuite]int
+	closed  bool // is set by close; no jobs are handed out after it
+}
+
+// newQueue returns a queue; a test suite without a limit, or with a
+// limit below 1, is only limited by the size of the worker pool
+func newQueue(limits map[mockingbird.TestSuite]int) *queue {
+	return &queue{
+		Cond:    sync.NewCond(&sync.Mutex{}),
+		running: map[mockingbird.TestSuite]int{},
+		limits:  limits,
+	}
+}
+
+func (q *queue) push(j job) {
+	q.L.Lock()
+	q.pending = append(q.pending, j)
+	q.record()
+	q.L.Unlock()
+	q.Broadcast()
+}
+
+// next blocks until a job can run, or returns false when the queue is
+// closed; call done when the job is finished
+func (q *queue) next() (job, bool) {
+	q.L.Lock()
+	defer q.L.Unlock()
+
+	for {
+		if q.closed {
+			return job{}, false
+		}
+
+		for i, j := range q.pending {
+			if q.isFull(j.suite) {
+				continue
+			}
+
+			q.pending = append(q.pending[:i:i], q.pending[i+1:]...)
+			q.running[j.suite] = q.running[j.suite] + 1
+			q.record()
+			return j, true
+		}
+		q.Wait()
+	}
+}
+
+// remove removes a pending job; it returns false if no job has the id
+func (q *queue) remove(id mockingbird.ULID) (job, bool) {
+	q.L.Lock()
+	defer q.L.Unlock()
+
+	for i, j := range q.pending {
+		if j.id == id {
+			q.pending = append(q.pending[:i:i], q.pending[i+1:]...)
+			q.record()
+			return j, true
+		}
+	}
+	return job{}, false
+}
+
+func (q *queue) done(j job) {
+	q.L.Lock()
+	q.running[j.suite] = q.running[j.suite] - 1
+	q.record()
+	q.L.Unlock()
+	q.Broadcast()
+}
+
+// close wakes the blocked next calls; the pending jobs are kept, they
+// are queued in the store and added again on the next start
+func (q *queue) close() {
+	q.L.Lock()
+	q.closed = true
+	q.L.Unlock()
+	q.Broadcast()
+}
+
+// snapshot returns a copy of the pending jobs
+func (q *queue) snapshot() []job {
+	q.L.Lock()
+	defer q.L.Unlock()
+
+	return append([]job(nil), q.pending...)
+}
+
+// record records the queue depth and the running jobs; it must be called with the lock held
+func (q *queue) record() {
+	n := 0
+	for _, running := range q.running {
+		n += running
+	}
+	recordQueue(len(q.pending), n)
+}
+
+func (q *queue) isFull(s mockingbird.TestSuite) bool {
+	limit, ok := q.limits[s]
+	return ok && limit > 0 && q.running[s] >= limit
+}
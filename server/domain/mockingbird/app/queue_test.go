@@ -0,0 +1,78 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+func TestQueue_Next_SkipsTestSuitesAtTheirLimit(t *testing.T) {
+	q := newQueue(map[mockingbird.TestSuite]int{mockingbird.FullTestSuite: 1})
+
+	q.push(job{id: "1", suite: mockingbird.FullTestSuite})
+	q.push(job{id: "2", suite: mockingbird.FullTestSuite})
+	q.push(job{id: "3", suite: "google:test"})
+	q.push(job{id: "4", suite: "google:test"})
+
+	first, _ := q.next()
+	if want, got := mockingbird.ULID("1"), first.id; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	for _, want := range []mockingbird.ULID{"3", "4"} {
+		if j, _ := q.next(); want != j.id {
+			t.Errorf("\nWant: %s\n Got: %s\n", want, j.id)
+		}
+	}
+
+	next := make(chan job)
+	go func() {
+		j, _ := q.next()
+		next <- j
+	}()
+
+	select {
+	case j := <-next:
+		t.Fatalf("\nWant: next to block\n Got: %s\n", j.id)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.done(first)
+
+	select {
+	case j := <-next:
+		if want, got := mockingbird.ULID("2"), j.id; want != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("\nWant: job 2\n Got: next is blocked\n")
+	}
+}
+
+func TestQueue_Close_WakesNextAndKeepsThePendingJobs(t *testing.T) {
+	q := newQueue(map[mockingbird.TestSuite]int{mockingbird.FullTestSuite: 1})
+	q.push(job{id: "1", suite: mockingbird.FullTestSuite})
+	q.push(job{id: "2", suite: mockingbird.FullTestSuite})
+	_, _ = q.next()
+
+	next := make(chan bool)
+	go func() {
+		_, ok := q.next()
+		next <- ok
+	}()
+
+	q.close()
+
+	select {
+	case ok := <-next:
+		if ok {
+			t.Error("\nWant: no job after close\n Got: a job\n")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("\nWant: next to return\n Got: next is blocked\n")
+	}
+
+	if want, got := 1, len(q.snapshot()); want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+}
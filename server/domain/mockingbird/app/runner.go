@@ -0,0 +1,109 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// errTimedOut is returned by run when the test suite ran past its deadline
+var errTimedOut = errors.New("timed out")
+
+// killDelay is how long a cancelled command gets to exit before it is killed
+const killDelay = 5 * time.Second
+
+// run executes the command, with env added to the environment of the
+// server, and writes its stdout and stderr to out
+//
+// Note:
+//
+//        The command is started in its own process group; when ctx is
+//        done the whole process tree is killed, so a hung test suite
+//        cannot keep its worker slot (or leave orphan processes).
+//
+//        * deadline exceeded: the process group is killed, errTimedOut is returned
+//        * cancelled: the process group is interrupted and killed after
+//          killDelay, context.Canceled is returned
+//
+func run(ctx context.Context, out io.Writer, env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return errors.Wrapf(err, "exec %s failed", name)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		killProcessGroup(cmd)
+		<-done
+		return errTimedOut
+	}
+
+	interruptProcessGroup(cmd)
+	select {
+	case <-done:
+	case <-time.After(killDelay):
+		killProcessGroup(cmd)
+		<-done
+	}
+	return ctx.Err()
+}
+
+// logBuffer collects the output of a running test suite
+type logBuffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	changed bool
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.changed = true
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// flush returns the log and true if the log changed since the last flush
+func (b *logBuffer) flush() (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	changed := b.changed
+	b.changed = false
+	return b.buf.String(), changed
+}
+
+// appendLine appends line to log on a line of its own
+func appendLine(log, line string) string {
+	if log == "" || strings.HasSuffix(log, "\n") {
+		return log + line
+	}
+	return log + "\n" + line
+}
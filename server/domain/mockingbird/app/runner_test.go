@@ -0,0 +1,66 @@
+// +build !windows
+
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestRun_ReturnsOutput(t *testing.T) {
+	out := &logBuffer{}
+	err := run(context.Background(), out, nil, "sh", "-c", "echo ok; echo fail >&2")
+	testdata.AssertNil(t, err)
+
+	if want, got := "ok\nfail\n", out.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestRun_WhenTimeout_KillsProcessTree(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	// the child shell holds stdout open, so run only returns if it is killed too
+	out := &logBuffer{}
+	err := run(ctx, out, nil, "sh", "-c", "echo started; sh -c 'sleep 30'; echo done")
+
+	if errTimedOut != err {
+		t.Errorf("\nWant: %s\n Got: %v\n", errTimedOut, err)
+	}
+	if want, got := "started\n", out.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("\nWant: < 10s\n Got: %s\n", elapsed)
+	}
+}
+
+func TestRun_WhenCancelled_InterruptsProcessTree(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := run(ctx, &logBuffer{}, nil, "sh", "-c", "sh -c 'sleep 30'")
+
+	if context.Canceled != err {
+		t.Errorf("\nWant: %s\n Got: %v\n", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > killDelay {
+		t.Errorf("\nWant: < %s\n Got: %s\n", killDelay, elapsed)
+	}
+}
+
+func TestRun_AddsEnv(t *testing.T) {
+	out := &logBuffer{}
+	err := run(context.Background(), out, []string{testJSONEnv}, "sh", "-c", "echo $MOCKINGBIRD_TEST_JSON")
+	testdata.AssertNil(t, err)
+
+	if want, got := "1\n", out.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/cron"
+	"github.com/unders/mockingbird/server/pkg/errs"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// scheduler runs the test suites that have a cron expression
+//
+// Note:
+//
+//        A scheduled run is added to the worker like a manual run, but it
+//        is tagged with the SCHEDULED trigger. A tick is skipped while the
+//        previous scheduled run of the test suite is queued or running.
+//
+type scheduler struct {
+	worker *worker
+	suites []scheduledSuite // sorted by test suite name
+	quit   chan struct{}    // is closed by stop
+
+	sync.Mutex
+	next   map[mockingbird.TestSuite]time.Time
+	latest map[mockingbird.TestSuite]mockingbird.ULID // the latest scheduled run
+}
+
+type scheduledSuite struct {
+	suite    mockingbird.TestSuite
+	expr     string
+	schedule cron.Schedule
+}
+
+// newScheduler returns a scheduler of the cron expressions, e.g: {"google:test": "*/5 * * * *"};
+// queued are the test results that were queued when the server stopped
+func newScheduler(w *worker, ts []mockingbird.TestSuite, exprs map[mockingbird.TestSuite]string, queued []mockingbird.TestResult) (*scheduler, error) {
+	known := map[mockingbird.TestSuite]bool{}
+	for _, s := range ts {
+		known[s] = true
+	}
+
+	s := &scheduler{
+		worker: w,
+		quit:   make(chan struct{}),
+		next:   map[mockingbird.TestSuite]time.Time{},
+		latest: map[mockingbird.TestSuite]mockingbird.ULID{},
+	}
+	for suite, expr := range exprs {
+		if !known[suite] {
+			return nil, errors.Errorf("schedule of unknown test suite %s", suite)
+		}
+
+		schedule, err := cron.Parse(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "schedule of %s", suite)
+		}
+		s.suites = append(s.suites, scheduledSuite{suite: suite, expr: expr, schedule: schedule})
+	}
+	sort.Slice(s.suites, func(i, j int) bool { return s.suites[i].suite < s.suites[j].suite })
+
+	for _, tr := range queued {
+		if tr.IsScheduled() {
+			s.latest[tr.TestSuite] = tr.ID
+		}
+	}
+	return s, nil
+}
+
+// start runs each scheduled test suite in the background
+func (s *scheduler) start() {
+	now := time.Now().UTC()
+	for _, ss := range s.suites {
+		s.setNext(ss.suite, ss.schedule.Next(now))
+		go s.loop(ss)
+	}
+}
+
+// stop stops the loops of the scheduled test suites; it must be called once
+func (s *scheduler) stop() {
+	close(s.quit)
+}
+
+// loop sleeps until the next time of the schedule and runs the test suite; it
+// stops when the scheduler is stopped or the schedule never matches again
+func (s *scheduler) loop(ss scheduledSuite) {
+	for {
+		next := s.nextRun(ss.suite)
+		if next.IsZero() {
+			return
+		}
+
+		select {
+		case <-time.After(time.Until(next)):
+		case <-s.quit:
+			return
+		}
+
+		if err := s.tick(ss.suite); err != nil {
+			s.worker.log.Error("scheduled run failed", mockingbird.KV("test_suite", ss.suite), mockingbird.KV("error", err))
+		}
+
+		// from now, so ticks that were missed while a tick was slow are skipped
+		s.setNext(ss.suite, ss.schedule.Next(time.Now().UTC()))
+	}
+}
+
+// tick adds a scheduled run of the test suite, unless its previous scheduled run is pending
+func (s *scheduler) tick(suite mockingbird.TestSuite) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if id, ok := s.latest[suite]; ok {
+		tr, err := s.worker.getTestResult(id)
+		if err != nil && !errs.IsNotFound(err) {
+			return err
+		}
+		if err == nil && tr.IsPending() {
+			s.worker.log.Info("scheduled run skipped", mockingbird.KV("test_suite", suite), mockingbird.KV("test_result_id", id), mockingbird.KV("status", tr.Status))
+			return nil
+		}
+	}
+
+	id, err := newID()
+	if err != nil {
+		return errors.Wrap(err, "newID() failed")
+	}
+	if err := s.worker.add(mockingbird.TestResult{ID: id, TestSuite: suite, Trigger: mockingbird.SCHEDULED}); err != nil {
+		return errors.Wrap(err, "s.worker.add() failed")
+	}
+
+	s.latest[suite] = id
+	return nil
+}
+
+// schedules returns the schedules with their next run
+func (s *scheduler) schedules() []mockingbird.Schedule {
+	s.Lock()
+	defer s.Unlock()
+
+	schedules := make([]mockingbird.Schedule, 0, len(s.suites))
+	for _, ss := range s.suites {
+		schedules = append(schedules, mockingbird.Schedule{
+			TestSuite:  ss.suite,
+			Expression: ss.expr,
+			Next:       s.next[ss.suite],
+		})
+	}
+	return schedules
+}
+
+func (s *scheduler) nextRun(suite mockingbird.TestSuite) time.Time {
+	s.Lock()
+	defer s.Unlock()
+	return s.next[suite]
+}
+
+func (s *scheduler) setNext(suite mockingbird.TestSuite, t time.Time) {
+	s.Lock()
+	s.next[suite] = t
+	s.Unlock()
+}
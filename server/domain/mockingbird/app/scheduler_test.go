@@ -0,0 +1,104 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/memory"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestScheduler_Tick_SkipsWhileThePreviousScheduledRunIsPending(t *testing.T) {
+	store := memory.NewStore()
+	w := newTestWorker(store)
+	ts := []mockingbird.TestSuite{"google:test"}
+	s, err := newScheduler(w, ts, map[mockingbird.TestSuite]string{"google:test": "*/5 * * * *"}, nil)
+	testdata.AssertNil(t, err)
+
+	testdata.AssertNil(t, s.tick("google:test"))
+	first := s.latest["google:test"]
+
+	tr, err := store.GetTestResult(first)
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, tr.IsScheduled())
+	testdata.AssertTrue(t, tr.IsQueued())
+
+	// skipped, the first run is still queued
+	testdata.AssertNil(t, s.tick("google:test"))
+	if want, got := first, s.latest["google:test"]; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	if want, got := 1, len(w.queue.snapshot()); want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	testdata.AssertNil(t, w.cancel(first))
+	testdata.AssertNil(t, s.tick("google:test"))
+	if s.latest["google:test"] == first {
+		t.Errorf("\nWant: a new scheduled run\n Got: %s\n", first)
+	}
+}
+
+func TestNewScheduler_ReturnsSchedulesAndTheQueuedScheduledRuns(t *testing.T) {
+	w := newTestWorker(memory.NewStore())
+	ts := []mockingbird.TestSuite{"all:test", "google:test"}
+	exprs := map[mockingbird.TestSuite]string{"google:test": "@hourly", "all:test": "0 2 * * *"}
+	queued := []mockingbird.TestResult{
+		{ID: "1", TestSuite: "google:test", Trigger: mockingbird.MANUAL},
+		{ID: "2", TestSuite: "google:test", Trigger: mockingbird.SCHEDULED},
+	}
+
+	s, err := newScheduler(w, ts, exprs, queued)
+	testdata.AssertNil(t, err)
+
+	if want, got := mockingbird.ULID("2"), s.latest["google:test"]; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	schedules := s.schedules()
+	if want, got := 2, len(schedules); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := mockingbird.TestSuite("all:test"), schedules[0].TestSuite; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	if want, got := "0 2 * * *", schedules[0].Expression; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestNewScheduler_WhenInvalid_ReturnsError(t *testing.T) {
+	w := newTestWorker(memory.NewStore())
+	ts := []mockingbird.TestSuite{"google:test"}
+
+	for _, exprs := range []map[mockingbird.TestSuite]string{
+		{"unknown:test": "@hourly"},
+		{"google:test": "every hour"},
+	} {
+		if _, err := newScheduler(w, ts, exprs, nil); err == nil {
+			t.Errorf("%v\nWant: error\n Got: nil\n", exprs)
+		}
+	}
+}
+
+func TestScheduler_Stop_StopsTheLoop(t *testing.T) {
+	w := newTestWorker(memory.NewStore())
+	ts := []mockingbird.TestSuite{"google:test"}
+	s, err := newScheduler(w, ts, map[mockingbird.TestSuite]string{"google:test": "@hourly"}, nil)
+	testdata.AssertNil(t, err)
+	s.setNext("google:test", time.Now().Add(time.Hour))
+
+	done := make(chan struct{})
+	go func() {
+		s.loop(s.suites[0])
+		close(done)
+	}()
+	s.stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("\nWant: the loop is stopped\n Got: it still waits for the next run\n")
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"time"
+
+	"github.com/montanaflynn/stats"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// suiteRunTimeSamples is the number of run times of a test suite the percentiles are computed from
+const suiteRunTimeSamples = 100
+
+// addSuiteStats adds a done test result to the stats of its test suite
+func addSuiteStats(s mockingbird.Stats, tr mockingbird.TestResult) mockingbird.Stats {
+	// copy, so the stats that were read from the store are not changed
+	suites := make(map[mockingbird.TestSuite]mockingbird.SuiteStats, len(s.Suites)+1)
+	for name, ss := range s.Suites {
+		suites[name] = ss
+	}
+	s.Suites = suites
+
+	ss := s.Suites[tr.TestSuite]
+	ss.TestSuite = tr.TestSuite
+	ss.LatestDoneID = tr.ID
+	ss.LatestDoneState = tr.State
+	ss.LatestDoneRunTime = tr.RunTime
+
+	ss.RunCounter = ss.RunCounter + 1
+	if tr.State == mockingbird.SUCCESSFUL {
+		ss.SuccessCounter = ss.SuccessCounter + 1
+	}
+	if tr.State == mockingbird.TIMED_OUT {
+		ss.TimeoutCounter = ss.TimeoutCounter + 1
+	}
+	ss.SuccessRate = (ss.SuccessCounter / ss.RunCounter) * 100
+
+	if tr.RunTime > ss.MaxRunTime {
+		ss.MaxRunTime = tr.RunTime
+	}
+
+	runTimes := make([]time.Duration, 0, len(ss.RunTimes)+1)
+	runTimes = append(runTimes, ss.RunTimes...)
+	runTimes = append(runTimes, tr.RunTime)
+	if len(runTimes) > suiteRunTimeSamples {
+		runTimes = runTimes[len(runTimes)-suiteRunTimeSamples:]
+	}
+	ss.RunTimes = runTimes
+
+	data := make(stats.Float64Data, 0, len(runTimes))
+	for _, d := range runTimes {
+		data = append(data, float64(d))
+	}
+	ss.P50RunTime = percentile(data, 50)
+	ss.P90RunTime = percentile(data, 90)
+	ss.P99RunTime = percentile(data, 99)
+
+	s.Suites[tr.TestSuite] = ss
+	return s
+}
+
+// percentile returns the nearest rank percentile; the median for p50
+func percentile(data stats.Float64Data, p float64) time.Duration {
+	var (
+		v   float64
+		err error
+	)
+	if p == 50 {
+		v, err = stats.Median(data)
+	} else {
+		v, err = stats.PercentileNearestRank(data, p)
+	}
+	if err != nil {
+		// only when data is empty
+		return 0
+	}
+	return time.Duration(v)
+}
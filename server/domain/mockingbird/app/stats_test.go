@@ -0,0 +1,90 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+func TestAddSuiteStats_BreaksOutStatsPerTestSuite(t *testing.T) {
+	s := mockingbird.Stats{}
+	for i := 1; i <= 10; i++ {
+		var state mockingbird.State = mockingbird.SUCCESSFUL
+		if i == 10 {
+			state = mockingbird.FAILED
+		}
+		s = addSuiteStats(s, mockingbird.TestResult{
+			ID:        mockingbird.ULID(string(rune('a' + i))),
+			TestSuite: "google:test",
+			State:     state,
+			RunTime:   time.Duration(i) * time.Second,
+		})
+	}
+	s = addSuiteStats(s, mockingbird.TestResult{ID: "z", TestSuite: mockingbird.FullTestSuite, State: mockingbird.TIMED_OUT, RunTime: time.Minute})
+
+	if want, got := 2, len(s.SuiteList()); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	testCases := []struct {
+		got  mockingbird.SuiteStats
+		want mockingbird.SuiteStats
+	}{
+		{
+			got: s.Suites["google:test"],
+			want: mockingbird.SuiteStats{
+				TestSuite:         "google:test",
+				RunCounter:        10,
+				SuccessCounter:    9,
+				SuccessRate:       90,
+				LatestDoneID:      "k",
+				LatestDoneState:   mockingbird.FAILED,
+				LatestDoneRunTime: 10 * time.Second,
+				P50RunTime:        5500 * time.Millisecond,
+				P90RunTime:        9 * time.Second,
+				P99RunTime:        10 * time.Second,
+				MaxRunTime:        10 * time.Second,
+			},
+		},
+		{
+			got: s.Suites[mockingbird.FullTestSuite],
+			want: mockingbird.SuiteStats{
+				TestSuite:         mockingbird.FullTestSuite,
+				RunCounter:        1,
+				TimeoutCounter:    1,
+				LatestDoneID:      "z",
+				LatestDoneState:   mockingbird.TIMED_OUT,
+				LatestDoneRunTime: time.Minute,
+				P50RunTime:        time.Minute,
+				P90RunTime:        time.Minute,
+				P99RunTime:        time.Minute,
+				MaxRunTime:        time.Minute,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := tc.got
+		got.RunTimes = nil
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("\nWant: %+v\n Got: %+v\n", tc.want, got)
+		}
+	}
+}
+
+func TestAddSuiteStats_KeepsTheLatestRunTimes(t *testing.T) {
+	s := mockingbird.Stats{}
+	for i := 0; i < suiteRunTimeSamples+5; i++ {
+		s = addSuiteStats(s, mockingbird.TestResult{TestSuite: "google:test", RunTime: time.Duration(i)})
+	}
+
+	runTimes := s.Suites["google:test"].RunTimes
+	if want, got := suiteRunTimeSamples, len(runTimes); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := time.Duration(5), runTimes[0]; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
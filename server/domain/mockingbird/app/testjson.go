@@ -0,0 +1,161 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// testJSONEnv makes the test suites run go test with the -json flag
+const testJSONEnv = "MOCKINGBIRD_TEST_JSON=1"
+
+// testEvent is a go test -json (test2json) event
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64 // seconds
+	Output  string
+}
+
+// testJSON parses go test -json output into test packages
+//
+// Note:
+//
+//        The output of each event, and every line that is not an
+//        event, is written to out as plain text, so the log reads like
+//        go test -v output.
+//
+type testJSON struct {
+	out  io.Writer
+	line []byte
+
+	packages []*testNode
+	index    map[string]*testNode // key: package + " " + test name
+}
+
+type testNode struct {
+	name     string
+	status   mockingbird.CaseStatus
+	elapsed  time.Duration
+	output   strings.Builder
+	children []*testNode
+}
+
+func newTestJSON(out io.Writer) *testJSON {
+	return &testJSON{out: out, index: map[string]*testNode{}}
+}
+
+// Write parses complete lines; a partial line is kept until the next Write or Close
+func (t *testJSON) Write(p []byte) (int, error) {
+	t.line = append(t.line, p...)
+
+	for {
+		i := bytes.IndexByte(t.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		if err := t.parse(t.line[:i+1]); err != nil {
+			return len(p), err
+		}
+		t.line = t.line[i+1:]
+	}
+}
+
+// Close parses the last line if it has no newline
+func (t *testJSON) Close() error {
+	if len(t.line) == 0 {
+		return nil
+	}
+
+	err := t.parse(t.line)
+	t.line = nil
+	return err
+}
+
+// Packages returns the parsed test packages
+func (t *testJSON) Packages() []mockingbird.TestPackage {
+	pkgs := make([]mockingbird.TestPackage, 0, len(t.packages))
+	for _, n := range t.packages {
+		pkgs = append(pkgs, mockingbird.TestPackage{
+			Name:    n.name,
+			Status:  n.status,
+			Elapsed: n.elapsed,
+			Output:  n.output.String(),
+			Tests:   testCases(n.children),
+		})
+	}
+	return pkgs
+}
+
+//
+// PRIVATE
+//
+
+func (t *testJSON) parse(line []byte) error {
+	e := testEvent{}
+	if !bytes.HasPrefix(line, []byte("{")) || json.Unmarshal(line, &e) != nil || e.Action == "" {
+		_, err := t.out.Write(line)
+		return err
+	}
+
+	n := t.node(e.Package, e.Test)
+	switch e.Action {
+	case "output":
+		n.output.WriteString(e.Output)
+		_, err := io.WriteString(t.out, e.Output)
+		return err
+	case "pass", "fail", "skip":
+		n.status = mockingbird.CaseStatus(e.Action)
+		n.elapsed = time.Duration(e.Elapsed * float64(time.Second))
+	}
+	return nil
+}
+
+// node returns the node for the test, creating it and its parents when missing
+func (t *testJSON) node(pkg, test string) *testNode {
+	key := pkg + " " + test
+	if n, ok := t.index[key]; ok {
+		return n
+	}
+
+	n := &testNode{name: test, status: mockingbird.RUN}
+	if test == "" {
+		n.name = pkg
+		t.packages = append(t.packages, n)
+		t.index[key] = n
+		return n
+	}
+
+	parent := t.node(pkg, "")
+	if i := strings.LastIndex(test, "/"); i > 0 {
+		parent = t.node(pkg, test[:i])
+	}
+	parent.children = append(parent.children, n)
+	t.index[key] = n
+	return n
+}
+
+func testCases(nodes []*testNode) []mockingbird.TestCase {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	cases := make([]mockingbird.TestCase, 0, len(nodes))
+	for _, n := range nodes {
+		cases = append(cases, mockingbird.TestCase{
+			Name:     n.name,
+			Status:   n.status,
+			Elapsed:  n.elapsed,
+			Output:   n.output.String(),
+			Subtests: testCases(n.children),
+		})
+	}
+	return cases
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestTestJSON_ParsesPackageTestAndSubtestTree(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/go-test.json")
+	testdata.AssertNil(t, err)
+
+	out := &bytes.Buffer{}
+	tests := newTestJSON(out)
+
+	// write in small chunks, so events are split over several writes
+	for len(b) > 0 {
+		n := 7
+		if n > len(b) {
+			n = len(b)
+		}
+		_, err := tests.Write(b[:n])
+		testdata.AssertNil(t, err)
+		b = b[n:]
+	}
+	testdata.AssertNil(t, tests.Close())
+
+	pkgs := tests.Packages()
+	if len(pkgs) != 1 {
+		t.Fatalf("\nWant: 1 package\n Got: %+v\n", pkgs)
+	}
+
+	pkg := pkgs[0]
+	if want, got := "example.com/search", pkg.Name; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	testdata.AssertTrue(t, pkg.IsFailed())
+	testdata.AssertTrue(t, strings.Contains(pkg.Output, "FAIL\texample.com/search"))
+
+	var got []string
+	for _, tc := range pkg.Tests {
+		got = append(got, tc.Name+" "+string(tc.Status))
+		for _, sub := range tc.Subtests {
+			got = append(got, sub.Name+" "+string(sub.Status))
+		}
+	}
+	want := []string{
+		"TestSearch fail",
+		"TestSearch/by_name pass",
+		"TestSearch/by_date fail",
+		"TestSkip skip",
+		"TestLogin pass",
+	}
+	if strings.Join(want, ",") != strings.Join(got, ",") {
+		t.Errorf("\nWant: %v\n Got: %v\n", want, got)
+	}
+
+	byDate := pkg.Tests[0].Subtests[1]
+	testdata.AssertTrue(t, strings.Contains(byDate.Output, "want 2 results, got 0"))
+	if byDate.Elapsed <= 0 {
+		t.Errorf("\nWant: > 0\n Got: %s\n", byDate.Elapsed)
+	}
+
+	log := out.String()
+	testdata.AssertTrue(t, strings.HasPrefix(log, "=== RUN   TestSearch\n"))
+	testdata.AssertTrue(t, strings.HasSuffix(log, "failed with exit code 1\n"))
+	testdata.AssertTrue(t, !strings.Contains(log, `"Action"`))
+}
+
+func TestTestJSON_WhenNotJSON_WritesOutput(t *testing.T) {
+	out := &bytes.Buffer{}
+	tests := newTestJSON(out)
+
+	_, err := tests.Write([]byte("ok\n{not json}\nno newline"))
+	testdata.AssertNil(t, err)
+	testdata.AssertNil(t, tests.Close())
+
+	if want, got := "ok\n{not json}\nno newline", out.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	if want, got := 0, len(tests.Packages()); want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+}
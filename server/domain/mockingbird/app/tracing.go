@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/tracing"
+)
+
+// runSpan traces a test suite run from when it is queued until it is done
+//
+// Note:
+//
+//        The mockingbird.run span has a mockingbird.queue child for the
+//        queue wait and a mockingbird.execute child for the run of the
+//        test suite; the test suite gets the context of the execute span
+//        in its environment, see tracing.Env. A nil runSpan is a no-op.
+//
+type runSpan struct {
+	root  *trace.Span
+	queue *trace.Span
+	exec  *trace.Span
+
+	queued time.Time
+	ended  bool
+}
+
+// startRunSpan starts the span of a queued test result
+func startRunSpan(tr mockingbird.TestResult) *runSpan {
+	ctx, root := trace.StartSpan(context.Background(), "mockingbird.run")
+	root.AddAttributes(
+		trace.StringAttribute("mockingbird.test_result_id", string(tr.ID)),
+		trace.StringAttribute("mockingbird.test_suite", string(tr.TestSuite)),
+		trace.StringAttribute("mockingbird.trigger", string(tr.Trigger)),
+	)
+	if tr.TriggeredBy != "" {
+		root.AddAttributes(trace.StringAttribute("mockingbird.triggered_by", tr.TriggeredBy))
+	}
+	if d := tr.Deployment; d != nil {
+		root.AddAttributes(
+			trace.StringAttribute("mockingbird.deployment.service", d.Service),
+			trace.StringAttribute("mockingbird.deployment.version", d.Version),
+		)
+	}
+
+	_, queue := trace.StartSpan(ctx, "mockingbird.queue")
+	return &runSpan{root: root, queue: queue, queued: time.Now()}
+}
+
+// start ends the queue wait and starts the execute span in the worker slot
+func (s *runSpan) start(slot int) {
+	if s == nil || s.ended {
+		return
+	}
+
+	s.queue.End()
+	wait := time.Since(s.queued)
+	s.root.AddAttributes(
+		trace.Int64Attribute("mockingbird.worker", int64(slot)),
+		trace.Int64Attribute("mockingbird.queue_wait_ms", int64(wait/time.Millisecond)),
+	)
+
+	ctx := trace.NewContext(context.Background(), s.root)
+	_, s.exec = trace.StartSpan(ctx, "mockingbird.execute")
+}
+
+// env returns the trace context of the execute span as environment variables
+func (s *runSpan) env() []string {
+	if s == nil || s.exec == nil {
+		return nil
+	}
+	return tracing.Env(s.exec.SpanContext())
+}
+
+// end ends the spans with the state of the test result; err is an error
+// that stopped the worker from running or saving it
+func (s *runSpan) end(tr mockingbird.TestResult, err error) {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended = true
+
+	status := runStatus(tr, err)
+	if s.exec == nil {
+		s.queue.SetStatus(status)
+		s.queue.End()
+	} else {
+		s.exec.SetStatus(status)
+		s.exec.End()
+	}
+
+	s.root.AddAttributes(
+		trace.StringAttribute("mockingbird.state", string(tr.State)),
+		trace.Int64Attribute("mockingbird.run_time_ms", int64(tr.RunTime/time.Millisecond)),
+	)
+	s.root.SetStatus(status)
+	s.root.End()
+}
+
+//
+// PRIVATE
+//
+
+func runStatus(tr mockingbird.TestResult, err error) trace.Status {
+	if err != nil {
+		return trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()}
+	}
+
+	switch tr.State {
+	case mockingbird.SUCCESSFUL:
+		return trace.Status{Code: trace.StatusCodeOK}
+	case mockingbird.CANCELLED:
+		return trace.Status{Code: trace.StatusCodeCancelled, Message: string(tr.State)}
+	case mockingbird.TIMED_OUT:
+		return trace.Status{Code: trace.StatusCodeDeadlineExceeded, Message: string(tr.State)}
+	default:
+		return trace.Status{Code: trace.StatusCodeUnknown, Message: string(tr.State)}
+	}
+}
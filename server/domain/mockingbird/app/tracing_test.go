@@ -0,0 +1,142 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+type spanRecorder struct {
+	sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.Lock()
+	defer r.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+// spansOf returns the spans of the trace of the test result by name
+func (r *spanRecorder) spansOf(id mockingbird.ULID) map[string]*trace.SpanData {
+	r.Lock()
+	defer r.Unlock()
+
+	var traceID trace.TraceID
+	for _, s := range r.spans {
+		if s.Attributes["mockingbird.test_result_id"] == string(id) {
+			traceID = s.TraceID
+		}
+	}
+
+	spans := map[string]*trace.SpanData{}
+	for _, s := range r.spans {
+		if s.TraceID == traceID {
+			spans[s.Name] = s
+		}
+	}
+	return spans
+}
+
+func recordSpans(t *testing.T) *spanRecorder {
+	t.Helper()
+
+	r := &spanRecorder{}
+	trace.RegisterExporter(r)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	return r
+}
+
+func TestRunSpan_TracesQueueWaitAndExecution(t *testing.T) {
+	r := recordSpans(t)
+	defer trace.UnregisterExporter(r)
+
+	tr := mockingbird.TestResult{ID: "01CZ0000000000000000000001", TestSuite: "all:test", Trigger: mockingbird.MANUAL, TriggeredBy: "alice"}
+	s := startRunSpan(tr)
+	s.start(2)
+	env := s.env()
+
+	tr.State = mockingbird.FAILED
+	tr.RunTime = 90 * time.Second
+	s.end(tr, nil)
+	s.end(tr, nil)
+
+	spans := r.spansOf(tr.ID)
+	if len(spans) != 3 {
+		t.Fatalf("\nWant: 3 spans\n Got: %d\n", len(spans))
+	}
+	root, queue, exec := spans["mockingbird.run"], spans["mockingbird.queue"], spans["mockingbird.execute"]
+	if root == nil || queue == nil || exec == nil {
+		t.Fatalf("\nWant: mockingbird.run, mockingbird.queue and mockingbird.execute\n Got: %v\n", spans)
+	}
+
+	for _, child := range []*trace.SpanData{queue, exec} {
+		if root.SpanID != child.ParentSpanID || root.TraceID != child.TraceID {
+			t.Errorf("%s\nWant: parent %s\n Got: %s\n", child.Name, root.SpanID, child.ParentSpanID)
+		}
+	}
+
+	attributes := map[string]interface{}{
+		"mockingbird.test_result_id": "01CZ0000000000000000000001",
+		"mockingbird.test_suite":     "all:test",
+		"mockingbird.trigger":        "manual",
+		"mockingbird.triggered_by":   "alice",
+		"mockingbird.worker":         int64(2),
+		"mockingbird.state":          "failed",
+		"mockingbird.run_time_ms":    int64(90000),
+	}
+	for key, want := range attributes {
+		if got := root.Attributes[key]; want != got {
+			t.Errorf("%s\nWant: %v\n Got: %v\n", key, want, got)
+		}
+	}
+	if _, ok := root.Attributes["mockingbird.queue_wait_ms"]; !ok {
+		t.Errorf("\nWant: mockingbird.queue_wait_ms\n Got: %v\n", root.Attributes)
+	}
+	if root.Code != trace.StatusCodeUnknown || exec.Code != trace.StatusCodeUnknown {
+		t.Errorf("\nWant: %d\n Got: run=%d execute=%d\n", trace.StatusCodeUnknown, root.Code, exec.Code)
+	}
+
+	want := "TRACEPARENT=00-" + exec.TraceID.String() + "-" + exec.SpanID.String() + "-01"
+	if len(env) == 0 || want != env[0] {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, strings.Join(env, " "))
+	}
+}
+
+func TestRunSpan_WhenCancelledInTheQueue_EndsTheQueueSpan(t *testing.T) {
+	r := recordSpans(t)
+	defer trace.UnregisterExporter(r)
+
+	tr := mockingbird.TestResult{ID: "01CZ0000000000000000000002", TestSuite: "all:test"}
+	s := startRunSpan(tr)
+	if env := s.env(); env != nil {
+		t.Errorf("\nWant: nil\n Got: %v\n", env)
+	}
+
+	tr.State = mockingbird.CANCELLED
+	s.end(tr, nil)
+
+	spans := r.spansOf(tr.ID)
+	if len(spans) != 2 {
+		t.Fatalf("\nWant: 2 spans\n Got: %d\n", len(spans))
+	}
+	for _, name := range []string{"mockingbird.run", "mockingbird.queue"} {
+		if got := spans[name].Code; got != trace.StatusCodeCancelled {
+			t.Errorf("%s\nWant: %d\n Got: %d\n", name, trace.StatusCodeCancelled, got)
+		}
+	}
+}
+
+func TestRunSpan_WhenNil_IsANoOp(t *testing.T) {
+	var s *runSpan
+	s.start(1)
+	s.end(mockingbird.TestResult{}, nil)
+	if env := s.env(); env != nil {
+		t.Errorf("\nWant: nil\n Got: %v\n", env)
+	}
+}
@@ -0,0 +1,292 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/montanaflynn/stats"
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// trendParts is the max number of points in the trend of a window
+const trendParts = 30
+
+// historyScanLimit caps the number of test results read on startup to load the history
+const historyScanLimit = 10000
+
+// historyTTL is how long the windows are cached when no test suite is done
+const historyTTL = time.Minute
+
+// history keeps the done test results the rolling windows need
+//
+// Note:
+//
+//        The history is read from the store once, on startup; after that
+//        the worker adds each done test result, like it does to runTimes
+//        and Stats. The windows are cached until a test suite is done, or
+//        for at most historyTTL so the duration windows keep rolling.
+//
+type history struct {
+	windows     []mockingbird.Window
+	maxRuns     int           // of the runs windows
+	maxDuration time.Duration // of the duration windows
+
+	sync.Mutex
+	suites map[mockingbird.TestSuite][]mockingbird.TestResult // oldest first
+	at     time.Time
+	cache  []mockingbird.WindowStats
+}
+
+func newHistory(windows []mockingbird.Window) *history {
+	h := &history{windows: windows, suites: map[mockingbird.TestSuite][]mockingbird.TestResult{}}
+	for _, w := range windows {
+		if w.Duration > h.maxDuration {
+			h.maxDuration = w.Duration
+		}
+		if w.Runs > h.maxRuns {
+			h.maxRuns = w.Runs
+		}
+	}
+	return h
+}
+
+// loadHistory returns the history of the latest done test results in the store
+func loadHistory(store mockingbird.Store, windows []mockingbird.Window, now time.Time) (*history, error) {
+	h := newHistory(windows)
+	if len(windows) == 0 {
+		return h, nil
+	}
+	from := now.Add(-h.maxDuration)
+
+	var done []mockingbird.TestResult
+	runs := map[mockingbird.TestSuite]int{}
+	q := mockingbird.ListTestsQuery{Status: mockingbird.DONE, PageSize: mockingbird.MaxPageSize}
+	for len(done) < historyScanLimit {
+		trs, err := store.ListTestResults(q)
+		if err != nil {
+			return nil, errors.Wrapf(err, "store.ListTestResults(%s) failed", q.PageToken)
+		}
+
+		enough := true
+		for _, tr := range trs.TestResults {
+			done = append(done, tr)
+			runs[tr.TestSuite]++
+
+			if !tr.StartTime.Before(from) {
+				enough = false
+			}
+		}
+		for _, n := range runs {
+			if n < h.maxRuns {
+				enough = false
+			}
+		}
+
+		if enough || trs.NextPageToken == "" {
+			break
+		}
+		q.PageToken = trs.NextPageToken
+	}
+
+	// add the oldest first, as the worker does
+	for i := len(done) - 1; i >= 0; i-- {
+		h.add(done[i], now)
+	}
+	return h, nil
+}
+
+// add adds a done test result; the runs that no window needs anymore are dropped
+func (h *history) add(tr mockingbird.TestResult, now time.Time) {
+	if h == nil || len(h.windows) == 0 {
+		return
+	}
+	if tr.IsCancelled() || tr.IsInterrupted() {
+		return // they are kept out of Stats too
+	}
+
+	// the windows do not need the log, so it is not kept in memory
+	tr.Log = ""
+	tr.Packages = nil
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.suites[tr.TestSuite] = append(h.suites[tr.TestSuite], tr)
+	h.prune(tr.TestSuite, now)
+	h.cache = nil
+}
+
+// windowStats returns the stats of the windows
+func (h *history) windowStats() []mockingbird.WindowStats {
+	if h == nil || len(h.windows) == 0 {
+		return nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	now := time.Now().UTC()
+	if h.cache != nil && now.Sub(h.at) < historyTTL {
+		return h.cache
+	}
+
+	h.cache = h.compute(now)
+	h.at = now
+	return h.cache
+}
+
+// computeWindows returns the stats of the windows from the done test results, newest first
+func computeWindows(windows []mockingbird.Window, done []mockingbird.TestResult, now time.Time) []mockingbird.WindowStats {
+	h := newHistory(windows)
+	for i := len(done) - 1; i >= 0; i-- {
+		h.add(done[i], now)
+	}
+	return h.compute(now)
+}
+
+//
+// PRIVATE
+//
+
+// compute returns the stats of the windows; it is called with the lock held
+func (h *history) compute(now time.Time) []mockingbird.WindowStats {
+	names := make([]mockingbird.TestSuite, 0, len(h.suites))
+	for ts := range h.suites {
+		h.prune(ts, now)
+		names = append(names, ts)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	ws := make([]mockingbird.WindowStats, 0, len(h.windows))
+	for _, w := range h.windows {
+		s := mockingbird.WindowStats{Window: w}
+		for _, ts := range names {
+			runs := inWindow(w, h.suites[ts], now)
+			if len(runs) == 0 {
+				continue
+			}
+			s.Suites = append(s.Suites, windowSuiteStats(w, ts, runs, now))
+		}
+		ws = append(ws, s)
+	}
+	return ws
+}
+
+// prune drops the oldest runs of the test suite that are neither in the
+// largest runs window nor in the largest duration window; it is called
+// with the lock held
+func (h *history) prune(ts mockingbird.TestSuite, now time.Time) {
+	from := now.Add(-h.maxDuration)
+	runs := h.suites[ts]
+
+	n := 0
+	for n < len(runs)-h.maxRuns && runs[n].StartTime.Before(from) {
+		n++
+	}
+	switch {
+	case n == len(runs):
+		delete(h.suites, ts)
+	case n > 0:
+		h.suites[ts] = append([]mockingbird.TestResult(nil), runs[n:]...)
+	}
+}
+
+// inWindow returns the runs, oldest first, that are in the window
+func inWindow(w mockingbird.Window, runs []mockingbird.TestResult, now time.Time) []mockingbird.TestResult {
+	if w.Runs > 0 {
+		if len(runs) > w.Runs {
+			return runs[len(runs)-w.Runs:]
+		}
+		return runs
+	}
+
+	// the runs are in queue order, which is not strictly in start time order
+	from := now.Add(-w.Duration)
+	var in []mockingbird.TestResult
+	for _, tr := range runs {
+		if !tr.StartTime.Before(from) {
+			in = append(in, tr)
+		}
+	}
+	return in
+}
+
+func windowSuiteStats(w mockingbird.Window, ts mockingbird.TestSuite, runs []mockingbird.TestResult, now time.Time) mockingbird.WindowSuiteStats {
+	s := mockingbird.WindowSuiteStats{TestSuite: ts}
+
+	data := make(stats.Float64Data, 0, len(runs))
+	for _, tr := range runs {
+		s.RunCounter = s.RunCounter + 1
+		if tr.State == mockingbird.SUCCESSFUL {
+			s.SuccessCounter = s.SuccessCounter + 1
+		}
+		if tr.RunTime > s.MaxRunTime {
+			s.MaxRunTime = tr.RunTime
+		}
+		data = append(data, float64(tr.RunTime))
+	}
+	s.SuccessRate = (s.SuccessCounter / s.RunCounter) * 100
+	s.P50RunTime = percentile(data, 50)
+	s.P90RunTime = percentile(data, 90)
+
+	s.Trend = trend(w, runs, now)
+	return s
+}
+
+// trend splits the window in at most trendParts parts: equal time spans for
+// a duration window and equal run counts for a runs window
+func trend(w mockingbird.Window, runs []mockingbird.TestResult, now time.Time) []mockingbird.TrendPoint {
+	var (
+		points []mockingbird.TrendPoint
+		part   func(i int, tr mockingbird.TestResult) int
+	)
+
+	if w.Runs > 0 {
+		n := len(runs)
+		if n > trendParts {
+			n = trendParts
+		}
+		points = make([]mockingbird.TrendPoint, n)
+		part = func(i int, tr mockingbird.TestResult) int { return i * n / len(runs) }
+	} else {
+		from := now.Add(-w.Duration)
+		span := w.Duration / trendParts
+		points = make([]mockingbird.TrendPoint, trendParts)
+		for i := range points {
+			points[i].StartTime = from.Add(time.Duration(i) * span)
+		}
+		part = func(i int, tr mockingbird.TestResult) int {
+			p := int(tr.StartTime.Sub(from) / span)
+			if p >= trendParts {
+				p = trendParts - 1
+			}
+			return p
+		}
+	}
+
+	successes := make([]float64, len(points))
+	runTimes := make([]time.Duration, len(points))
+	for i, tr := range runs {
+		p := part(i, tr)
+		if points[p].RunCounter == 0 && w.Runs > 0 {
+			points[p].StartTime = tr.StartTime
+		}
+		points[p].RunCounter = points[p].RunCounter + 1
+		if tr.State == mockingbird.SUCCESSFUL {
+			successes[p] = successes[p] + 1
+		}
+		runTimes[p] += tr.RunTime
+	}
+
+	for i := range points {
+		if points[i].RunCounter == 0 {
+			continue
+		}
+		points[i].SuccessRate = (successes[i] / points[i].RunCounter) * 100
+		points[i].MeanRunTime = runTimes[i] / time.Duration(points[i].RunCounter)
+	}
+	return points
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+func TestComputeWindows_RollsOverTheHistory(t *testing.T) {
+	now := time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+
+	// newest first, as the store lists them
+	var done []mockingbird.TestResult
+	for i := 0; i < 5; i++ {
+		var state mockingbird.State = mockingbird.SUCCESSFUL
+		if i == 0 {
+			state = mockingbird.FAILED
+		}
+		done = append(done, mockingbird.TestResult{
+			TestSuite: "google:test",
+			State:     state,
+			StartTime: now.Add(-time.Duration(i) * 10 * time.Hour),
+			RunTime:   time.Duration(i+1) * time.Minute,
+		})
+	}
+
+	windows := []mockingbird.Window{{Duration: 36 * time.Hour}, {Runs: 2}}
+	ws := computeWindows(windows, done, now)
+
+	testCases := []struct {
+		window mockingbird.Window
+		want   mockingbird.WindowSuiteStats
+	}{
+		{
+			window: windows[0],
+			want: mockingbird.WindowSuiteStats{
+				TestSuite:      "google:test",
+				RunCounter:     4,
+				SuccessCounter: 3,
+				SuccessRate:    75,
+				P50RunTime:     150 * time.Second,
+				P90RunTime:     4 * time.Minute,
+				MaxRunTime:     4 * time.Minute,
+			},
+		},
+		{
+			window: windows[1],
+			want: mockingbird.WindowSuiteStats{
+				TestSuite:      "google:test",
+				RunCounter:     2,
+				SuccessCounter: 1,
+				SuccessRate:    50,
+				P50RunTime:     90 * time.Second,
+				P90RunTime:     2 * time.Minute,
+				MaxRunTime:     2 * time.Minute,
+			},
+		},
+	}
+
+	if want, got := len(testCases), len(ws); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	for i, tc := range testCases {
+		if want, got := tc.window, ws[i].Window; want != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+		}
+		if want, got := 1, len(ws[i].Suites); want != got {
+			t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+		}
+
+		got := ws[i].Suites[0]
+		got.Trend = nil
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("%s\nWant: %+v\n Got: %+v\n", tc.window, tc.want, got)
+		}
+	}
+}
+
+func TestComputeWindows_SkipsTheCancelledAndInterruptedRuns(t *testing.T) {
+	now := time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+	done := []mockingbird.TestResult{
+		{TestSuite: "google:test", State: mockingbird.CANCELLED, StartTime: now.Add(-time.Minute)},
+		{TestSuite: "google:test", State: mockingbird.INTERRUPTED, StartTime: now.Add(-2 * time.Minute)},
+		{TestSuite: "google:test", State: mockingbird.SUCCESSFUL, StartTime: now.Add(-3 * time.Minute)},
+		{TestSuite: "all:test", State: mockingbird.CANCELLED, StartTime: now.Add(-4 * time.Minute)},
+	}
+
+	for _, w := range []mockingbird.Window{{Duration: time.Hour}, {Runs: 2}} {
+		ws := computeWindows([]mockingbird.Window{w}, done, now)
+		if want, got := 1, len(ws[0].Suites); want != got {
+			t.Fatalf("%s\nWant: %d\n Got: %d\n", w, want, got)
+		}
+
+		got := ws[0].Suites[0]
+		if got.TestSuite != "google:test" || got.RunCounter != 1 || got.SuccessRate != 100 {
+			t.Errorf("%s\nWant: google:test 1 run 100%%\n Got: %s %v runs %v%%\n", w, got.TestSuite, got.RunCounter, got.SuccessRate)
+		}
+	}
+}
+
+func TestHistory_Add_DropsTheRunsThatNoWindowNeeds(t *testing.T) {
+	now := time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+	h := newHistory([]mockingbird.Window{{Duration: time.Hour}, {Runs: 2}})
+
+	// 4 old runs of google:test, then 3 runs in the last hour
+	for i := 0; i < 7; i++ {
+		start := now.Add(-time.Duration(10-i) * time.Hour)
+		if i >= 4 {
+			start = now.Add(-time.Duration(7-i) * 10 * time.Minute)
+		}
+		h.add(mockingbird.TestResult{TestSuite: "google:test", State: mockingbird.SUCCESSFUL, StartTime: start, Log: "log"}, now)
+	}
+	h.add(mockingbird.TestResult{TestSuite: "all:test", State: mockingbird.FAILED, StartTime: now.Add(-48 * time.Hour)}, now)
+
+	runs := h.suites["google:test"]
+	if want, got := 3, len(runs); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if runs[0].Log != "" {
+		t.Errorf("\nWant: no log\n Got: %s\n", runs[0].Log)
+	}
+	// the latest runs of a test suite are kept for the runs windows
+	if want, got := 1, len(h.suites["all:test"]); want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	ws := h.compute(now.Add(time.Hour))
+	if want, got := 0, len(ws[0].Suites); want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := 2, len(ws[1].Suites); want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := 2, len(h.suites["google:test"]); want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+}
+
+func TestTrend_SplitsTheWindowIntoParts(t *testing.T) {
+	now := time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+	w := mockingbird.Window{Duration: trendParts * time.Hour}
+	runs := []mockingbird.TestResult{
+		{State: mockingbird.FAILED, StartTime: now.Add(-w.Duration), RunTime: time.Minute},
+		{State: mockingbird.SUCCESSFUL, StartTime: now.Add(-w.Duration + time.Minute), RunTime: 3 * time.Minute},
+		{State: mockingbird.SUCCESSFUL, StartTime: now, RunTime: time.Minute},
+	}
+
+	points := trend(w, runs, now)
+	if want, got := trendParts, len(points); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	first := mockingbird.TrendPoint{StartTime: now.Add(-w.Duration), RunCounter: 2, SuccessRate: 50, MeanRunTime: 2 * time.Minute}
+	if got := points[0]; first != got {
+		t.Errorf("\nWant: %+v\n Got: %+v\n", first, got)
+	}
+	if want, got := 0.0, points[1].RunCounter; want != got {
+		t.Errorf("\nWant: %f\n Got: %f\n", want, got)
+	}
+	if want, got := 1.0, points[trendParts-1].RunCounter; want != got {
+		t.Errorf("\nWant: %f\n Got: %f\n", want, got)
+	}
+}
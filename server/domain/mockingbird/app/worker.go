@@ -1,103 +1,397 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 
-	"github.com/magefile/mage/sh"
-
 	"github.com/unders/mockingbird/server/domain/mockingbird"
 	"github.com/unders/mockingbird/server/pkg/errs"
 )
 
-type testResult struct {
-	mockingbird.TestResult
-	index int
-}
+// interruptedLog is appended to the log of a test result that was running when the server stopped
+const interruptedLog = "error: interrupted, the server stopped while the test suite was running"
+
+// defaultTimeout is used when no test suite timeout is configured
+const defaultTimeout = 30 * time.Minute
+
+// logFlushInterval is how often the log of a running test suite is saved
+const logFlushInterval = time.Second
 
 type worker struct {
-	// the work buffer must be big enough :)
-	work chan mockingbird.ULID
+	queue   *queue
+	workers int // the size of the worker pool
 
-	//
-	// This part should be replaced by a DB
-	//
-	*sync.RWMutex
-	testResults map[mockingbird.ULID]testResult
-	stats       mockingbird.Stats
+	// timeout is the default test suite timeout; timeouts overrides it per test suite
+	timeout  time.Duration
+	timeouts map[mockingbird.TestSuite]time.Duration
+
+	// serializes the read-modify-write of test results and stats
+	*sync.Mutex
+	store    mockingbird.Store
+	log      mockingbird.Log
+	running  map[mockingbird.ULID]*task
+	stopping bool // is set by stop; the running test suites are interrupted
 
-	idIndex []mockingbird.ULID
+	loops sync.WaitGroup // of the worker pool
+
+	// runTimes are used to estimate when pending test suites are done
+	runTimes runTimes
+
+	// history keeps the done test results of the rolling windows
+	history *history
+
+	// notifier is notified when a test suite is done; nil when there are no subscriptions
+	notifier *notifier
 }
 
-func (w *worker) add(id mockingbird.ULID, s mockingbird.TestSuite) {
-	tr := mockingbird.TestResult{
-		ID: id,
+// task is a running test suite
+type task struct {
+	suite  mockingbird.TestSuite
+	start  time.Time
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the test result is saved
+}
 
-		State:     mockingbird.PENDING,
-		Status:    mockingbird.QUEUED,
-		TestSuite: s,
-	}
+// add queues the test result; the caller sets its ID, test suite, trigger and deployment
+func (w *worker) add(tr mockingbird.TestResult) error {
+	tr.State = mockingbird.PENDING
+	tr.Status = mockingbird.QUEUED
 
 	w.Lock()
-	w.idIndex = append(w.idIndex, tr.ID)
-	index := len(w.idIndex) - 1
-	w.testResults[id] = testResult{TestResult: tr, index: index}
+	err := w.enqueue(tr)
 	w.Unlock()
+	if err != nil {
+		return err
+	}
 
-	w.work <- tr.ID
+	w.queue.push(job{id: tr.ID, suite: tr.TestSuite, span: startRunSpan(tr)})
+	return nil
 }
 
-func (w *worker) loop() {
-	for id := range w.work {
-		w.workTask(id)
+// recover reconciles the persisted queue after a restart: running test
+// results are marked as interrupted and queued test results are returned,
+// in their original order, so they can be added to the work queue
+func (w *worker) recover() ([]mockingbird.TestResult, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	q, err := w.store.Queue()
+	if err != nil {
+		return nil, errors.Wrap(err, "w.store.Queue() failed")
 	}
+
+	var queued []mockingbird.TestResult
+	for _, id := range q {
+		tr, err := w.store.GetTestResult(id)
+		if errs.IsNotFound(err) {
+			if err := w.store.Dequeue(id); err != nil {
+				return nil, errors.Wrapf(err, "w.store.Dequeue(%s) failed", id)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "w.store.GetTestResult(%s) failed", id)
+		}
+
+		switch tr.Status {
+		case mockingbird.QUEUED:
+			queued = append(queued, tr)
+			continue
+		case mockingbird.RUNNING:
+			tr.Status = mockingbird.DONE
+			tr.State = mockingbird.INTERRUPTED
+			tr.RunTime = time.Since(tr.StartTime)
+			tr.Log = tr.Log + "\n" + interruptedLog
+			if err := w.store.SaveTestResult(tr); err != nil {
+				return nil, errors.Wrapf(err, "w.store.SaveTestResult(%s) failed", id)
+			}
+			w.log.Info("test result interrupted", mockingbird.KV("test_result_id", id))
+		}
+
+		if err := w.store.Dequeue(id); err != nil {
+			return nil, errors.Wrapf(err, "w.store.Dequeue(%s) failed", id)
+		}
+	}
+
+	return queued, nil
 }
 
-func (w *worker) workTask(id mockingbird.ULID) {
+// loop runs the queued test suites in the given worker slot until the queue is closed
+func (w *worker) loop(slot int) {
+	defer w.loops.Done()
+
+	for {
+		j, ok := w.queue.next()
+		if !ok {
+			return
+		}
+		if err := w.workTask(j, slot); err != nil {
+			w.log.Error("test result failed", mockingbird.KV("test_result_id", j.id), mockingbird.KV("worker", slot), mockingbird.KV("error", err))
+		}
+		w.queue.done(j)
+	}
+}
+
+func (w *worker) workTask(j job, slot int) (err error) {
+	id := j.id
+	var tr mockingbird.TestResult
+	defer func() { j.span.end(tr, err) }()
+
 	//
 	// Update tr with current status and set start time
 	//
 	w.Lock()
-	tr := w.testResults[id]
-	tr.Status = mockingbird.RUNNING
+	tr, err = w.store.GetTestResult(id)
+	if err != nil {
+		w.Unlock()
+		return errors.Wrapf(err, "w.store.GetTestResult(%s) failed", id)
+	}
+	if tr.Status != mockingbird.QUEUED || w.stopping {
+		// cancelled after the job was taken from the queue, or kept
+		// queued in the store when the worker pool is stopped
+		w.Unlock()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	tr.StartTime = time.Now().UTC()
-	w.testResults[id] = tr
+	t := &task{suite: tr.TestSuite, start: tr.StartTime, cancel: cancel, done: make(chan struct{})}
+	w.running[id] = t
+	defer func() {
+		w.Lock()
+		delete(w.running, id)
+		w.Unlock()
+		close(t.done)
+	}()
+
+	tr.Status = mockingbird.RUNNING
+	tr.Worker = slot
+	j.span.start(slot)
+	err = w.store.SaveTestResult(tr)
 	w.Unlock()
+	if err != nil {
+		return errors.Wrapf(err, "w.store.SaveTestResult(%s) failed", id)
+	}
 
 	//
 	// run the test suite
 	//
-	out, err := sh.Output("mage", string(tr.TestSuite))
-	tr.Log = out
+	timeout := w.timeoutFor(tr.TestSuite)
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	out := &logBuffer{}
+	tests := newTestJSON(out)
+	stopFlush := w.flushLog(tr, out)
+	env := append([]string{testJSONEnv}, j.span.env()...)
+	err = run(ctx, tests, env, "mage", string(tr.TestSuite))
+	_ = tests.Close()
+	stopFlush()
+	cancelTimeout()
+	tr.Log = out.String()
+	tr.Packages = tests.Packages()
 	tr.RunTime = time.Since(tr.StartTime)
 	tr.Status = mockingbird.DONE
 
 	tr.State = mockingbird.SUCCESSFUL
-	if s := sh.ExitStatus(err); s != 0 {
+	switch {
+	case err == errTimedOut:
+		tr.State = mockingbird.TIMED_OUT
+		tr.Log = appendLine(tr.Log, fmt.Sprintf("error: timed out after %s, the test suite was killed", timeout))
+	case err == context.Canceled && w.isStopping():
+		tr.State = mockingbird.INTERRUPTED
+		tr.Log = appendLine(tr.Log, interruptedLog)
+	case err == context.Canceled:
+		tr.State = mockingbird.CANCELLED
+		tr.Log = appendLine(tr.Log, "error: cancelled, the test suite was stopped")
+	case err != nil:
 		tr.State = mockingbird.FAILED
-		tr.Log = tr.Log + "\nerror: " + err.Error()
+		tr.Log = appendLine(tr.Log, "error: "+err.Error())
 	}
 
 	//
-	// Save the test result
+	// Save the test result and update Stats
 	//
 	w.Lock()
-	w.testResults[id] = tr
+	defer w.Unlock()
+
+	if err := w.store.SaveTestResult(tr); err != nil {
+		return errors.Wrapf(err, "w.store.SaveTestResult(%s) failed", id)
+	}
+	if err := w.store.Dequeue(id); err != nil {
+		return errors.Wrapf(err, "w.store.Dequeue(%s) failed", id)
+	}
+	if tr.IsCancelled() || tr.IsInterrupted() {
+		return nil
+	}
+
+	w.runTimes.add(tr)
+	w.history.add(tr, time.Now().UTC())
+	recordRun(tr)
+	previous, err := w.updateStats(tr)
+	if err != nil {
+		return err
+	}
+
+	w.notifier.notify(tr, previous)
+	return nil
+}
+
+// stop stops taking test suites from the queue and waits until the running
+// test suites are done; when ctx is done before them, they are stopped and
+// their test results are saved as interrupted
+//
+// Note:
+//
+//        The queued test suites are kept in the store, so they are run
+//        when the server is started again.
+//
+func (w *worker) stop(ctx context.Context) error {
+	w.queue.close()
+
+	done := make(chan struct{})
+	go func() {
+		w.loops.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	w.Lock()
+	w.stopping = true
+	for _, t := range w.running {
+		t.cancel()
+	}
 	w.Unlock()
 
-	//
-	// Update Stats
-	//
-	w.updateStats(tr)
+	<-done
+	return errors.Wrap(ctx.Err(), "test suites were interrupted")
+}
+
+func (w *worker) isStopping() bool {
+	w.Lock()
+	defer w.Unlock()
+
+	return w.stopping
+}
+
+// flushLog saves the log of the running test suite every logFlushInterval
+// until the returned stop function is called
+func (w *worker) flushLog(tr mockingbird.TestResult, out *logBuffer) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(logFlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+
+			log, changed := out.flush()
+			if !changed {
+				continue
+			}
+
+			tr.Log = log
+			w.Lock()
+			err := w.store.SaveTestResult(tr)
+			w.Unlock()
+			if err != nil {
+				w.log.Error("test result flush log failed", mockingbird.KV("test_result_id", tr.ID), mockingbird.KV("error", err))
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-done
+	}
 }
 
-func (w *worker) updateStats(tr testResult) {
-	w.RLock()
-	s := w.stats
-	w.RUnlock()
+// cancel removes a queued test suite from the queue, or stops a
+// running test suite and waits until its test result is saved
+func (w *worker) cancel(id mockingbird.ULID) error {
+	w.Lock()
+	tr, err := w.store.GetTestResult(id)
+	if err != nil {
+		w.Unlock()
+		return errors.Wrapf(err, "w.store.GetTestResult(%s) failed", id)
+	}
+
+	switch tr.Status {
+	case mockingbird.QUEUED:
+		defer w.Unlock()
+
+		// a job already taken from the queue is skipped by workTask
+		j, removed := w.queue.remove(id)
+
+		tr.Status = mockingbird.DONE
+		tr.State = mockingbird.CANCELLED
+		tr.Log = "error: cancelled before the test suite started"
+		if removed {
+			j.span.end(tr, nil)
+		}
+		if err := w.store.SaveTestResult(tr); err != nil {
+			return errors.Wrapf(err, "w.store.SaveTestResult(%s) failed", id)
+		}
+		return errors.Wrapf(w.store.Dequeue(id), "w.store.Dequeue(%s) failed", id)
+	case mockingbird.RUNNING:
+		t, ok := w.running[id]
+		w.Unlock()
+		if !ok {
+			return nil
+		}
+
+		t.cancel()
+		<-t.done
+		return nil
+	default:
+		w.Unlock()
+		return nil
+	}
+}
+
+func (w *worker) timeoutFor(s mockingbird.TestSuite) time.Duration {
+	if t, ok := w.timeouts[s]; ok && t > 0 {
+		return t
+	}
+	if w.timeout > 0 {
+		return w.timeout
+	}
+	return defaultTimeout
+}
+
+// enqueue must be called with the worker lock held
+func (w *worker) enqueue(tr mockingbird.TestResult) error {
+	if err := w.store.SaveTestResult(tr); err != nil {
+		return errors.Wrapf(err, "w.store.SaveTestResult(%s) failed", tr.ID)
+	}
+
+	return errors.Wrapf(w.store.Enqueue(tr.ID), "w.store.Enqueue(%s) failed", tr.ID)
+}
+
+// updateStats returns the state of the previous run of the test suite;
+// it must be called with the worker lock held
+func (w *worker) updateStats(tr mockingbird.TestResult) (previous mockingbird.State, err error) {
+	s, err := w.store.GetStats()
+	if err != nil {
+		return "", errors.Wrap(err, "w.store.GetStats() failed")
+	}
+	previous = s.Suites[tr.TestSuite].LatestDoneState
 
 	if mockingbird.FullTestSuite == tr.TestSuite {
 		s.LatestDoneFullTestSuiteID = tr.ID
@@ -112,6 +406,9 @@ func (w *worker) updateStats(tr testResult) {
 		if tr.State == mockingbird.SUCCESSFUL {
 			s.FullTestSuiteSuccessCounter = s.FullTestSuiteSuccessCounter + 1
 		}
+		if tr.State == mockingbird.TIMED_OUT {
+			s.FullTestSuiteTimeoutCounter = s.FullTestSuiteTimeoutCounter + 1
+		}
 		s.FullTestSuiteSuccessRate = (s.FullTestSuiteSuccessCounter / s.FullTestSuiteRunCounter) * 100
 	} else {
 		s.LatestDoneTestSuiteID = tr.ID
@@ -127,6 +424,9 @@ func (w *worker) updateStats(tr testResult) {
 	if tr.State == mockingbird.SUCCESSFUL {
 		s.TestSuiteSuccessCounter = s.TestSuiteSuccessCounter + 1
 	}
+	if tr.State == mockingbird.TIMED_OUT {
+		s.TestSuiteTimeoutCounter = s.TestSuiteTimeoutCounter + 1
+	}
 	s.TestSuiteSuccessRate = (s.TestSuiteSuccessCounter / s.TestSuiteRunCounter) * 100
 
 	if tr.RunTime > s.SlowestTestSuiteRunTime {
@@ -134,60 +434,21 @@ func (w *worker) updateStats(tr testResult) {
 		s.SlowestTestSuiteRunTime = tr.RunTime
 	}
 
-	w.Lock()
-	w.stats = s
-	w.Unlock()
-}
+	s = addSuiteStats(s, tr)
 
-func (w *worker) getStats() mockingbird.Stats {
-	return w.stats
+	return previous, errors.Wrap(w.store.SaveStats(s), "w.store.SaveStats() failed")
 }
 
-func (w *worker) getTestResults(pageToken string) (*mockingbird.TestResults, error) {
-	startIndex := len(w.idIndex) - 1
-	if pageToken != "" {
-		if ts, ok := w.testResults[mockingbird.ULID(pageToken)]; ok {
-			startIndex = ts.index
-		}
-	}
-
-	nextPageToken := ""
-	stopIndex := startIndex - 10
-	nextPageTokenIndex := startIndex - 11
-	if stopIndex < 1 {
-		stopIndex = 0
-		nextPageTokenIndex = -1
-	}
-
-	var trs []mockingbird.TestResult
-
-	if nextPageTokenIndex > -1 {
-		id := w.idIndex[nextPageTokenIndex]
-		if tr, ok := w.testResults[id]; ok {
-			nextPageToken = string(tr.ID)
-		}
-	}
-
-	for i := startIndex; i >= stopIndex; i-- {
-		id := w.idIndex[i]
-		tr, ok := w.testResults[id]
-		if !ok {
-			return nil, errors.Errorf("w.testResults[%s] failed", string(id))
-		}
-		trs = append(trs, tr.TestResult)
-	}
+func (w *worker) getStats() (mockingbird.Stats, error) {
+	s, err := w.store.GetStats()
+	return s, errors.Wrap(err, "w.store.GetStats() failed")
+}
 
-	return &mockingbird.TestResults{NextPageToken: nextPageToken, TestResults: trs}, nil
+func (w *worker) getTestResults(q mockingbird.ListTestsQuery) (*mockingbird.TestResults, error) {
+	trs, err := w.store.ListTestResults(q)
+	return trs, errors.Wrapf(err, "w.store.ListTestResults(%+v) failed", q)
 }
 
 func (w *worker) getTestResult(id mockingbird.ULID) (mockingbird.TestResult, error) {
-	w.RLock()
-	defer w.RUnlock()
-
-	if ts, ok := w.testResults[id]; ok {
-		return ts.TestResult, nil
-	}
-
-	msg := fmt.Sprintf("test result %s not found", id)
-	return mockingbird.TestResult{}, errs.NotFound(msg)
+	return w.store.GetTestResult(id)
 }
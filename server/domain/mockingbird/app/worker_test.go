@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/memory"
+	"github.com/unders/mockingbird/server/domain/mockingbird/mock"
+	"github.com/unders/mockingbird/server/pkg/errs"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestWorker_Recover_InterruptsRunningAndReturnsQueuedInOrder(t *testing.T) {
+	store := memory.NewStore()
+	w := newTestWorker(store)
+
+	running := mockingbird.TestResult{
+		ID:        "01CZ0000000000000000000001",
+		Status:    mockingbird.RUNNING,
+		State:     mockingbird.PENDING,
+		StartTime: time.Now().Add(-time.Minute),
+	}
+	first := mockingbird.TestResult{ID: "01CZ0000000000000000000002", Status: mockingbird.QUEUED}
+	second := mockingbird.TestResult{ID: "01CZ0000000000000000000003", Status: mockingbird.QUEUED}
+	for _, tr := range []mockingbird.TestResult{running, second, first} {
+		testdata.AssertNil(t, w.enqueue(tr))
+	}
+
+	queued, err := w.recover()
+	testdata.AssertNil(t, err)
+
+	if len(queued) != 2 || queued[0].ID != second.ID || queued[1].ID != first.ID {
+		t.Errorf("\nWant: [%s %s]\n Got: %+v\n", second.ID, first.ID, queued)
+	}
+
+	tr, err := store.GetTestResult(running.ID)
+	testdata.AssertNil(t, err)
+	if mockingbird.DONE != tr.Status {
+		t.Errorf("\nWant: %s\n Got: %s\n", mockingbird.DONE, tr.Status)
+	}
+	testdata.AssertTrue(t, tr.IsInterrupted())
+	testdata.AssertTrue(t, strings.Contains(tr.Log, "interrupted"))
+
+	q, err := store.Queue()
+	testdata.AssertNil(t, err)
+	if len(q) != 2 || q[0] != second.ID || q[1] != first.ID {
+		t.Errorf("\nWant: [%s %s]\n Got: %v\n", second.ID, first.ID, q)
+	}
+}
+
+func TestWorker_Cancel_WhenQueued_RemovesItFromTheQueue(t *testing.T) {
+	store := memory.NewStore()
+	w := newTestWorker(store)
+
+	const id mockingbird.ULID = "01CZ0000000000000000000001"
+	testdata.AssertNil(t, w.add(mockingbird.TestResult{ID: id, TestSuite: "google:test", Trigger: mockingbird.MANUAL}))
+	testdata.AssertNil(t, w.cancel(id))
+
+	tr, err := store.GetTestResult(id)
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, tr.IsCancelled())
+	if mockingbird.DONE != tr.Status {
+		t.Errorf("\nWant: %s\n Got: %s\n", mockingbird.DONE, tr.Status)
+	}
+
+	_, removed := w.queue.remove(id)
+	testdata.AssertTrue(t, !removed)
+	q, err := store.Queue()
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, len(q) == 0)
+}
+
+func newTestWorker(store mockingbird.Store) *worker {
+	return &worker{
+		queue:   newQueue(nil),
+		Mutex:   &sync.Mutex{},
+		store:   store,
+		log:     &mock.Log{},
+		running: map[mockingbird.ULID]*task{},
+		workers: 1,
+
+		runTimes: runTimes{},
+	}
+}
+
+func TestMockingbird_RunTest_WhenTheTestSuiteDoesNotExist_ReturnsNotFound(t *testing.T) {
+	store := memory.NewStore()
+	m := &Mockingbird{worker: newTestWorker(store), testSuites: []mockingbird.TestSuite{"all:test", "google:test"}}
+
+	_, err := m.RunTest("unknown:test", "alice")
+	testdata.AssertTrue(t, errs.IsNotFound(err))
+	q, err := store.Queue()
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, len(q) == 0)
+
+	id, err := m.RunTest("google:test", "alice")
+	testdata.AssertNil(t, err)
+	tr, err := store.GetTestResult(id)
+	testdata.AssertNil(t, err)
+	if want, got := "alice", tr.TriggeredBy; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestMockingbird_FollowLog_SendsCompleteLinesUntilDone(t *testing.T) {
+	store := memory.NewStore()
+	m := &Mockingbird{worker: newTestWorker(store)}
+
+	tr := mockingbird.TestResult{ID: "01CZ0000000000000000000001", Status: mockingbird.RUNNING, Log: "line 1\nline"}
+	testdata.AssertNil(t, store.SaveTestResult(tr))
+
+	var chunks []string
+	err := m.FollowLog(context.Background(), tr.ID, 0, func(chunk string, offset int) error {
+		chunks = append(chunks, chunk)
+		if len(chunks) == 1 {
+			tr.Status = mockingbird.DONE
+			tr.Log = "line 1\nline 2"
+			testdata.AssertNil(t, store.SaveTestResult(tr))
+		}
+		return nil
+	})
+	testdata.AssertNil(t, err)
+
+	if len(chunks) != 2 || chunks[0] != "line 1\n" || chunks[1] != "line 2" {
+		t.Errorf("\nWant: [line 1\\n line 2]\n Got: %q\n", chunks)
+	}
+}
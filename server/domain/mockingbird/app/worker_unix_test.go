@@ -0,0 +1,120 @@
+// +build !windows
+
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/memory"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestWorker_Stop_WhenTheTestSuitesAreDone_KeepsTheQueuedTestSuites(t *testing.T) {
+	defer fakeMage(t, "sleep 1; echo ok")()
+
+	store := memory.NewStore()
+	w := newTestWorker(store)
+	w.history = newHistory(nil)
+	w.loops.Add(1)
+	go w.loop(1)
+
+	const first, second mockingbird.ULID = "01CZ0000000000000000000001", "01CZ0000000000000000000002"
+	testdata.AssertNil(t, w.add(mockingbird.TestResult{ID: first, TestSuite: "google:test", Trigger: mockingbird.MANUAL}))
+	waitForStatus(t, store, first, mockingbird.RUNNING)
+	testdata.AssertNil(t, w.add(mockingbird.TestResult{ID: second, TestSuite: "google:test", Trigger: mockingbird.MANUAL}))
+
+	testdata.AssertNil(t, w.stop(context.Background()))
+
+	tr, err := store.GetTestResult(first)
+	testdata.AssertNil(t, err)
+	if mockingbird.DONE != tr.Status {
+		t.Errorf("\nWant: %s\n Got: %s\n", mockingbird.DONE, tr.Status)
+	}
+	assertQueue(t, store, second)
+}
+
+func TestWorker_Stop_WhenCtxIsDone_InterruptsTheRunningTestSuites(t *testing.T) {
+	defer fakeMage(t, "echo started; sleep 30")()
+
+	store := memory.NewStore()
+	w := newTestWorker(store)
+	w.loops.Add(1)
+	go w.loop(1)
+
+	const first, second mockingbird.ULID = "01CZ0000000000000000000001", "01CZ0000000000000000000002"
+	testdata.AssertNil(t, w.add(mockingbird.TestResult{ID: first, TestSuite: "google:test", Trigger: mockingbird.MANUAL}))
+	testdata.AssertNil(t, w.add(mockingbird.TestResult{ID: second, TestSuite: "google:test", Trigger: mockingbird.MANUAL}))
+	waitForStatus(t, store, first, mockingbird.RUNNING)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	testdata.AssertErr(t, w.stop(ctx))
+
+	tr, err := store.GetTestResult(first)
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, tr.IsInterrupted())
+	if mockingbird.DONE != tr.Status {
+		t.Errorf("\nWant: %s\n Got: %s\n", mockingbird.DONE, tr.Status)
+	}
+	testdata.AssertTrue(t, strings.Contains(tr.Log, interruptedLog))
+
+	tr, err = store.GetTestResult(second)
+	testdata.AssertNil(t, err)
+	if mockingbird.QUEUED != tr.Status {
+		t.Errorf("\nWant: %s\n Got: %s\n", mockingbird.QUEUED, tr.Status)
+	}
+	assertQueue(t, store, second)
+}
+
+// fakeMage puts a mage command that runs script first in the PATH; call
+// the returned function to restore the PATH
+func fakeMage(t *testing.T, script string) func() {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "mockingbird-mage-")
+	testdata.AssertNil(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "mage"), []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	testdata.AssertNil(t, err)
+
+	path := os.Getenv("PATH")
+	testdata.AssertNil(t, os.Setenv("PATH", dir+string(os.PathListSeparator)+path))
+	return func() {
+		_ = os.Setenv("PATH", path)
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func waitForStatus(t *testing.T, store mockingbird.Store, id mockingbird.ULID, status mockingbird.Status) {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		tr, err := store.GetTestResult(id)
+		testdata.AssertNil(t, err)
+		if status == tr.Status {
+			return
+		}
+	}
+	t.Fatalf("\nWant: %s\n Got: timed out\n", status)
+}
+
+func assertQueue(t *testing.T, store mockingbird.Store, want ...mockingbird.ULID) {
+	t.Helper()
+
+	got, err := store.Queue()
+	testdata.AssertNil(t, err)
+	if len(want) != len(got) {
+		t.Fatalf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+		}
+	}
+}
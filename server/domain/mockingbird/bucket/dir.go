@@ -0,0 +1,132 @@
+package bucket
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/errs"
+)
+
+// Dir implements the Bucket interface on top of a local directory
+type Dir struct {
+	root string
+}
+
+// Verifies that *Dir implements Bucket interface
+var _ Bucket = &Dir{}
+
+// NewDir returns a Dir rooted at root; the directory is created if missing
+func NewDir(root string) (*Dir, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "filepath.Abs(%s) failed", root)
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, errors.Wrapf(err, "os.MkdirAll(%s) failed", root)
+	}
+
+	return &Dir{root: root}, nil
+}
+
+// Put writes body to a temporary file and renames it to key, so readers
+// never see a partially written object
+func (d *Dir) Put(key string, body []byte) error {
+	f, err := ioutil.TempFile(d.root, ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "ioutil.TempFile() failed")
+	}
+	tmp := f.Name()
+
+	if _, err := f.Write(body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return errors.Wrapf(err, "write %s failed", tmp)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return errors.Wrapf(err, "sync %s failed", tmp)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrapf(err, "close %s failed", tmp)
+	}
+
+	if err := os.Rename(tmp, d.path(key)); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Wrapf(err, "os.Rename(%s) failed", key)
+	}
+	return nil
+}
+
+// Get returns the content of key
+func (d *Dir) Get(key string) ([]byte, error) {
+	b, err := ioutil.ReadFile(d.path(key))
+	if os.IsNotExist(err) {
+		msg := fmt.Sprintf("object %s not found", key)
+		return nil, errs.NotFound(msg)
+	}
+	return b, errors.Wrapf(err, "ioutil.ReadFile(%s) failed", key)
+}
+
+// List returns at most max keys with the given prefix that sort after marker
+func (d *Dir) List(prefix, marker string, max int) ([]string, error) {
+	names, err := d.names()
+	if err != nil {
+		return nil, err
+	}
+
+	i := sort.SearchStrings(names, marker)
+	if i < len(names) && names[i] == marker {
+		i++
+	}
+
+	var keys []string
+	for _, name := range names[i:] {
+		if len(keys) == max {
+			break
+		}
+		if strings.HasPrefix(name, prefix) {
+			keys = append(keys, name)
+		}
+	}
+	return keys, nil
+}
+
+// URL returns a file URL to key
+func (d *Dir) URL(key string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(d.path(key))}
+	return u.String()
+}
+
+//
+// PRIVATE
+//
+
+func (d *Dir) path(key string) string {
+	return filepath.Join(d.root, filepath.Base(key))
+}
+
+func (d *Dir) names() ([]string, error) {
+	f, err := os.Open(d.root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "os.Open(%s) failed", d.root)
+	}
+	defer func() { _ = f.Close() }()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "f.Readdirnames(%s) failed", d.root)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,413 @@
+package bucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/errs"
+)
+
+// Bucket defines the object storage the Store is built on
+//
+// Note:
+//
+//        * Put must replace an object atomically.
+//        * Get must return an errs.NotFound error for missing objects.
+//        * List returns keys in ascending order, starting after marker.
+//
+type Bucket interface {
+	Put(key string, body []byte) error
+	Get(key string) ([]byte, error)
+	List(prefix, marker string, max int) ([]string, error)
+	URL(key string) string
+}
+
+// Object keys
+//
+//      bucket/{ stats.json | queue.json | test-{inverted-time}-{id}.json | log-{id}.json }
+//
+// The test key starts with the inverted ULID timestamp, so listing
+// the bucket in key order returns the newest test result first.
+//
+const (
+	statsKey   = "stats.json"
+	queueKey   = "queue.json"
+	testPrefix = "test-"
+	logPrefix  = "log-"
+	jsonSuffix = ".json"
+)
+
+const (
+	defaultPageSize  = 10
+	defaultScanLimit = 1000 // test results read per ListTestResults call
+)
+
+// Store implements the mockingbird.Store interface
+//
+// Note:
+//
+//        Each test result is stored as a JSON document without its
+//        log; the log is stored in its own object.
+//
+type Store struct {
+	Bucket    Bucket
+	PageSize  int
+	ScanLimit int
+}
+
+// Verifies that *Store implements mockingbird.Store interface
+var _ mockingbird.Store = &Store{}
+
+// NewStore returns a store that keeps its objects in b
+func NewStore(b Bucket) *Store {
+	return &Store{Bucket: b, PageSize: defaultPageSize, ScanLimit: defaultScanLimit}
+}
+
+type logObject struct {
+	ID  mockingbird.ULID
+	Log string
+}
+
+//
+// Test results
+//
+
+// SaveTestResult inserts or updates the test result
+func (s *Store) SaveTestResult(tr mockingbird.TestResult) error {
+	key, err := testKey(tr.ID)
+	if err != nil {
+		return err
+	}
+
+	if tr.Log != "" {
+		lk := logKey(tr.ID)
+		b, err := json.Marshal(logObject{ID: tr.ID, Log: tr.Log})
+		if err != nil {
+			return errors.Wrapf(err, "json.Marshal(log %s) failed", tr.ID)
+		}
+		if err := s.Bucket.Put(lk, b); err != nil {
+			return errors.Wrapf(err, "s.Bucket.Put(%s) failed", lk)
+		}
+		tr.LogURL = s.Bucket.URL(lk)
+	}
+
+	tr.Log = ""
+	b, err := json.Marshal(tr)
+	if err != nil {
+		return errors.Wrapf(err, "json.Marshal(test result %s) failed", tr.ID)
+	}
+
+	return errors.Wrapf(s.Bucket.Put(key, b), "s.Bucket.Put(%s) failed", key)
+}
+
+// GetTestResult returns the test result, including its log, for the given id
+func (s *Store) GetTestResult(id mockingbird.ULID) (mockingbird.TestResult, error) {
+	key, err := testKey(id)
+	if err != nil {
+		msg := fmt.Sprintf("test result %s not found", id)
+		return mockingbird.TestResult{}, errs.NotFound(msg)
+	}
+
+	tr, err := s.getTestResult(key)
+	if err != nil {
+		return tr, err
+	}
+	if tr.LogURL == "" {
+		return tr, nil
+	}
+
+	lk := logKey(id)
+	b, err := s.Bucket.Get(lk)
+	if err != nil {
+		return tr, errors.Wrapf(err, "s.Bucket.Get(%s) failed", lk)
+	}
+
+	l := logObject{}
+	if err := json.Unmarshal(b, &l); err != nil {
+		return tr, errors.Wrapf(err, "json.Unmarshal(%s) failed", lk)
+	}
+	tr.Log = l.Log
+
+	return tr, nil
+}
+
+// ListTestResults returns a page of the test results that match the query, newest first
+//
+// Note:
+//
+//        The page token is the ID of the last test result that was read
+//        for the previous page. Logs are not included, but they are read
+//        to match the query text.
+//
+//        The keys are in ULID time order, so the scan starts at
+//        StartedBefore and stops at the first key that is older than
+//        StartedAfter; a test result that was queued before StartedAfter
+//        and started after it is not listed. At most ScanLimit test
+//        results are read per call, so a page can have fewer test
+//        results than the page size and still have a next page token.
+//
+func (s *Store) ListTestResults(q mockingbird.ListTestsQuery) (*mockingbird.TestResults, error) {
+	marker := ""
+	if q.PageToken != "" {
+		key, err := testKey(mockingbird.ULID(q.PageToken))
+		if err != nil {
+			return nil, err
+		}
+		marker = key
+	}
+	if !q.StartedBefore.IsZero() {
+		if m := beforeMarker(q.StartedBefore); m > marker {
+			marker = m
+		}
+	}
+
+	size := q.Size(s.pageSize())
+	limit := s.scanLimit()
+	trs := make([]mockingbird.TestResult, 0, size+1)
+
+	for read := 0; ; {
+		keys, err := s.Bucket.List(testPrefix, marker, size+1)
+		if err != nil {
+			return nil, errors.Wrapf(err, "s.Bucket.List(%s, %s) failed", testPrefix, marker)
+		}
+
+		for _, key := range keys {
+			if !q.StartedAfter.IsZero() {
+				t, err := keyTime(key)
+				if err != nil {
+					return nil, err
+				}
+				if t < ulid.Timestamp(q.StartedAfter) {
+					return page(trs, size, ""), nil
+				}
+			}
+
+			tr, err := s.getTestResult(key)
+			if err != nil {
+				return nil, err
+			}
+			read++
+
+			ok, err := s.match(q, tr)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				trs = append(trs, tr)
+			}
+			if len(trs) > size {
+				return page(trs, size, ""), nil
+			}
+			if read >= limit {
+				return page(trs, size, string(tr.ID)), nil
+			}
+		}
+
+		if len(keys) <= size {
+			return page(trs, size, ""), nil
+		}
+		marker = keys[len(keys)-1]
+	}
+}
+
+//
+// Stats
+//
+
+// SaveStats replaces stats.json
+func (s *Store) SaveStats(stats mockingbird.Stats) error {
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal(stats) failed")
+	}
+
+	return errors.Wrapf(s.Bucket.Put(statsKey, b), "s.Bucket.Put(%s) failed", statsKey)
+}
+
+// GetStats returns the content of stats.json
+func (s *Store) GetStats() (mockingbird.Stats, error) {
+	stats := mockingbird.Stats{}
+
+	b, err := s.Bucket.Get(statsKey)
+	if errs.IsNotFound(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, errors.Wrapf(err, "s.Bucket.Get(%s) failed", statsKey)
+	}
+
+	err = json.Unmarshal(b, &stats)
+	return stats, errors.Wrapf(err, "json.Unmarshal(%s) failed", statsKey)
+}
+
+//
+// Queue
+//
+
+// Enqueue appends id to queue.json
+//
+// Note:
+//
+//        queue.json is read, modified and written back, so calls to
+//        Enqueue and Dequeue must be serialized by the caller.
+//
+func (s *Store) Enqueue(id mockingbird.ULID) error {
+	q, err := s.Queue()
+	if err != nil {
+		return err
+	}
+
+	for _, qid := range q {
+		if qid == id {
+			return nil
+		}
+	}
+
+	return s.putQueue(append(q, id))
+}
+
+// Dequeue removes id from queue.json
+func (s *Store) Dequeue(id mockingbird.ULID) error {
+	q, err := s.Queue()
+	if err != nil {
+		return err
+	}
+
+	for i, qid := range q {
+		if qid == id {
+			return s.putQueue(append(q[:i], q[i+1:]...))
+		}
+	}
+	return nil
+}
+
+// Queue returns the content of queue.json, oldest first
+func (s *Store) Queue() ([]mockingbird.ULID, error) {
+	q := []mockingbird.ULID{}
+
+	b, err := s.Bucket.Get(queueKey)
+	if errs.IsNotFound(err) {
+		return q, nil
+	}
+	if err != nil {
+		return q, errors.Wrapf(err, "s.Bucket.Get(%s) failed", queueKey)
+	}
+
+	err = json.Unmarshal(b, &q)
+	return q, errors.Wrapf(err, "json.Unmarshal(%s) failed", queueKey)
+}
+
+//
+// PRIVATE
+//
+
+func (s *Store) putQueue(q []mockingbird.ULID) error {
+	b, err := json.Marshal(q)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal(queue) failed")
+	}
+
+	return errors.Wrapf(s.Bucket.Put(queueKey, b), "s.Bucket.Put(%s) failed", queueKey)
+}
+
+func (s *Store) pageSize() int {
+	if s.PageSize < 1 {
+		return defaultPageSize
+	}
+	return s.PageSize
+}
+
+func (s *Store) scanLimit() int {
+	if s.ScanLimit < 1 {
+		return defaultScanLimit
+	}
+	return s.ScanLimit
+}
+
+// page returns the first size test results; token is the next page token
+// when the scan stopped before the page was full
+func page(trs []mockingbird.TestResult, size int, token string) *mockingbird.TestResults {
+	if len(trs) > size {
+		trs = trs[:size]
+		token = string(trs[size-1].ID)
+	}
+	return &mockingbird.TestResults{NextPageToken: token, TestResults: trs}
+}
+
+// match matches the test result, with its log when the query has a text
+func (s *Store) match(q mockingbird.ListTestsQuery, tr mockingbird.TestResult) (bool, error) {
+	if q.Text == "" || tr.LogURL == "" {
+		return q.Match(tr), nil
+	}
+
+	// match the other filters first, the log is an extra read
+	filters := q
+	filters.Text = ""
+	if !filters.Match(tr) {
+		return false, nil
+	}
+
+	full, err := s.GetTestResult(tr.ID)
+	if err != nil {
+		return false, err
+	}
+	return q.Match(full), nil
+}
+
+func (s *Store) getTestResult(key string) (mockingbird.TestResult, error) {
+	tr := mockingbird.TestResult{}
+
+	b, err := s.Bucket.Get(key)
+	if err != nil {
+		return tr, errors.Wrapf(err, "s.Bucket.Get(%s) failed", key)
+	}
+
+	err = json.Unmarshal(b, &tr)
+	return tr, errors.Wrapf(err, "json.Unmarshal(%s) failed", key)
+}
+
+func testKey(id mockingbird.ULID) (string, error) {
+	u, err := ulid.Parse(string(id))
+	if err != nil {
+		return "", errors.Wrapf(err, "ulid.Parse(%s) failed", id)
+	}
+
+	inverted := ulid.MaxTime() - u.Time()
+	return fmt.Sprintf("%s%015d-%s%s", testPrefix, inverted, id, jsonSuffix), nil
+}
+
+// beforeMarker returns the marker that skips the keys of the test results
+// created after the millisecond of t, which can not have started before t
+func beforeMarker(t time.Time) string {
+	inverted := ulid.MaxTime() - ulid.Timestamp(t)
+	if inverted > 0 {
+		inverted--
+	}
+	// ~ sorts after the ULIDs of the keys that have the same time
+	return fmt.Sprintf("%s%015d-~", testPrefix, inverted)
+}
+
+// keyTime returns the ULID time of a test key
+func keyTime(key string) (uint64, error) {
+	digits := strings.TrimPrefix(key, testPrefix)
+	if len(digits) < 15 {
+		return 0, errors.Errorf("test key %s has no time", key)
+	}
+
+	inverted, err := strconv.ParseUint(digits[:15], 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "test key %s has no time", key)
+	}
+	return ulid.MaxTime() - inverted, nil
+}
+
+func logKey(id mockingbird.ULID) string {
+	return logPrefix + string(id) + jsonSuffix
+}
@@ -0,0 +1,312 @@
+package bucket_test
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/bucket"
+	"github.com/unders/mockingbird/server/pkg/errs"
+	"github.com/unders/mockingbird/server/pkg/s3"
+	"github.com/unders/mockingbird/server/pkg/s3/s3test"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestStore_SaveTestResult_StoresLogInItsOwnObject(t *testing.T) {
+	root, s := newDirStore(t)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	id := newID(t, time.Now())
+	tr := mockingbird.TestResult{ID: id, Status: mockingbird.DONE, Log: "--- PASS: TestSearch"}
+	testdata.AssertNil(t, s.SaveTestResult(tr))
+
+	got, err := s.GetTestResult(id)
+	testdata.AssertNil(t, err)
+
+	if tr.Log != got.Log {
+		t.Errorf("\nWant: %s\n Got: %s\n", tr.Log, got.Log)
+	}
+	wantURL := "file://" + root + "/log-" + string(id) + ".json"
+	if wantURL != got.LogURL {
+		t.Errorf("\nWant: %s\n Got: %s\n", wantURL, got.LogURL)
+	}
+
+	b, err := ioutil.ReadFile(root + "/log-" + string(id) + ".json")
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, strings.Contains(string(b), tr.Log))
+
+	_, err = s.GetTestResult(newID(t, time.Now()))
+	testdata.AssertTrue(t, errs.IsNotFound(err))
+}
+
+func TestStore_ListTestResults_ReturnsNewestFirst(t *testing.T) {
+	root, s := newDirStore(t)
+	defer func() { _ = os.RemoveAll(root) }()
+	s.PageSize = 2
+
+	start := time.Now()
+	var ids []mockingbird.ULID
+	for i := 0; i < 5; i++ {
+		id := newID(t, start.Add(time.Duration(i)*time.Second))
+		ids = append(ids, id)
+		testdata.AssertNil(t, s.SaveTestResult(mockingbird.TestResult{ID: id}))
+	}
+
+	var got []mockingbird.ULID
+	q := mockingbird.ListTestsQuery{}
+	for i := 0; i < 5; i++ {
+		page, err := s.ListTestResults(q)
+		testdata.AssertNil(t, err)
+		for _, tr := range page.TestResults {
+			got = append(got, tr.ID)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		q.PageToken = page.NextPageToken
+	}
+
+	want := []mockingbird.ULID{ids[4], ids[3], ids[2], ids[1], ids[0]}
+	if len(want) != len(got) {
+		t.Fatalf("\nWant: %v\n Got: %v\n", want, got)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("\nWant: %v\n Got: %v\n", want, got)
+		}
+	}
+}
+
+func TestStore_Stats(t *testing.T) {
+	root, s := newDirStore(t)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	stats, err := s.GetStats()
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, stats.TestSuiteRunCounter == 0)
+
+	stats.TestSuiteRunCounter = 7
+	testdata.AssertNil(t, s.SaveStats(stats))
+
+	stats, err = s.GetStats()
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, stats.TestSuiteRunCounter == 7)
+}
+
+func TestStore_Queue_KeepsEnqueueOrder(t *testing.T) {
+	root, s := newDirStore(t)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	q, err := s.Queue()
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, len(q) == 0)
+
+	start := time.Now()
+	a, b, c := newID(t, start.Add(2*time.Second)), newID(t, start), newID(t, start.Add(time.Second))
+	for _, id := range []mockingbird.ULID{a, b, c, a} {
+		testdata.AssertNil(t, s.Enqueue(id))
+	}
+	testdata.AssertNil(t, s.Dequeue(b))
+
+	q, err = s.Queue()
+	testdata.AssertNil(t, err)
+	if len(q) != 2 || q[0] != a || q[1] != c {
+		t.Errorf("\nWant: [%s %s]\n Got: %v\n", a, c, q)
+	}
+}
+
+func TestStore_ListTestResults_FiltersOverManyListCalls(t *testing.T) {
+	root, s := newDirStore(t)
+	defer func() { _ = os.RemoveAll(root) }()
+	s.PageSize = 2
+
+	start := time.Now()
+	var ids []mockingbird.ULID
+	for i := 0; i < 7; i++ {
+		id := newID(t, start.Add(time.Duration(i)*time.Second))
+		ids = append(ids, id)
+
+		tr := mockingbird.TestResult{ID: id, State: mockingbird.SUCCESSFUL, Log: "PASS"}
+		if i%3 == 0 {
+			tr.State = mockingbird.FAILED
+			tr.Log = "--- FAIL: TestSearch"
+		}
+		testdata.AssertNil(t, s.SaveTestResult(tr))
+	}
+
+	q := mockingbird.ListTestsQuery{State: mockingbird.FAILED, Text: "FAIL"}
+	page, err := s.ListTestResults(q)
+	testdata.AssertNil(t, err)
+	if len(page.TestResults) != 2 || page.TestResults[0].ID != ids[6] || page.TestResults[1].ID != ids[3] {
+		t.Fatalf("\nWant: [%s %s]\n Got: %+v\n", ids[6], ids[3], page.TestResults)
+	}
+	testdata.AssertTrue(t, page.TestResults[0].Log == "")
+
+	q.PageToken = page.NextPageToken
+	page, err = s.ListTestResults(q)
+	testdata.AssertNil(t, err)
+	if len(page.TestResults) != 1 || page.TestResults[0].ID != ids[0] || page.NextPageToken != "" {
+		t.Fatalf("\nWant: [%s]\n Got: %+v\n", ids[0], page)
+	}
+}
+
+func TestStore_ListTestResults_ReadsOnlyTheKeysOfTheTimeRange(t *testing.T) {
+	root, s := newDirStore(t)
+	defer func() { _ = os.RemoveAll(root) }()
+	b := &countingBucket{Bucket: s.Bucket}
+	s.Bucket = b
+
+	start := time.Now().Truncate(time.Second)
+	var ids []mockingbird.ULID
+	for i := 0; i < 10; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		id := newID(t, now)
+		ids = append(ids, id)
+		testdata.AssertNil(t, s.SaveTestResult(mockingbird.TestResult{ID: id, StartTime: now}))
+	}
+
+	q := mockingbird.ListTestsQuery{StartedAfter: start.Add(3 * time.Second), StartedBefore: start.Add(7 * time.Second)}
+	page, err := s.ListTestResults(q)
+	testdata.AssertNil(t, err)
+
+	want := []mockingbird.ULID{ids[6], ids[5], ids[4], ids[3]}
+	var got []mockingbird.ULID
+	for _, tr := range page.TestResults {
+		got = append(got, tr.ID)
+	}
+	if len(want) != len(got) || page.NextPageToken != "" {
+		t.Fatalf("\nWant: %v\n Got: %v %s\n", want, got, page.NextPageToken)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("\nWant: %v\n Got: %v\n", want, got)
+		}
+	}
+	// the test result of the StartedBefore millisecond is read, it may have started before it
+	if want, got := 5, b.gets; want != got {
+		t.Errorf("\nWant: %d reads\n Got: %d reads\n", want, got)
+	}
+}
+
+func TestStore_ListTestResults_ReadsAtMostScanLimitTestResults(t *testing.T) {
+	root, s := newDirStore(t)
+	defer func() { _ = os.RemoveAll(root) }()
+	b := &countingBucket{Bucket: s.Bucket}
+	s.Bucket = b
+	s.PageSize = 2
+	s.ScanLimit = 3
+
+	start := time.Now()
+	var ids []mockingbird.ULID
+	for i := 0; i < 7; i++ {
+		id := newID(t, start.Add(time.Duration(i)*time.Second))
+		ids = append(ids, id)
+
+		tr := mockingbird.TestResult{ID: id, State: mockingbird.SUCCESSFUL}
+		if i%3 == 0 {
+			tr.State = mockingbird.FAILED
+		}
+		testdata.AssertNil(t, s.SaveTestResult(tr))
+	}
+
+	wantPages := [][]mockingbird.ULID{{ids[6]}, {ids[3]}, {ids[0]}}
+	q := mockingbird.ListTestsQuery{State: mockingbird.FAILED}
+	for i, want := range wantPages {
+		b.gets = 0
+		page, err := s.ListTestResults(q)
+		testdata.AssertNil(t, err)
+
+		if len(page.TestResults) != len(want) || page.TestResults[0].ID != want[0] {
+			t.Fatalf("page %d\nWant: %v\n Got: %+v\n", i+1, want, page.TestResults)
+		}
+		if b.gets > s.ScanLimit {
+			t.Errorf("page %d\nWant: at most %d reads\n Got: %d reads\n", i+1, s.ScanLimit, b.gets)
+		}
+		if last := i == len(wantPages)-1; last != (page.NextPageToken == "") {
+			t.Fatalf("page %d\nWant: a next page token %t\n Got: %q\n", i+1, !last, page.NextPageToken)
+		}
+		q.PageToken = page.NextPageToken
+	}
+}
+
+func TestStore_OnS3_ListsWithPrefixAndMarker(t *testing.T) {
+	srv := s3test.NewServer("results")
+	defer srv.Close()
+
+	c, err := s3.New(s3.Config{Endpoint: srv.URL, Bucket: "results", PathStyle: true})
+	testdata.AssertNil(t, err)
+	s := bucket.NewStore(c)
+	s.PageSize = 2
+
+	start := time.Now()
+	var ids []mockingbird.ULID
+	for i := 0; i < 3; i++ {
+		id := newID(t, start.Add(time.Duration(i)*time.Second))
+		ids = append(ids, id)
+		tr := mockingbird.TestResult{ID: id, Log: "log " + string(id)}
+		testdata.AssertNil(t, s.SaveTestResult(tr))
+	}
+	testdata.AssertNil(t, s.SaveStats(mockingbird.Stats{TestSuiteRunCounter: 3}))
+
+	_, ok := srv.Object("stats.json")
+	testdata.AssertTrue(t, ok)
+	_, ok = srv.Object("log-" + string(ids[0]) + ".json")
+	testdata.AssertTrue(t, ok)
+
+	page, err := s.ListTestResults(mockingbird.ListTestsQuery{})
+	testdata.AssertNil(t, err)
+	if len(page.TestResults) != 2 || page.TestResults[0].ID != ids[2] {
+		t.Fatalf("\nWant: [%s %s]\n Got: %+v\n", ids[2], ids[1], page.TestResults)
+	}
+
+	page, err = s.ListTestResults(mockingbird.ListTestsQuery{PageToken: page.NextPageToken})
+	testdata.AssertNil(t, err)
+	if len(page.TestResults) != 1 || page.TestResults[0].ID != ids[0] {
+		t.Fatalf("\nWant: [%s]\n Got: %+v\n", ids[0], page.TestResults)
+	}
+
+	listedWithMarker := false
+	for _, r := range srv.Requests {
+		if strings.Contains(r, "prefix=test-") && strings.Contains(r, "marker=test-") {
+			listedWithMarker = true
+		}
+	}
+	testdata.AssertTrue(t, listedWithMarker)
+}
+
+// countingBucket counts the objects that are read
+type countingBucket struct {
+	bucket.Bucket
+	gets int
+}
+
+func (b *countingBucket) Get(key string) ([]byte, error) {
+	b.gets++
+	return b.Bucket.Get(key)
+}
+
+func newDirStore(t *testing.T) (string, *bucket.Store) {
+	t.Helper()
+
+	root, err := ioutil.TempDir("", "mockingbird-bucket-")
+	testdata.AssertNil(t, err)
+
+	dir, err := bucket.NewDir(root)
+	testdata.AssertNil(t, err)
+
+	return root, bucket.NewStore(dir)
+}
+
+func newID(t *testing.T, now time.Time) mockingbird.ULID {
+	t.Helper()
+
+	id, err := ulid.New(ulid.Timestamp(now), rand.Reader)
+	testdata.AssertNil(t, err)
+	return mockingbird.ULID(id.String())
+}
@@ -0,0 +1,90 @@
+package mockingbird
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Deployment is a deploy of the service under test, as sent to the deploy webhook
+type Deployment struct {
+	Service     string `json:"service"`
+	Version     string `json:"version"`
+	Environment string `json:"environment"`
+	Commit      string `json:"commit"`
+}
+
+// Validate returns an error if the deployment has no service
+func (d Deployment) Validate() error {
+	if d.Service == "" {
+		return errors.New("service is required")
+	}
+	return nil
+}
+
+// DeployRule maps the deployments of a service to the test suites that run after them
+//
+// Note:
+//
+//        An empty Service or Environment matches any; so does "*".
+//
+type DeployRule struct {
+	Service     string      `json:"service"`
+	Environment string      `json:"environment"`
+	TestSuites  []TestSuite `json:"test_suites"`
+}
+
+// Match returns true if the rule matches the deployment
+func (r DeployRule) Match(d Deployment) bool {
+	return matchAny(r.Service, d.Service) && matchAny(r.Environment, d.Environment)
+}
+
+// DeployRules is the rule table of the deploy webhook
+type DeployRules []DeployRule
+
+// TestSuites returns the test suites of all rules that match the deployment,
+// in rule order and without duplicates
+func (rules DeployRules) TestSuites(d Deployment) []TestSuite {
+	var suites []TestSuite
+	seen := map[TestSuite]bool{}
+	for _, r := range rules {
+		if !r.Match(d) {
+			continue
+		}
+		for _, s := range r.TestSuites {
+			if !seen[s] {
+				seen[s] = true
+				suites = append(suites, s)
+			}
+		}
+	}
+	return suites
+}
+
+// ParseDeployRules returns the rule table of a JSON document
+//
+// Usage:
+//
+//        [
+//          {"service": "api", "environment": "staging", "test_suites": ["google:test"]},
+//          {"service": "api", "test_suites": ["all:test"]}
+//        ]
+//
+func ParseDeployRules(r io.Reader) (DeployRules, error) {
+	var rules DeployRules
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, errors.Wrap(err, "json.Decode(rules) failed")
+	}
+
+	for i, rule := range rules {
+		if len(rule.TestSuites) == 0 {
+			return nil, errors.Errorf("rule %d has no test_suites", i+1)
+		}
+	}
+	return rules, nil
+}
+
+func matchAny(pattern, value string) bool {
+	return pattern == "" || pattern == "*" || pattern == value
+}
@@ -0,0 +1,57 @@
+package mockingbird_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+func TestDeployRules_TestSuites_ReturnsTheMatchingTestSuites(t *testing.T) {
+	rules, err := mockingbird.ParseDeployRules(strings.NewReader(`[
+		{"service": "api", "environment": "staging", "test_suites": ["google:test"]},
+		{"service": "api", "test_suites": ["all:test", "google:test"]},
+		{"service": "*", "environment": "production", "test_suites": ["smoke:test"]}
+	]`))
+	if err != nil {
+		t.Fatalf("\nWant: nil\n Got: %s\n", err)
+	}
+
+	testCases := []struct {
+		d    mockingbird.Deployment
+		want []mockingbird.TestSuite
+	}{
+		{
+			d:    mockingbird.Deployment{Service: "api", Environment: "staging"},
+			want: []mockingbird.TestSuite{"google:test", "all:test"},
+		},
+		{
+			d:    mockingbird.Deployment{Service: "api", Environment: "production"},
+			want: []mockingbird.TestSuite{"all:test", "google:test", "smoke:test"},
+		},
+		{
+			d:    mockingbird.Deployment{Service: "web", Environment: "production"},
+			want: []mockingbird.TestSuite{"smoke:test"},
+		},
+		{
+			d:    mockingbird.Deployment{Service: "web", Environment: "staging"},
+			want: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		got := rules.TestSuites(tc.d)
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("%+v\nWant: %v\n Got: %v\n", tc.d, tc.want, got)
+		}
+	}
+}
+
+func TestParseDeployRules_WhenInvalid_ReturnsError(t *testing.T) {
+	for _, s := range []string{"", "{}", `[{"service": "api"}]`} {
+		if _, err := mockingbird.ParseDeployRules(strings.NewReader(s)); err == nil {
+			t.Errorf("%q\nWant: error\n Got: nil\n", s)
+		}
+	}
+}
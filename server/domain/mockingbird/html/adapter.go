@@ -45,13 +45,13 @@ func (a Adapter) Dashboard() (code int, body []byte, err error) {
 }
 
 // ListTest returns the ListTest page
-func (a Adapter) ListTests(pageToken string) (code int, body []byte, err error) {
-	tests, err := a.App.ListTests(pageToken)
+func (a Adapter) ListTests(q mockingbird.ListTestsQuery) (code int, body []byte, err error) {
+	tests, err := a.App.ListTests(q)
 	if err != nil {
 		return http.StatusInternalServerError, a.Tmpl.InternalError(), err
 	}
 
-	b, err := a.Tmpl.ListTest(tests)
+	b, err := a.Tmpl.ListTest(tests, q, a.App.ShowTestSuites())
 	if err != nil {
 		return http.StatusInternalServerError, a.Tmpl.InternalError(), err
 	}
@@ -78,8 +78,7 @@ func (a Adapter) ShowTest(id mockingbird.ULID) (code int, body []byte, err error
 
 // ShowTestSuites returns the ShowTestSuites page
 func (a Adapter) ShowTestSuites() (code int, body []byte, err error) {
-	ts := a.App.ShowTestSuites()
-	b, err := a.Tmpl.ShowTestSuites(ts)
+	b, err := a.Tmpl.ShowTestSuites(a.App.ShowTestSuites(), a.App.ShowSchedules())
 	if err != nil {
 		return http.StatusInternalServerError, a.Tmpl.InternalError(), err
 	}
@@ -88,8 +87,8 @@ func (a Adapter) ShowTestSuites() (code int, body []byte, err error) {
 }
 
 // RunTest starts a test suite
-func (a Adapter) RunTest(ts mockingbird.TestSuite) (id mockingbird.ULID, code int, body []byte, err error) {
-	ulid, err := a.App.RunTest(ts)
+func (a Adapter) RunTest(ts mockingbird.TestSuite, user string) (id mockingbird.ULID, code int, body []byte, err error) {
+	ulid, err := a.App.RunTest(ts, user)
 	if errs.IsNotFound(err) {
 		return ulid, http.StatusNotFound, a.Tmpl.ErrorNotFound(), err
 	}
@@ -101,6 +100,30 @@ func (a Adapter) RunTest(ts mockingbird.TestSuite) (id mockingbird.ULID, code in
 	return ulid, 200, nil, nil
 }
 
+// CancelTest cancels a queued or running test suite
+func (a Adapter) CancelTest(id mockingbird.ULID) (code int, body []byte, err error) {
+	err = a.App.CancelTest(id)
+	if errs.IsNotFound(err) {
+		return http.StatusNotFound, a.Tmpl.ErrorNotFound(), err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, a.Tmpl.InternalError(), err
+	}
+
+	// When no error, code, body, nil are ignored...
+	return 200, nil, nil
+}
+
+// ShowDeliveries returns the notification delivery log page
+func (a Adapter) ShowDeliveries() (code int, body []byte, err error) {
+	b, err := a.Tmpl.ShowDeliveries(a.App.ShowDeliveries())
+	if err != nil {
+		return http.StatusInternalServerError, a.Tmpl.InternalError(), err
+	}
+
+	return 200, b, nil
+}
+
 //
 // Error pages
 //
@@ -114,3 +137,8 @@ func (a Adapter) ErrorNotFound() (body []byte) {
 func (a Adapter) InvalidURL() (body []byte) {
 	return a.Tmpl.InvalidURL()
 }
+
+// InternalError returns the internal error page
+func (a Adapter) InternalError() (body []byte) {
+	return a.Tmpl.InternalError()
+}
@@ -0,0 +1,66 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+)
+
+// The size of a sparkline in SVG user units
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+	sparklinePad    = 2
+)
+
+// sparkline returns an inline SVG line of the values, scaled from 0 to max;
+// NaN values are gaps that the line skips
+//
+// Note:
+//
+//        The dashboard renders the sparklines on the server, so the page
+//        needs no JavaScript charting library.
+//
+func sparkline(values []float64, max float64, class string) template.HTML {
+	if max <= 0 {
+		max = 1
+	}
+
+	step := 0.0
+	if len(values) > 1 {
+		step = float64(sparklineWidth-2*sparklinePad) / float64(len(values)-1)
+	}
+
+	var (
+		points bytes.Buffer
+		lastX  float64
+		lastY  float64
+		n      int
+	)
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+
+		lastX = sparklinePad + float64(i)*step
+		if len(values) == 1 {
+			lastX = sparklineWidth / 2
+		}
+		lastY = sparklineHeight - sparklinePad - (v/max)*(sparklineHeight-2*sparklinePad)
+		fmt.Fprintf(&points, "%.1f,%.1f ", lastX, lastY)
+		n++
+	}
+
+	var svg bytes.Buffer
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" class="sparkline %s" viewBox="0 0 %d %d" width="%d" height="%d">`,
+		template.HTMLEscapeString(class), sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight)
+	if n > 0 {
+		fmt.Fprintf(&svg, `<polyline points="%s"></polyline>`, bytes.TrimSpace(points.Bytes()))
+		fmt.Fprintf(&svg, `<circle cx="%.1f" cy="%.1f" r="1.5"></circle>`, lastX, lastY)
+	}
+	svg.WriteString(`</svg>`)
+
+	// the SVG has only numbers and an escaped class name
+	return template.HTML(svg.String())
+}
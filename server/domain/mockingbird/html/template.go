@@ -2,6 +2,9 @@ package html
 
 import (
 	"fmt"
+	"html/template"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
@@ -13,7 +16,8 @@ import (
 
 // Layouts
 const (
-	mainLayout = "main.html"
+	mainLayout  = "main.html"
+	emailLayout = "email.html"
 )
 
 // Page file names
@@ -22,9 +26,14 @@ const (
 	testResult      = "tests/show.html"
 	testResultsFile = "tests/index.html"
 	testSuitesFile  = "tests/suites.html"
+	deliveriesFile  = "tests/notifications.html"
 	errorFile       = "error.html"
+	emailReportFile = "email/report.html"
 )
 
+// emailLogLines is the number of log lines at the end of the log that an email report shows
+const emailLogLines = 20
+
 // Assets files
 const (
 	cssFile = "/public/css/main.css"
@@ -40,7 +49,10 @@ type Path struct {
 	ListTests      string
 	RunTest        string
 	showTest       string
+	cancelTest     string
+	logStream      string
 	ListTestSuites string
+	ListDeliveries string
 }
 
 var path = Path{
@@ -48,7 +60,10 @@ var path = Path{
 	ListTests:      "/tests/",
 	RunTest:        "/tests/",
 	showTest:       "/tests/%s",
+	cancelTest:     "/tests/%s/cancel",
+	logStream:      "/tests/%s/log/stream",
 	ListTestSuites: "/tests/-/suites/",
+	ListDeliveries: "/tests/-/notifications/",
 }
 
 // ShowTest returns path to show test page
@@ -56,6 +71,16 @@ func (p Path) ShowTest(id string) string {
 	return fmt.Sprintf(p.showTest, id)
 }
 
+// CancelTest returns path to cancel a test
+func (p Path) CancelTest(id string) string {
+	return fmt.Sprintf(p.cancelTest, id)
+}
+
+// LogStream returns path to the live log of a test
+func (p Path) LogStream(id string) string {
+	return fmt.Sprintf(p.logStream, id)
+}
+
 //
 // Pages
 //
@@ -74,20 +99,51 @@ type dashboardPage struct {
 	LatestTestSuitePath     string
 	LatestFullTestSuitePath string
 
+	Suites  []suiteCard
+	Windows []windowCard
+
 	Path *Path
 	mockingbird.Dashboard
 }
 
+// suiteCard contains the stats of one test suite on the dashboard page
+type suiteCard struct {
+	mockingbird.SuiteStats
+
+	SuccessRate string
+	State       mockingbird.TestResult
+	LatestPath  string
+}
+
+// windowCard contains the stats of the test suites in a rolling window on the dashboard page
+type windowCard struct {
+	Title  string
+	Suites []windowRow
+}
+
+// windowRow contains the stats and trends of one test suite in a rolling window
+type windowRow struct {
+	mockingbird.WindowSuiteStats
+
+	SuccessRate  string
+	SuccessTrend template.HTML
+	RunTimeTrend template.HTML
+}
+
 // testResultPage contains all required data for rendering test result page
 type testResultPage struct {
 	CSS             string
 	Title           string
 	PageTitle       string
 	ReloadPath      string
+	CancelPath      string
+	LogStreamPath   string
 	Path            *Path
 	Result          mockingbird.TestResult
 	ResultLog       string
 	ResultStartTime string
+	ResultETA       string                    // empty when it is unknown
+	Packages        []mockingbird.TestPackage // failing packages and test cases first
 }
 
 type testSuites struct {
@@ -97,7 +153,34 @@ type testSuites struct {
 	ReloadPath string
 	Path       *Path
 
-	TestSuites []mockingbird.TestSuite
+	TestSuites []testSuiteItem
+}
+
+// testSuiteItem contains a test suite and its schedule on the test suites page
+type testSuiteItem struct {
+	Name       mockingbird.TestSuite
+	Schedule   string
+	NextRun    string
+	NextRunISO string
+}
+
+type deliveriesPage struct {
+	CSS        string
+	Title      string
+	PageTitle  string
+	ReloadPath string
+	Path       *Path
+
+	Deliveries []deliveryItem
+}
+
+// deliveryItem contains a notification delivery on the notifications page
+type deliveryItem struct {
+	mockingbird.Delivery
+
+	TestPath string
+	Sent     string
+	SentISO  string
 }
 
 type testResultsPage struct {
@@ -111,6 +194,33 @@ type testResultsPage struct {
 
 	NextPage    string
 	TestResults []mockingbird.TestResult
+
+	// the filter form
+	Query      mockingbird.ListTestsQuery
+	TestSuites []mockingbird.TestSuite
+	States     []mockingbird.State
+	Statuses   []mockingbird.Status
+	PageSizes  []int
+}
+
+// emailReportPage contains the finished run of an email report
+type emailReportPage struct {
+	Title string
+	Color string // of the state, the styles of an email are inline
+
+	ID         mockingbird.ULID
+	TestSuite  mockingbird.TestSuite
+	Outcome    string
+	State      mockingbird.State
+	Previous   mockingbird.State
+	Duration   string
+	Started    string
+	Trigger    mockingbird.Trigger
+	Deployment *mockingbird.Deployment
+	URL        string
+
+	Log      string
+	LogLines int
 }
 
 type errorPage struct {
@@ -168,13 +278,28 @@ func (t *Template) Dashboard(d mockingbird.Dashboard) ([]byte, error) {
 
 		Dashboard: d,
 	}
+	for _, ss := range d.Stats.SuiteList() {
+		page.Suites = append(page.Suites, suiteCard{
+			SuiteStats:  ss,
+			SuccessRate: fmt.Sprintf("%.1f", ss.SuccessRate),
+			State:       mockingbird.TestResult{State: ss.LatestDoneState},
+			LatestPath:  path.ShowTest(string(ss.LatestDoneID)),
+		})
+	}
+	for _, ws := range d.Windows {
+		page.Windows = append(page.Windows, newWindowCard(ws))
+	}
 	return t.tmpl.Execute(mainLayout, dashboard, page)
 }
 
 // ListTests returns test results page
-func (t *Template) ListTest(ts *mockingbird.TestResults) ([]byte, error) {
+func (t *Template) ListTest(ts *mockingbird.TestResults, q mockingbird.ListTestsQuery, suites []mockingbird.TestSuite) ([]byte, error) {
 	const title = "Test history - Mockingbird"
 
+	filters := q.Values()
+	next := q.Values()
+	next.Set("page_token", ts.NextPageToken)
+
 	page := testResultsPage{
 		Title: title,
 		CSS:   cssFile,
@@ -185,8 +310,20 @@ func (t *Template) ListTest(ts *mockingbird.TestResults) ([]byte, error) {
 
 		MoreResult: len(ts.NextPageToken) != 0,
 
-		NextPage:    fmt.Sprintf("%s?page_token=%s", path.ListTests, ts.NextPageToken),
+		NextPage:    fmt.Sprintf("%s?%s", path.ListTests, next.Encode()),
 		TestResults: ts.TestResults,
+
+		Query:      q,
+		TestSuites: suites,
+		States: []mockingbird.State{
+			mockingbird.PENDING, mockingbird.SUCCESSFUL, mockingbird.FAILED,
+			mockingbird.INTERRUPTED, mockingbird.TIMED_OUT, mockingbird.CANCELLED,
+		},
+		Statuses:  []mockingbird.Status{mockingbird.QUEUED, mockingbird.RUNNING, mockingbird.DONE},
+		PageSizes: []int{mockingbird.DefaultPageSize, 25, 50, mockingbird.MaxPageSize},
+	}
+	if len(filters) > 0 {
+		page.ReloadPath = fmt.Sprintf("%s?%s", path.ListTests, filters.Encode())
 	}
 	return t.tmpl.Execute(mainLayout, testResultsFile, page)
 }
@@ -200,19 +337,43 @@ func (t *Template) ShowTest(ts mockingbird.TestResult) ([]byte, error) {
 		CSS:   cssFile,
 
 		ReloadPath:      path.ShowTest(string(ts.ID)),
+		CancelPath:      path.CancelTest(string(ts.ID)),
+		LogStreamPath:   path.LogStream(string(ts.ID)),
 		PageTitle:       fmt.Sprintf("%s", ts.TestSuite),
 		Path:            &path,
 		Result:          ts,
 		ResultLog:       string(ts.Log),
 		ResultStartTime: ts.StartTime.Format(time.RFC3339),
+		Packages:        failedFirst(ts.Packages),
+	}
+	if !ts.Progress.ETA.IsZero() {
+		page.ResultETA = ts.Progress.ETA.Format(time.RFC3339)
 	}
 	return t.tmpl.Execute(mainLayout, testResult, page)
 }
 
 // ShowTestSuites returns test  suite page
-func (t *Template) ShowTestSuites(ts []mockingbird.TestSuite) ([]byte, error) {
+func (t *Template) ShowTestSuites(ts []mockingbird.TestSuite, schedules []mockingbird.Schedule) ([]byte, error) {
 	const title = "Test suites - Mockingbird"
 
+	scheduled := map[mockingbird.TestSuite]mockingbird.Schedule{}
+	for _, s := range schedules {
+		scheduled[s.TestSuite] = s
+	}
+
+	items := make([]testSuiteItem, 0, len(ts))
+	for _, s := range ts {
+		item := testSuiteItem{Name: s}
+		if sched, ok := scheduled[s]; ok {
+			item.Schedule = sched.Expression
+			if !sched.Next.IsZero() {
+				item.NextRun = sched.Next.Format(time.RFC822)
+				item.NextRunISO = sched.Next.Format(time.RFC3339)
+			}
+		}
+		items = append(items, item)
+	}
+
 	page := testSuites{
 		Title: title,
 		CSS:   cssFile,
@@ -220,11 +381,65 @@ func (t *Template) ShowTestSuites(ts []mockingbird.TestSuite) ([]byte, error) {
 		ReloadPath: path.ListTestSuites,
 		PageTitle:  "Test Suites",
 		Path:       &path,
-		TestSuites: ts,
+		TestSuites: items,
 	}
 	return t.tmpl.Execute(mainLayout, testSuitesFile, page)
 }
 
+// ShowDeliveries returns the notification delivery log page
+func (t *Template) ShowDeliveries(deliveries []mockingbird.Delivery) ([]byte, error) {
+	const title = "Notifications - Mockingbird"
+
+	items := make([]deliveryItem, 0, len(deliveries))
+	for _, d := range deliveries {
+		items = append(items, deliveryItem{
+			Delivery: d,
+			TestPath: path.ShowTest(string(d.TestResultID)),
+			Sent:     d.Time.Format(time.RFC822),
+			SentISO:  d.Time.Format(time.RFC3339),
+		})
+	}
+
+	page := deliveriesPage{
+		Title: title,
+		CSS:   cssFile,
+
+		ReloadPath: path.ListDeliveries,
+		PageTitle:  "Notifications",
+		Path:       &path,
+		Deliveries: items,
+	}
+	return t.tmpl.Execute(mainLayout, deliveriesFile, page)
+}
+
+//
+// Email
+//
+
+// EmailReport returns the HTML part of the email report of a finished run
+func (t *Template) EmailReport(n mockingbird.Notification) ([]byte, error) {
+	tr := n.Result
+
+	page := emailReportPage{
+		Title: fmt.Sprintf("%s %s - Mockingbird", tr.TestSuite, n.Outcome()),
+		Color: stateColor(tr.State),
+
+		ID:         tr.ID,
+		TestSuite:  tr.TestSuite,
+		Outcome:    n.Outcome(),
+		State:      tr.State,
+		Previous:   n.Previous,
+		Duration:   tr.RunTime.String(),
+		Started:    tr.StartTime.UTC().Format(time.RFC1123),
+		Trigger:    tr.Trigger,
+		Deployment: tr.Deployment,
+		URL:        n.URL,
+		Log:        n.LogTail(emailLogLines),
+		LogLines:   emailLogLines,
+	}
+	return t.tmpl.Execute(emailLayout, emailReportFile, page)
+}
+
 //
 // Client Errors
 //
@@ -296,3 +511,72 @@ func internalError(err error) []byte {
 	s := fmt.Sprintf("Internal Error %s", err)
 	return []byte(s)
 }
+
+// failedFirst returns a copy of the packages with the failed packages and
+// test cases sorted first; otherwise they keep the order they were run in.
+func failedFirst(pkgs []mockingbird.TestPackage) []mockingbird.TestPackage {
+	sorted := make([]mockingbird.TestPackage, len(pkgs))
+	for i, p := range pkgs {
+		p.Tests = failedCasesFirst(p.Tests)
+		sorted[i] = p
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].IsFailed() && !sorted[j].IsFailed()
+	})
+	return sorted
+}
+
+func failedCasesFirst(cases []mockingbird.TestCase) []mockingbird.TestCase {
+	if len(cases) == 0 {
+		return nil
+	}
+
+	sorted := make([]mockingbird.TestCase, len(cases))
+	for i, c := range cases {
+		c.Subtests = failedCasesFirst(c.Subtests)
+		sorted[i] = c
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].IsFailed() && !sorted[j].IsFailed()
+	})
+	return sorted
+}
+
+// stateColor returns the color of the state in an email
+func stateColor(s mockingbird.State) string {
+	switch s {
+	case mockingbird.SUCCESSFUL:
+		return "#28a745"
+	case mockingbird.FAILED, mockingbird.TIMED_OUT:
+		return "#d73a49"
+	default:
+		return "#f0ad4e"
+	}
+}
+
+func newWindowCard(ws mockingbird.WindowStats) windowCard {
+	card := windowCard{Title: fmt.Sprintf("Last %s", ws.Window)}
+	if ws.Window.Runs > 0 {
+		card.Title = fmt.Sprintf("Last %d runs", ws.Window.Runs)
+	}
+
+	for _, s := range ws.Suites {
+		successes := make([]float64, len(s.Trend))
+		runTimes := make([]float64, len(s.Trend))
+		for i, p := range s.Trend {
+			successes[i], runTimes[i] = math.NaN(), math.NaN()
+			if p.RunCounter > 0 {
+				successes[i] = p.SuccessRate
+				runTimes[i] = float64(p.MeanRunTime)
+			}
+		}
+
+		card.Suites = append(card.Suites, windowRow{
+			WindowSuiteStats: s,
+			SuccessRate:      fmt.Sprintf("%.1f", s.SuccessRate),
+			SuccessTrend:     sparkline(successes, 100, "sparkline-success"),
+			RunTimeTrend:     sparkline(runTimes, float64(s.MaxRunTime), "sparkline-run-time"),
+		})
+	}
+	return card
+}
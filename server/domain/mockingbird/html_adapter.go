@@ -6,15 +6,19 @@ type HTMLAdapter interface {
 	// Business  Logic
 	//
 	Dashboard() (code int, body []byte, err error)
-	ListTests(pageToken string) (code int, body []byte, err error)
+	ListTests(q ListTestsQuery) (code int, body []byte, err error)
 	ShowTest(id ULID) (code int, body []byte, err error)
 	ShowTestSuites() (code int, body []byte, err error)
 
-	RunTest(testSuite TestSuite) (id ULID, code int, body []byte, err error)
+	RunTest(testSuite TestSuite, user string) (id ULID, code int, body []byte, err error)
+	CancelTest(id ULID) (code int, body []byte, err error)
+
+	ShowDeliveries() (code int, body []byte, err error)
 
 	//
 	// Error pages
 	//
 	ErrorNotFound() (body []byte)
 	InvalidURL() (body []byte)
+	InternalError() (body []byte)
 }
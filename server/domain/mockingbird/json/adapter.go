@@ -0,0 +1,228 @@
+package json
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/errs"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// Adapter implements the mockingbird.JSONAdapter interface
+//
+//
+// Note:
+//
+//         * It adapts between the http.Handler and the mockingbird.App
+//         * This adapter returns versioned JSON documents.
+//
+//
+type Adapter struct {
+	App mockingbird.App
+}
+
+// Verifies that *Adapter implements mockingbird.JSONAdapter interface
+var _ mockingbird.JSONAdapter = &Adapter{}
+
+//
+// Business  Logic
+//
+
+// Dashboard returns the stats
+func (a Adapter) Dashboard() (code int, body []byte, err error) {
+	d, err := a.App.Dashboard()
+	if err != nil {
+		return http.StatusInternalServerError, a.InternalError(), err
+	}
+
+	return a.encode(http.StatusOK, dashboardV1{Version: version, Stats: newStatsV1(d.Stats), Windows: newWindowsV1(d.Windows)})
+}
+
+// ListTests returns a page of the test results that match the query
+func (a Adapter) ListTests(q mockingbird.ListTestsQuery) (code int, body []byte, err error) {
+	tests, err := a.App.ListTests(q)
+	if err != nil {
+		return http.StatusInternalServerError, a.InternalError(), err
+	}
+
+	doc := testResultsV1{
+		Version:       version,
+		NextPageToken: tests.NextPageToken,
+		TestResults:   make([]testSummaryV1, 0, len(tests.TestResults)),
+	}
+	for _, tr := range tests.TestResults {
+		doc.TestResults = append(doc.TestResults, newTestSummaryV1(tr))
+	}
+	return a.encode(http.StatusOK, doc)
+}
+
+// ShowTest returns a test result
+func (a Adapter) ShowTest(id mockingbird.ULID) (code int, body []byte, err error) {
+	test, err := a.App.ShowTest(id)
+	if errs.IsNotFound(err) {
+		return http.StatusNotFound, a.ErrorNotFound(), err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, a.InternalError(), err
+	}
+
+	return a.encode(http.StatusOK, testResultV1{Version: version, TestResult: newTestDetailV1(test)})
+}
+
+// ShowTestSuites returns the test suites and the schedules of the scheduled test suites
+func (a Adapter) ShowTestSuites() (code int, body []byte, err error) {
+	doc := testSuitesV1{Version: version, TestSuites: []string{}, Schedules: []scheduleV1{}}
+	for _, ts := range a.App.ShowTestSuites() {
+		doc.TestSuites = append(doc.TestSuites, string(ts))
+	}
+	for _, s := range a.App.ShowSchedules() {
+		sched := scheduleV1{TestSuite: string(s.TestSuite), Expression: s.Expression}
+		if !s.Next.IsZero() {
+			next := s.Next
+			sched.NextRun = &next
+		}
+		doc.Schedules = append(doc.Schedules, sched)
+	}
+
+	return a.encode(http.StatusOK, doc)
+}
+
+// RunTest queues a test suite and returns its test result with 202 Accepted
+func (a Adapter) RunTest(ts mockingbird.TestSuite, user string) (id mockingbird.ULID, code int, body []byte, err error) {
+	if ts == "" {
+		const msg = "test_suite is required"
+		return "", http.StatusBadRequest, errorBody(http.StatusBadRequest, msg), errors.New(msg)
+	}
+
+	id, err = a.App.RunTest(ts, user)
+	if errs.IsNotFound(err) {
+		const msg = "The test suite does not exist."
+		return id, http.StatusNotFound, errorBody(http.StatusNotFound, msg), err
+	}
+	if err != nil {
+		return id, http.StatusInternalServerError, a.InternalError(), err
+	}
+
+	test, err := a.App.ShowTest(id)
+	if err != nil {
+		return id, http.StatusInternalServerError, a.InternalError(), err
+	}
+
+	code, body, err = a.encode(http.StatusAccepted, testResultV1{Version: version, TestResult: newTestDetailV1(test)})
+	return id, code, body, err
+}
+
+// RunDeploy queues the test suites of a deployment payload, e.g:
+// {"service": "api", "version": "1.4.0", "environment": "staging", "commit": "9f2c1e7"},
+// and returns their test results
+// with 202 Accepted, or with 200 OK when no deploy rule matches
+func (a Adapter) RunDeploy(payload []byte) (code int, body []byte, err error) {
+	d := mockingbird.Deployment{}
+	if err := json.Unmarshal(payload, &d); err != nil {
+		const msg = "The payload is not a JSON deployment."
+		return http.StatusBadRequest, errorBody(http.StatusBadRequest, msg), errors.Wrap(err, "json.Unmarshal(payload) failed")
+	}
+	if err := d.Validate(); err != nil {
+		return http.StatusBadRequest, errorBody(http.StatusBadRequest, err.Error()), err
+	}
+
+	ids, err := a.App.RunDeploy(d)
+	if err != nil {
+		return http.StatusInternalServerError, a.InternalError(), err
+	}
+
+	doc := deployV1{
+		Version:     version,
+		Deployment:  newDeploymentV1(d),
+		TestResults: make([]testSummaryV1, 0, len(ids)),
+	}
+	for _, id := range ids {
+		test, err := a.App.ShowTest(id)
+		if err != nil {
+			return http.StatusInternalServerError, a.InternalError(), err
+		}
+		doc.TestResults = append(doc.TestResults, newTestSummaryV1(test))
+	}
+
+	code = http.StatusAccepted
+	if len(ids) == 0 {
+		code = http.StatusOK
+	}
+	return a.encode(code, doc)
+}
+
+// ShowDeliveries returns the notification delivery log, newest first
+func (a Adapter) ShowDeliveries() (code int, body []byte, err error) {
+	doc := deliveriesV1{Version: version, Deliveries: []deliveryV1{}}
+	for _, d := range a.App.ShowDeliveries() {
+		doc.Deliveries = append(doc.Deliveries, newDeliveryV1(d))
+	}
+
+	return a.encode(http.StatusOK, doc)
+}
+
+// CancelTest cancels a queued or running test suite and returns its test result
+func (a Adapter) CancelTest(id mockingbird.ULID) (code int, body []byte, err error) {
+	err = a.App.CancelTest(id)
+	if errs.IsNotFound(err) {
+		return http.StatusNotFound, a.ErrorNotFound(), err
+	}
+	if err != nil {
+		return http.StatusInternalServerError, a.InternalError(), err
+	}
+
+	return a.ShowTest(id)
+}
+
+//
+// Notifications
+//
+
+// Notification returns the payload of a notification webhook
+func Notification(n mockingbird.Notification) ([]byte, error) {
+	b, err := json.Marshal(newNotificationV1(n))
+	return b, errors.Wrap(err, "json.Marshal(notification) failed")
+}
+
+//
+// Error bodies
+//
+
+// ErrorNotFound returns a not found error
+func (a Adapter) ErrorNotFound() (body []byte) {
+	return errorBody(http.StatusNotFound, "The resource does not exist.")
+}
+
+// InvalidURL returns an invalid URL error
+func (a Adapter) InvalidURL() (body []byte) {
+	return errorBody(http.StatusBadRequest, "The URL is invalid.")
+}
+
+// InternalError returns an internal error
+func (a Adapter) InternalError() (body []byte) {
+	return errorBody(http.StatusInternalServerError, "The server had an internal error.")
+}
+
+//
+// PRIVATE
+//
+
+func (a Adapter) encode(code int, doc interface{}) (int, []byte, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return http.StatusInternalServerError, a.InternalError(), errors.Wrap(err, "json.Marshal(doc) failed")
+	}
+
+	return code, b, nil
+}
+
+func errorBody(code int, msg string) []byte {
+	doc := errorV1{Error: errorBodyV1{Code: code, Status: http.StatusText(code), Message: msg}}
+
+	// errorV1 has only string and int fields, so it always marshals
+	b, _ := json.Marshal(doc)
+	return b
+}
@@ -0,0 +1,177 @@
+package json_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/errs"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/mock"
+
+	mbjson "github.com/unders/mockingbird/server/domain/mockingbird/json"
+)
+
+func newAdapter() mbjson.Adapter {
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	return mbjson.Adapter{App: &mock.AppMockingbird{Now: now}}
+}
+
+func TestAdapter_Dashboard_ReturnsVersionedStats(t *testing.T) {
+	code, b, err := newAdapter().Dashboard()
+	testdata.AssertNil(t, err)
+
+	if want, got := http.StatusOK, code; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	want := `{"version":1,"stats":{` +
+		`"test_suite":{"runs":17931,"successes":0,"timeouts":0,"success_rate":90,` +
+		`"latest_done":{"id":"01BX5ZZKBKACTAV9WEVGEMMVRY","test_suite":"registration:test","state":"successful","run_time_ms":66000}},` +
+		`"full_test_suite":{"runs":9840,"successes":0,"timeouts":0,"success_rate":99,` +
+		`"latest_done":{"id":"01BX5ZZKBKACTAV9WEVGEMMVS0","test_suite":"all:test","state":"failed","run_time_ms":150000}},` +
+		`"slowest":{"test_suite":"all:test","run_time_ms":255000},` +
+		`"suites":[{"test_suite":"all:test","runs":9840,"successes":0,"timeouts":0,"success_rate":99,` +
+		`"latest_done":{"id":"01BX5ZZKBKACTAV9WEVGEMMVS0","test_suite":"all:test","state":"failed","run_time_ms":150000},` +
+		`"p50_ms":120000,"p90_ms":150000,"p99_ms":200000,"max_ms":255000}]},` +
+		`"windows":[{"window":"2runs","suites":[{"test_suite":"all:test","runs":2,"successes":1,"success_rate":50,` +
+		`"p50_ms":125000,"p90_ms":150000,"max_ms":150000,"trend":[` +
+		`{"start_time":"2019-01-02T03:04:05Z","runs":1,"success_rate":100,"mean_ms":100000},` +
+		`{"start_time":"2019-01-02T04:04:05Z","runs":1,"success_rate":0,"mean_ms":150000}]}]}]}`
+	if got := string(b); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestAdapter_ShowTest_ReturnsTestResult(t *testing.T) {
+	code, b, err := newAdapter().ShowTest(mock.ULID3)
+	testdata.AssertNil(t, err)
+
+	if want, got := http.StatusOK, code; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	doc := struct {
+		Version    int
+		TestResult map[string]interface{} `json:"test_result"`
+	}{}
+	testdata.AssertNil(t, json.Unmarshal(b, &doc))
+
+	if want, got := 1, doc.Version; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	for key, want := range map[string]interface{}{
+		"id":          string(mock.ULID3),
+		"status":      "done",
+		"state":       "failed",
+		"test_suite":  "all:test",
+		"start_time":  "2019-01-02T03:04:05Z",
+		"run_time_ms": float64(100000),
+	} {
+		if got := doc.TestResult[key]; want != got {
+			t.Errorf("%s\nWant: %v\n Got: %v\n", key, want, got)
+		}
+	}
+}
+
+func TestAdapter_WhenNotFound_ReturnsErrorBody(t *testing.T) {
+	code, b, err := newAdapter().ShowTest(mock.NotFoundULID)
+	testdata.AssertTrue(t, errs.IsNotFound(err))
+
+	if want, got := http.StatusNotFound, code; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	want := `{"error":{"code":404,"status":"Not Found","message":"The resource does not exist."}}`
+	if got := string(b); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestAdapter_RunTest(t *testing.T) {
+	testCases := []struct {
+		testSuite string
+		wantCode  int
+		wantErr   bool
+	}{
+		{testSuite: "all:test", wantCode: http.StatusAccepted},
+		{testSuite: "", wantCode: http.StatusBadRequest, wantErr: true},
+		{testSuite: "unknown:test", wantCode: http.StatusNotFound, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testSuite, func(t *testing.T) {
+			_, code, b, err := newAdapter().RunTest(mockingbird.TestSuite(tc.testSuite), "alice")
+			if tc.wantErr != (err != nil) {
+				t.Errorf("\nWant: error=%t\n Got: %v\n", tc.wantErr, err)
+			}
+			if tc.wantCode != code {
+				t.Errorf("\nWant: %d\n Got: %d\n", tc.wantCode, code)
+			}
+			testdata.AssertTrue(t, json.Valid(b))
+		})
+	}
+}
+
+func TestAdapter_RunDeploy(t *testing.T) {
+	testCases := []struct {
+		payload  string
+		wantCode int
+		wantErr  bool
+	}{
+		{payload: `{"service":"api","version":"1.4.0"}`, wantCode: http.StatusAccepted},
+		{payload: `{"service":"web"}`, wantCode: http.StatusOK},
+		{payload: `{"version":"1.4.0"}`, wantCode: http.StatusBadRequest, wantErr: true},
+		{payload: `service=api`, wantCode: http.StatusBadRequest, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.payload, func(t *testing.T) {
+			code, b, err := newAdapter().RunDeploy([]byte(tc.payload))
+			if tc.wantErr != (err != nil) {
+				t.Errorf("\nWant: error=%t\n Got: %v\n", tc.wantErr, err)
+			}
+			if tc.wantCode != code {
+				t.Errorf("\nWant: %d\n Got: %d\n", tc.wantCode, code)
+			}
+			testdata.AssertTrue(t, json.Valid(b))
+		})
+	}
+}
+
+func TestAdapter_ShowTestSuites_ReturnsSchedules(t *testing.T) {
+	code, b, err := newAdapter().ShowTestSuites()
+	testdata.AssertNil(t, err)
+
+	if want, got := http.StatusOK, code; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	want := `{"version":1,"test_suites":["all:test","navigation:test","registration:test"],` +
+		`"schedules":[{"test_suite":"all:test","expression":"*/5 * * * *","next_run":"2019-01-02T03:09:05Z"}]}`
+	if got := string(b); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestAdapter_ShowDeliveries_ReturnsTheDeliveryLog(t *testing.T) {
+	code, b, err := newAdapter().ShowDeliveries()
+	testdata.AssertNil(t, err)
+
+	if want, got := http.StatusOK, code; want != got {
+		t.Errorf("\nWant: %d\n Got: %d\n", want, got)
+	}
+
+	want := `{"version":1,"deliveries":[` +
+		`{"test_result_id":"01BX5ZZKBKACTAV9WEVGEMMVS0","test_suite":"all:test","event":"failure",` +
+		`"notifier":"webhook https://hooks.example.com/mockingbird","attempts":1,"time":"2019-01-02T03:04:05Z"},` +
+		`{"test_result_id":"01BX5ZZKBKACTAV9WEVGEMMVS0","test_suite":"all:test","event":"failure",` +
+		`"notifier":"webhook https://down.example.com/mockingbird","attempts":4,` +
+		`"error":"POST https://down.example.com/mockingbird returned 503","time":"2019-01-02T03:04:05Z"}]}`
+	if got := string(b); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
@@ -0,0 +1,418 @@
+package json
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// version is the version of the JSON documents
+//
+// Note:
+//
+//        Fields may be added to a version; the version is bumped when a
+//        field is removed, renamed or changes meaning.
+//
+const version = 1
+
+//
+// Documents
+//
+
+type dashboardV1 struct {
+	Version int        `json:"version"`
+	Stats   statsV1    `json:"stats"`
+	Windows []windowV1 `json:"windows"`
+}
+
+type testResultsV1 struct {
+	Version       int             `json:"version"`
+	NextPageToken string          `json:"next_page_token"`
+	TestResults   []testSummaryV1 `json:"test_results"`
+}
+
+type testResultV1 struct {
+	Version    int          `json:"version"`
+	TestResult testDetailV1 `json:"test_result"`
+}
+
+type deployV1 struct {
+	Version     int             `json:"version"`
+	Deployment  deploymentV1    `json:"deployment"`
+	TestResults []testSummaryV1 `json:"test_results"`
+}
+
+type testSuitesV1 struct {
+	Version    int          `json:"version"`
+	TestSuites []string     `json:"test_suites"`
+	Schedules  []scheduleV1 `json:"schedules"`
+}
+
+type deliveriesV1 struct {
+	Version    int          `json:"version"`
+	Deliveries []deliveryV1 `json:"deliveries"`
+}
+
+// notificationV1 is the payload of a notification webhook
+type notificationV1 struct {
+	Version       int           `json:"version"`
+	Event         string        `json:"event"`
+	PreviousState string        `json:"previous_state,omitempty"`
+	TestResult    testSummaryV1 `json:"test_result"`
+	Deployment    *deploymentV1 `json:"deployment,omitempty"`
+	URL           string        `json:"url"`
+}
+
+type errorV1 struct {
+	Error errorBodyV1 `json:"error"`
+}
+
+//
+// Parts
+//
+
+type statsV1 struct {
+	TestSuite     suiteStatsV1 `json:"test_suite"`
+	FullTestSuite suiteStatsV1 `json:"full_test_suite"`
+	Slowest       *latestRunV1 `json:"slowest,omitempty"`
+	Suites        []suiteV1    `json:"suites"`
+}
+
+type suiteStatsV1 struct {
+	Runs        int64   `json:"runs"`
+	Successes   int64   `json:"successes"`
+	Timeouts    int64   `json:"timeouts"`
+	SuccessRate float64 `json:"success_rate"` // percent
+
+	LatestDone *latestRunV1 `json:"latest_done,omitempty"`
+}
+
+// suiteV1 is the stats of one test suite; the percentiles are of the latest runs
+type suiteV1 struct {
+	TestSuite   string  `json:"test_suite"`
+	Runs        int64   `json:"runs"`
+	Successes   int64   `json:"successes"`
+	Timeouts    int64   `json:"timeouts"`
+	SuccessRate float64 `json:"success_rate"` // percent
+
+	LatestDone *latestRunV1 `json:"latest_done,omitempty"`
+
+	P50MS int64 `json:"p50_ms"`
+	P90MS int64 `json:"p90_ms"`
+	P99MS int64 `json:"p99_ms"`
+	MaxMS int64 `json:"max_ms"`
+}
+
+// windowV1 is the stats of the test suites in a rolling window, e.g: 24h, 7d or 50runs
+type windowV1 struct {
+	Window string          `json:"window"`
+	Suites []windowSuiteV1 `json:"suites"`
+}
+
+type windowSuiteV1 struct {
+	TestSuite   string  `json:"test_suite"`
+	Runs        int64   `json:"runs"`
+	Successes   int64   `json:"successes"`
+	SuccessRate float64 `json:"success_rate"` // percent
+
+	P50MS int64 `json:"p50_ms"`
+	P90MS int64 `json:"p90_ms"`
+	MaxMS int64 `json:"max_ms"`
+
+	Trend []trendPointV1 `json:"trend"`
+}
+
+// trendPointV1 is a part of a window, oldest first; a part without runs has no success rate
+type trendPointV1 struct {
+	StartTime   time.Time `json:"start_time"`
+	Runs        int64     `json:"runs"`
+	SuccessRate *float64  `json:"success_rate,omitempty"`
+	MeanMS      int64     `json:"mean_ms"`
+}
+
+type latestRunV1 struct {
+	ID        string `json:"id,omitempty"`
+	TestSuite string `json:"test_suite"`
+	State     string `json:"state,omitempty"`
+	RunTimeMS int64  `json:"run_time_ms"`
+}
+
+type testSummaryV1 struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	State       string    `json:"state"`
+	TestSuite   string    `json:"test_suite"`
+	StartTime   time.Time `json:"start_time"`
+	RunTimeMS   int64     `json:"run_time_ms"`
+	Worker      int       `json:"worker,omitempty"`
+	Trigger     string    `json:"trigger,omitempty"`
+	TriggeredBy string    `json:"triggered_by,omitempty"`
+}
+
+type testDetailV1 struct {
+	testSummaryV1
+	QueuePosition int             `json:"queue_position"` // 0 when it is not queued
+	ETA           *time.Time      `json:"eta,omitempty"`
+	Log           string          `json:"log"`
+	LogURL        string          `json:"log_url,omitempty"`
+	Packages      []testPackageV1 `json:"packages,omitempty"`
+	Deployment    *deploymentV1   `json:"deployment,omitempty"`
+	Links         linksV1         `json:"links"`
+}
+
+type deploymentV1 struct {
+	Service     string `json:"service"`
+	Version     string `json:"version"`
+	Environment string `json:"environment"`
+	Commit      string `json:"commit"`
+}
+
+// linksV1 are the URLs to poll, stream or cancel a pending test result
+type linksV1 struct {
+	Self      string `json:"self"`
+	LogStream string `json:"log_stream,omitempty"`
+	Cancel    string `json:"cancel,omitempty"`
+}
+
+type testPackageV1 struct {
+	Name      string       `json:"name"`
+	Status    string       `json:"status"`
+	ElapsedMS int64        `json:"elapsed_ms"`
+	Output    string       `json:"output"`
+	Tests     []testCaseV1 `json:"tests,omitempty"`
+}
+
+type testCaseV1 struct {
+	Name      string       `json:"name"`
+	Status    string       `json:"status"`
+	ElapsedMS int64        `json:"elapsed_ms"`
+	Output    string       `json:"output"`
+	Subtests  []testCaseV1 `json:"subtests,omitempty"`
+}
+
+// scheduleV1 is a scheduled test suite; next_run is not set when the expression never matches again
+type scheduleV1 struct {
+	TestSuite  string     `json:"test_suite"`
+	Expression string     `json:"expression"`
+	NextRun    *time.Time `json:"next_run,omitempty"`
+}
+
+// deliveryV1 is an entry in the notification delivery log; error is not set when it was delivered
+type deliveryV1 struct {
+	TestResultID string    `json:"test_result_id"`
+	TestSuite    string    `json:"test_suite"`
+	Event        string    `json:"event"`
+	Notifier     string    `json:"notifier"`
+	Attempts     int       `json:"attempts"`
+	Error        string    `json:"error,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+type errorBodyV1 struct {
+	Code    int    `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+//
+// Conversions
+//
+
+func newStatsV1(s mockingbird.Stats) statsV1 {
+	stats := statsV1{
+		TestSuite: suiteStatsV1{
+			Runs:        int64(s.TestSuiteRunCounter),
+			Successes:   int64(s.TestSuiteSuccessCounter),
+			Timeouts:    int64(s.TestSuiteTimeoutCounter),
+			SuccessRate: s.TestSuiteSuccessRate,
+		},
+		FullTestSuite: suiteStatsV1{
+			Runs:        int64(s.FullTestSuiteRunCounter),
+			Successes:   int64(s.FullTestSuiteSuccessCounter),
+			Timeouts:    int64(s.FullTestSuiteTimeoutCounter),
+			SuccessRate: s.FullTestSuiteSuccessRate,
+		},
+		Suites: []suiteV1{},
+	}
+
+	if s.LatestDoneTestSuiteID != "" {
+		stats.TestSuite.LatestDone = &latestRunV1{
+			ID:        string(s.LatestDoneTestSuiteID),
+			TestSuite: string(s.LatestDoneTestSuiteName),
+			State:     string(s.LatestDoneTestSuiteState),
+			RunTimeMS: ms(s.LatestDoneTestSuiteRunTime),
+		}
+	}
+	if s.LatestDoneFullTestSuiteID != "" {
+		stats.FullTestSuite.LatestDone = &latestRunV1{
+			ID:        string(s.LatestDoneFullTestSuiteID),
+			TestSuite: string(s.LatestDoneFullTestSuiteName),
+			State:     string(s.LatestDoneFullTestSuiteState),
+			RunTimeMS: ms(s.LatestDoneFullTestSuiteRunTime),
+		}
+	}
+	if s.SlowestTestSuiteName != "" {
+		stats.Slowest = &latestRunV1{
+			TestSuite: string(s.SlowestTestSuiteName),
+			RunTimeMS: ms(s.SlowestTestSuiteRunTime),
+		}
+	}
+
+	for _, ss := range s.SuiteList() {
+		suite := suiteV1{
+			TestSuite:   string(ss.TestSuite),
+			Runs:        int64(ss.RunCounter),
+			Successes:   int64(ss.SuccessCounter),
+			Timeouts:    int64(ss.TimeoutCounter),
+			SuccessRate: ss.SuccessRate,
+			P50MS:       ms(ss.P50RunTime),
+			P90MS:       ms(ss.P90RunTime),
+			P99MS:       ms(ss.P99RunTime),
+			MaxMS:       ms(ss.MaxRunTime),
+		}
+		if ss.LatestDoneID != "" {
+			suite.LatestDone = &latestRunV1{
+				ID:        string(ss.LatestDoneID),
+				TestSuite: string(ss.TestSuite),
+				State:     string(ss.LatestDoneState),
+				RunTimeMS: ms(ss.LatestDoneRunTime),
+			}
+		}
+		stats.Suites = append(stats.Suites, suite)
+	}
+
+	return stats
+}
+
+func newWindowsV1(windows []mockingbird.WindowStats) []windowV1 {
+	ws := make([]windowV1, 0, len(windows))
+	for _, w := range windows {
+		window := windowV1{Window: w.Window.String(), Suites: make([]windowSuiteV1, 0, len(w.Suites))}
+		for _, s := range w.Suites {
+			suite := windowSuiteV1{
+				TestSuite:   string(s.TestSuite),
+				Runs:        int64(s.RunCounter),
+				Successes:   int64(s.SuccessCounter),
+				SuccessRate: s.SuccessRate,
+				P50MS:       ms(s.P50RunTime),
+				P90MS:       ms(s.P90RunTime),
+				MaxMS:       ms(s.MaxRunTime),
+				Trend:       make([]trendPointV1, 0, len(s.Trend)),
+			}
+			for _, p := range s.Trend {
+				point := trendPointV1{StartTime: p.StartTime, Runs: int64(p.RunCounter), MeanMS: ms(p.MeanRunTime)}
+				if p.RunCounter > 0 {
+					rate := p.SuccessRate
+					point.SuccessRate = &rate
+				}
+				suite.Trend = append(suite.Trend, point)
+			}
+			window.Suites = append(window.Suites, suite)
+		}
+		ws = append(ws, window)
+	}
+	return ws
+}
+
+func newTestSummaryV1(tr mockingbird.TestResult) testSummaryV1 {
+	return testSummaryV1{
+		ID:          string(tr.ID),
+		Status:      string(tr.Status),
+		State:       string(tr.State),
+		TestSuite:   string(tr.TestSuite),
+		StartTime:   tr.StartTime,
+		RunTimeMS:   ms(tr.RunTime),
+		Worker:      tr.Worker,
+		Trigger:     string(tr.Trigger),
+		TriggeredBy: tr.TriggeredBy,
+	}
+}
+
+func newTestDetailV1(tr mockingbird.TestResult) testDetailV1 {
+	self := fmt.Sprintf("/tests/%s", tr.ID)
+	d := testDetailV1{
+		testSummaryV1: newTestSummaryV1(tr),
+		QueuePosition: tr.Progress.QueuePosition,
+		Log:           tr.Log,
+		LogURL:        tr.LogURL,
+		Links:         linksV1{Self: self},
+	}
+
+	if !tr.Progress.ETA.IsZero() {
+		eta := tr.Progress.ETA
+		d.ETA = &eta
+	}
+	if tr.Deployment != nil {
+		deployment := newDeploymentV1(*tr.Deployment)
+		d.Deployment = &deployment
+	}
+	if tr.IsPending() {
+		d.Links.LogStream = self + "/log/stream"
+		d.Links.Cancel = self + "/cancel"
+	}
+
+	for _, p := range tr.Packages {
+		d.Packages = append(d.Packages, testPackageV1{
+			Name:      p.Name,
+			Status:    string(p.Status),
+			ElapsedMS: ms(p.Elapsed),
+			Output:    p.Output,
+			Tests:     newTestCasesV1(p.Tests),
+		})
+	}
+	return d
+}
+
+func newDeploymentV1(d mockingbird.Deployment) deploymentV1 {
+	return deploymentV1{Service: d.Service, Version: d.Version, Environment: d.Environment, Commit: d.Commit}
+}
+
+func newNotificationV1(n mockingbird.Notification) notificationV1 {
+	doc := notificationV1{
+		Version:       version,
+		Event:         n.Event(),
+		PreviousState: string(n.Previous),
+		TestResult:    newTestSummaryV1(n.Result),
+		URL:           n.URL,
+	}
+	if n.Result.Deployment != nil {
+		deployment := newDeploymentV1(*n.Result.Deployment)
+		doc.Deployment = &deployment
+	}
+	return doc
+}
+
+func newDeliveryV1(d mockingbird.Delivery) deliveryV1 {
+	return deliveryV1{
+		TestResultID: string(d.TestResultID),
+		TestSuite:    string(d.TestSuite),
+		Event:        d.Event,
+		Notifier:     d.Notifier,
+		Attempts:     d.Attempts,
+		Error:        d.Error,
+		Time:         d.Time,
+	}
+}
+
+func newTestCasesV1(cases []mockingbird.TestCase) []testCaseV1 {
+	if len(cases) == 0 {
+		return nil
+	}
+
+	tcs := make([]testCaseV1, 0, len(cases))
+	for _, c := range cases {
+		tcs = append(tcs, testCaseV1{
+			Name:      c.Name,
+			Status:    string(c.Status),
+			ElapsedMS: ms(c.Elapsed),
+			Output:    c.Output,
+			Subtests:  newTestCasesV1(c.Subtests),
+		})
+	}
+	return tcs
+}
+
+func ms(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
@@ -0,0 +1,25 @@
+package mockingbird
+
+// JSONAdapter interface for working with the JSON API
+type JSONAdapter interface {
+	//
+	// Business  Logic
+	//
+	Dashboard() (code int, body []byte, err error)
+	ListTests(q ListTestsQuery) (code int, body []byte, err error)
+	ShowTest(id ULID) (code int, body []byte, err error)
+	ShowTestSuites() (code int, body []byte, err error)
+
+	RunTest(testSuite TestSuite, user string) (id ULID, code int, body []byte, err error)
+	RunDeploy(payload []byte) (code int, body []byte, err error)
+	CancelTest(id ULID) (code int, body []byte, err error)
+
+	ShowDeliveries() (code int, body []byte, err error)
+
+	//
+	// Error bodies
+	//
+	ErrorNotFound() (body []byte)
+	InvalidURL() (body []byte)
+	InternalError() (body []byte)
+}
@@ -0,0 +1,184 @@
+package mockingbird
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The page sizes of a ListTestsQuery
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// dateFormat is the format of the dates in a query string, e.g: 2019-01-31
+const dateFormat = "2006-01-02"
+
+// ListTestsQuery filters and pages the test results; a zero value matches all test results
+//
+// Note:
+//
+//        The page token is the ID of the last test result on the previous
+//        page, so it must be sent with the same filters.
+//
+type ListTestsQuery struct {
+	PageToken string
+	PageSize  int // DefaultPageSize when below 1, never above MaxPageSize
+
+	TestSuite TestSuite
+	State     State
+	Status    Status
+
+	StartedAfter  time.Time // inclusive
+	StartedBefore time.Time // exclusive
+
+	Text string // a case-insensitive match in the log
+}
+
+// Size returns the page size; defaultSize is used when PageSize is not set
+func (q ListTestsQuery) Size(defaultSize int) int {
+	size := q.PageSize
+	if size < 1 {
+		size = defaultSize
+	}
+	if size < 1 {
+		size = DefaultPageSize
+	}
+	if size > MaxPageSize {
+		size = MaxPageSize
+	}
+	return size
+}
+
+// Match returns true if the test result matches all filters; the log is
+// only matched when Text is set
+func (q ListTestsQuery) Match(tr TestResult) bool {
+	switch {
+	case q.TestSuite != "" && q.TestSuite != tr.TestSuite:
+		return false
+	case q.State != "" && q.State != tr.State:
+		return false
+	case q.Status != "" && q.Status != tr.Status:
+		return false
+	case !q.StartedAfter.IsZero() && tr.StartTime.Before(q.StartedAfter):
+		return false
+	case !q.StartedBefore.IsZero() && !tr.StartTime.Before(q.StartedBefore):
+		return false
+	case q.Text != "" && !strings.Contains(strings.ToLower(tr.Log), strings.ToLower(q.Text)):
+		return false
+	}
+	return true
+}
+
+// IsFiltered returns true if any filter is set
+func (q ListTestsQuery) IsFiltered() bool {
+	q.PageToken = ""
+	q.PageSize = 0
+	return q != ListTestsQuery{}
+}
+
+// Values returns the filters and the page size as a query string, without the page token
+func (q ListTestsQuery) Values() url.Values {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+
+	set("suite", string(q.TestSuite))
+	set("state", string(q.State))
+	set("status", string(q.Status))
+	set("from", q.From())
+	set("to", q.To())
+	set("q", q.Text)
+	if q.PageSize > 0 {
+		set("page_size", strconv.Itoa(q.PageSize))
+	}
+	return v
+}
+
+// From returns StartedAfter as a date, or as a RFC 3339 time when it is not midnight
+func (q ListTestsQuery) From() string {
+	return formatTime(q.StartedAfter)
+}
+
+// To returns StartedBefore as the last date it includes, or as a RFC 3339 time
+// when it is not midnight
+func (q ListTestsQuery) To() string {
+	if isMidnight(q.StartedBefore) {
+		return formatTime(q.StartedBefore.AddDate(0, 0, -1))
+	}
+	return formatTime(q.StartedBefore)
+}
+
+// ParseListTestsQuery returns the query from a query string
+//
+// Usage:
+//
+//        /tests/?suite=google:test&state=failed&from=2019-01-28&to=2019-02-03&q=timeout&page_size=50
+//
+// Note:
+//
+//        from and to are RFC 3339 times or dates; a to date includes the whole day.
+//
+func ParseListTestsQuery(v url.Values) (ListTestsQuery, error) {
+	q := ListTestsQuery{
+		PageToken: v.Get("page_token"),
+		TestSuite: TestSuite(v.Get("suite")),
+		State:     State(v.Get("state")),
+		Status:    Status(v.Get("status")),
+		Text:      v.Get("q"),
+	}
+
+	var err error
+	if s := v.Get("page_size"); s != "" {
+		if q.PageSize, err = strconv.Atoi(s); err != nil {
+			return q, errors.Wrapf(err, "invalid page_size=%s", s)
+		}
+	}
+	if s := v.Get("from"); s != "" {
+		if q.StartedAfter, _, err = parseTime(s); err != nil {
+			return q, errors.Wrapf(err, "invalid from=%s", s)
+		}
+	}
+	if s := v.Get("to"); s != "" {
+		var isDate bool
+		if q.StartedBefore, isDate, err = parseTime(s); err != nil {
+			return q, errors.Wrapf(err, "invalid to=%s", s)
+		}
+		if isDate {
+			q.StartedBefore = q.StartedBefore.AddDate(0, 0, 1)
+		}
+	}
+
+	return q, nil
+}
+
+func formatTime(t time.Time) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case isMidnight(t):
+		return t.UTC().Format(dateFormat)
+	default:
+		return t.Format(time.RFC3339)
+	}
+}
+
+func isMidnight(t time.Time) bool {
+	return !t.IsZero() && t.Equal(t.UTC().Truncate(24*time.Hour))
+}
+
+func parseTime(s string) (t time.Time, isDate bool, err error) {
+	if t, err := time.Parse(dateFormat, s); err == nil {
+		return t, true, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, s)
+	return t.UTC(), false, errors.WithStack(err)
+}
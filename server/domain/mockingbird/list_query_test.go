@@ -0,0 +1,66 @@
+package mockingbird_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestParseListTestsQuery_ReturnsFiltersThatRoundTrip(t *testing.T) {
+	v, err := url.ParseQuery("suite=google:test&state=failed&from=2019-01-28&to=2019-02-03&q=timeout&page_size=50&page_token=id-1")
+	testdata.AssertNil(t, err)
+
+	q, err := mockingbird.ParseListTestsQuery(v)
+	testdata.AssertNil(t, err)
+
+	want := mockingbird.ListTestsQuery{
+		PageToken:     "id-1",
+		PageSize:      50,
+		TestSuite:     "google:test",
+		State:         mockingbird.FAILED,
+		StartedAfter:  time.Date(2019, 1, 28, 0, 0, 0, 0, time.UTC),
+		StartedBefore: time.Date(2019, 2, 4, 0, 0, 0, 0, time.UTC),
+		Text:          "timeout",
+	}
+	if want != q {
+		t.Errorf("\nWant: %+v\n Got: %+v\n", want, q)
+	}
+
+	v.Del("page_token")
+	if want, got := v.Encode(), q.Values().Encode(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestParseListTestsQuery_WhenInvalid_ReturnsError(t *testing.T) {
+	for _, s := range []string{"page_size=ten", "from=yesterday", "to=2019-13-01"} {
+		v, err := url.ParseQuery(s)
+		testdata.AssertNil(t, err)
+
+		_, err = mockingbird.ParseListTestsQuery(v)
+		if err == nil {
+			t.Errorf("%s\nWant: error\n Got: nil\n", s)
+		}
+	}
+}
+
+func TestListTestsQuery_Size(t *testing.T) {
+	testCases := []struct {
+		pageSize, defaultSize, want int
+	}{
+		{pageSize: 0, defaultSize: 0, want: mockingbird.DefaultPageSize},
+		{pageSize: 0, defaultSize: 2, want: 2},
+		{pageSize: 25, defaultSize: 2, want: 25},
+		{pageSize: 1000, defaultSize: 2, want: mockingbird.MaxPageSize},
+	}
+
+	for _, tc := range testCases {
+		got := mockingbird.ListTestsQuery{PageSize: tc.pageSize}.Size(tc.defaultSize)
+		if tc.want != got {
+			t.Errorf("\nWant: %d\n Got: %d\n", tc.want, got)
+		}
+	}
+}
@@ -1,15 +1,66 @@
 package mockingbird
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Log interface
+//
+// Usage:
+//
+//        l.Error("test result failed", mockingbird.KV("test_result_id", id), mockingbird.KV("error", err))
+//
 type Log interface {
-	Error(msg string)
-	Notice(msg string)
-	Info(msg string)
-	Debug(msg string)
+	Error(msg string, fields ...Field)
+	Notice(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Debug(msg string, fields ...Field)
+}
+
+// Field is a key/value pair of a log message
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// KV returns the field of key and value
+func KV(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Level defines the severity of a log message
+type Level string
+
+// Specifies the levels, from the least to the most severe
+const (
+	DEBUG  Level = "debug"
+	INFO         = "info"
+	NOTICE       = "notice"
+	ERROR        = "error"
+)
+
+// ParseLevel returns the level of s, e.g: info
+func ParseLevel(s string) (Level, error) {
+	l := Level(strings.ToLower(strings.TrimSpace(s)))
+	switch l {
+	case DEBUG, INFO, NOTICE, ERROR:
+		return l, nil
+	}
+
+	const format = "log level %q must be one of debug, info, notice, error"
+	return "", fmt.Errorf(format, s)
+}
+
+// Enabled returns true if messages of level m are logged at level l;
+// the empty level logs all messages
+func (l Level) Enabled(m Level) bool {
+	return m.rank() >= l.rank()
 }
 
 //
@@ -19,31 +70,154 @@ type Log interface {
 // Verifies that Logger implements Log interface
 var _ Log = &Logger{}
 
-// Logger implements Log interface
+// Logger implements Log interface; it writes each message as a line of
+// text with its fields as key=value pairs, e.g: for local development
+//
+// Usage:
+//
+//        ERROR: test result failed test_result_id=01CZ0000000000000000000001 error="exit status 1"
+//
 type Logger struct {
-	Log *log.Logger
+	Log   *log.Logger
+	Level Level
 }
 
 // Error logs msg with ERROR prefix
-func (l *Logger) Error(msg string) {
-	const format = "ERROR: %s\n"
-	l.Log.Printf(format, msg)
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.print(ERROR, "ERROR", msg, fields)
 }
 
 // Notice logs msg with Notice prefix
-func (l *Logger) Notice(msg string) {
-	const format = "NOTICE: %s\n"
-	l.Log.Printf(format, msg)
+func (l *Logger) Notice(msg string, fields ...Field) {
+	l.print(NOTICE, "NOTICE", msg, fields)
 }
 
 // Info logs msg with INFO prefix
-func (l *Logger) Info(msg string) {
-	const format = "INFO: %s\n"
-	l.Log.Printf(format, msg)
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.print(INFO, "INFO", msg, fields)
 }
 
 // Debug logs msg with Debug prefix
-func (l *Logger) Debug(msg string) {
-	const format = "Debug: %s\n"
-	l.Log.Printf(format, msg)
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.print(DEBUG, "Debug", msg, fields)
+}
+
+//
+// JSONLogger
+//
+
+// Verifies that JSONLogger implements Log interface
+var _ Log = &JSONLogger{}
+
+// JSONLogger implements Log interface; it writes each message as a line of
+// JSON that Cloud Logging and CloudWatch can query by field
+//
+// Usage:
+//
+//        {"severity":"ERROR","timestamp":"2019-01-02T03:04:05.123Z",
+//         "message":"GET /tests    <-    500 Internal Server Error",
+//         "request_id":"8a7f...","trace_id":"4bf9...","method":"GET","path":"/tests",
+//         "status":500,"size":1024,"latency":"1.5ms","error":"store failed"}
+//
+type JSONLogger struct {
+	Log   *log.Logger
+	Level Level
+}
+
+// Error logs msg with severity ERROR
+func (l *JSONLogger) Error(msg string, fields ...Field) {
+	l.print(ERROR, msg, fields)
+}
+
+// Notice logs msg with severity NOTICE
+func (l *JSONLogger) Notice(msg string, fields ...Field) {
+	l.print(NOTICE, msg, fields)
+}
+
+// Info logs msg with severity INFO
+func (l *JSONLogger) Info(msg string, fields ...Field) {
+	l.print(INFO, msg, fields)
+}
+
+// Debug logs msg with severity DEBUG
+func (l *JSONLogger) Debug(msg string, fields ...Field) {
+	l.print(DEBUG, msg, fields)
+}
+
+//
+// PRIVATE
+//
+
+func (l Level) rank() int {
+	switch l {
+	case INFO:
+		return 1
+	case NOTICE:
+		return 2
+	case ERROR:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func (l *Logger) print(level Level, prefix, msg string, fields []Field) {
+	if !l.Level.Enabled(level) {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(prefix)
+	buf.WriteString(": ")
+	buf.WriteString(msg)
+	for _, f := range fields {
+		s := fmt.Sprint(fieldValue(f.Value))
+		if s == "" || strings.ContainsAny(s, " =\"\n") {
+			s = strconv.Quote(s)
+		}
+		fmt.Fprintf(&buf, " %s=%s", f.Key, s)
+	}
+	l.Log.Println(buf.String())
+}
+
+func (l *JSONLogger) print(level Level, msg string, fields []Field) {
+	if !l.Level.Enabled(level) {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"severity":`)
+	writeJSON(&buf, strings.ToUpper(string(level)))
+	buf.WriteString(`,"timestamp":`)
+	writeJSON(&buf, time.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"message":`)
+	writeJSON(&buf, msg)
+	for _, f := range fields {
+		buf.WriteByte(',')
+		writeJSON(&buf, f.Key)
+		buf.WriteByte(':')
+		writeJSON(&buf, fieldValue(f.Value))
+	}
+	buf.WriteByte('}')
+	l.Log.Println(buf.String())
+}
+
+// fieldValue returns errors as their message and durations as text, e.g: 1.5ms
+func fieldValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
 }
@@ -0,0 +1,16 @@
+package mockingbird
+
+import "context"
+
+// LogStream defines the interface for following the log of a test suite
+//
+// Note:
+//
+//        FollowLog calls fn with the log from byte offset and then with
+//        each new chunk of complete lines, until the test suite is done
+//        or ctx is done. fn gets the offset after the chunk, so a client
+//        can resume from it.
+//
+type LogStream interface {
+	FollowLog(ctx context.Context, id ULID, offset int, fn func(chunk string, offset int) error) error
+}
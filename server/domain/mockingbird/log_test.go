@@ -0,0 +1,114 @@
+package mockingbird_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want mockingbird.Level
+	}{
+		{s: "debug", want: mockingbird.DEBUG},
+		{s: "INFO", want: mockingbird.INFO},
+		{s: " notice ", want: mockingbird.NOTICE},
+		{s: "error", want: mockingbird.ERROR},
+	}
+
+	for _, tc := range testCases {
+		got, err := mockingbird.ParseLevel(tc.s)
+		if err != nil {
+			t.Fatalf("%s\nWant: nil\n Got: %s\n", tc.s, err)
+		}
+		if tc.want != got {
+			t.Errorf("%s\nWant: %s\n Got: %s\n", tc.s, tc.want, got)
+		}
+	}
+
+	want := `log level "warning" must be one of debug, info, notice, error`
+	if _, err := mockingbird.ParseLevel("warning"); err == nil || want != err.Error() {
+		t.Errorf("\nWant: %s\n Got: %v\n", want, err)
+	}
+}
+
+func TestLogger_WritesFieldsAsKeyValuePairs(t *testing.T) {
+	var buf bytes.Buffer
+	l := mockingbird.Logger{Log: log.New(&buf, "", 0), Level: mockingbird.NOTICE}
+
+	l.Debug("not logged")
+	l.Info("not logged")
+	l.Notice("notify failed", mockingbird.KV("attempt", 2))
+	l.Error("test result failed", mockingbird.KV("test_result_id", "01CZ0000000000000000000001"),
+		mockingbird.KV("error", errors.New("exit status 1")), mockingbird.KV("run_time", 1500*time.Millisecond))
+
+	want := "NOTICE: notify failed attempt=2\n" +
+		`ERROR: test result failed test_result_id=01CZ0000000000000000000001 error="exit status 1" run_time=1.5s` + "\n"
+	if got := buf.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestJSONLogger_WritesALinePerMessage(t *testing.T) {
+	var buf bytes.Buffer
+	l := mockingbird.JSONLogger{Log: log.New(&buf, "", 0), Level: mockingbird.INFO}
+
+	l.Debug("not logged")
+	l.Info("GET /tests    <-    200 OK", mockingbird.KV("request_id", "8a7f"), mockingbird.KV("method", "GET"),
+		mockingbird.KV("path", "/tests"), mockingbird.KV("status", 200), mockingbird.KV("latency", 1500*time.Microsecond))
+	l.Error("response failed", mockingbird.KV("error", errors.New("broken pipe")))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("\nWant: 2 lines\n Got: %s\n", buf.String())
+	}
+
+	testCases := []struct {
+		line []byte
+		want map[string]interface{}
+	}{
+		{
+			line: lines[0],
+			want: map[string]interface{}{
+				"severity": "INFO", "message": "GET /tests    <-    200 OK", "request_id": "8a7f",
+				"method": "GET", "path": "/tests", "status": float64(200), "latency": "1.5ms",
+			},
+		},
+		{
+			line: lines[1],
+			want: map[string]interface{}{"severity": "ERROR", "message": "response failed", "error": "broken pipe"},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := map[string]interface{}{}
+		if err := json.Unmarshal(tc.line, &got); err != nil {
+			t.Fatalf("%s\nWant: nil\n Got: %s\n", tc.line, err)
+		}
+
+		ts, _ := got["timestamp"].(string)
+		if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+			t.Errorf("\nWant: RFC 3339 timestamp\n Got: %s\n", tc.line)
+		}
+		delete(got, "timestamp")
+
+		if len(tc.want) != len(got) {
+			t.Errorf("\nWant: %v\n Got: %v\n", tc.want, got)
+		}
+		for key, want := range tc.want {
+			if got[key] != want {
+				t.Errorf("%s\nWant: %v\n Got: %v\n", key, want, got[key])
+			}
+		}
+	}
+
+	if want := `{"severity":"INFO",`; !bytes.HasPrefix(lines[0], []byte(want)) {
+		t.Errorf("\nWant: %s...\n Got: %s\n", want, lines[0])
+	}
+}
@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/errs"
+)
+
+type testResult struct {
+	mockingbird.TestResult
+	index int
+}
+
+// Store implements the mockingbird.Store interface
+//
+// Note:
+//
+//        Everything is kept in process memory, so the test history
+//        is lost when the server restarts.
+//
+type Store struct {
+	*sync.RWMutex
+	testResults map[mockingbird.ULID]testResult
+	stats       mockingbird.Stats
+
+	idIndex []mockingbird.ULID
+	queue   []mockingbird.ULID
+}
+
+// Verifies that *Store implements mockingbird.Store interface
+var _ mockingbird.Store = &Store{}
+
+// NewStore returns an empty in-memory store
+func NewStore() *Store {
+	return &Store{
+		RWMutex:     &sync.RWMutex{},
+		testResults: make(map[mockingbird.ULID]testResult),
+	}
+}
+
+//
+// Test results
+//
+
+// SaveTestResult inserts or updates the test result
+func (s *Store) SaveTestResult(tr mockingbird.TestResult) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if ts, ok := s.testResults[tr.ID]; ok {
+		s.testResults[tr.ID] = testResult{TestResult: tr, index: ts.index}
+		return nil
+	}
+
+	s.idIndex = append(s.idIndex, tr.ID)
+	index := len(s.idIndex) - 1
+	s.testResults[tr.ID] = testResult{TestResult: tr, index: index}
+	return nil
+}
+
+// GetTestResult returns the test result for the given id
+func (s *Store) GetTestResult(id mockingbird.ULID) (mockingbird.TestResult, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if ts, ok := s.testResults[id]; ok {
+		return ts.TestResult, nil
+	}
+
+	msg := fmt.Sprintf("test result %s not found", id)
+	return mockingbird.TestResult{}, errs.NotFound(msg)
+}
+
+// ListTestResults returns a page of the test results that match the query, newest first
+func (s *Store) ListTestResults(q mockingbird.ListTestsQuery) (*mockingbird.TestResults, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	startIndex := len(s.idIndex) - 1
+	if q.PageToken != "" {
+		if ts, ok := s.testResults[mockingbird.ULID(q.PageToken)]; ok {
+			startIndex = ts.index - 1
+		}
+	}
+
+	size := q.Size(mockingbird.DefaultPageSize)
+	trs := []mockingbird.TestResult{}
+	nextPageToken := ""
+
+	for i := startIndex; i >= 0; i-- {
+		id := s.idIndex[i]
+		tr, ok := s.testResults[id]
+		if !ok {
+			return nil, errors.Errorf("s.testResults[%s] failed", string(id))
+		}
+		if !q.Match(tr.TestResult) {
+			continue
+		}
+		if len(trs) == size {
+			nextPageToken = string(trs[size-1].ID)
+			break
+		}
+		trs = append(trs, tr.TestResult)
+	}
+
+	return &mockingbird.TestResults{NextPageToken: nextPageToken, TestResults: trs}, nil
+}
+
+//
+// Stats
+//
+
+// SaveStats replaces the stats
+func (s *Store) SaveStats(stats mockingbird.Stats) error {
+	s.Lock()
+	s.stats = stats
+	s.Unlock()
+	return nil
+}
+
+// GetStats returns the stats
+func (s *Store) GetStats() (mockingbird.Stats, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.stats, nil
+}
+
+//
+// Queue
+//
+
+// Enqueue appends id to the queue
+func (s *Store) Enqueue(id mockingbird.ULID) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, q := range s.queue {
+		if q == id {
+			return nil
+		}
+	}
+	s.queue = append(s.queue, id)
+	return nil
+}
+
+// Dequeue removes id from the queue
+func (s *Store) Dequeue(id mockingbird.ULID) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for i, q := range s.queue {
+		if q == id {
+			s.queue = append(s.queue[:i:i], s.queue[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Queue returns the queued IDs, oldest first
+func (s *Store) Queue() ([]mockingbird.ULID, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	q := make([]mockingbird.ULID, len(s.queue))
+	copy(q, s.queue)
+	return q, nil
+}
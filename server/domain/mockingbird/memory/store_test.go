@@ -0,0 +1,106 @@
+package memory_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/memory"
+	"github.com/unders/mockingbird/server/pkg/errs"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestStore_GetTestResult(t *testing.T) {
+	s := memory.NewStore()
+
+	tr := mockingbird.TestResult{ID: "id-1", Status: mockingbird.QUEUED}
+	testdata.AssertNil(t, s.SaveTestResult(tr))
+
+	tr.Status = mockingbird.DONE
+	testdata.AssertNil(t, s.SaveTestResult(tr))
+
+	got, err := s.GetTestResult("id-1")
+	testdata.AssertNil(t, err)
+	if got.Status != mockingbird.DONE {
+		t.Errorf("\nWant: %s\n Got: %s\n", mockingbird.DONE, got.Status)
+	}
+
+	_, err = s.GetTestResult("id-2")
+	testdata.AssertTrue(t, errs.IsNotFound(err))
+}
+
+func TestStore_ListTestResults_ReturnsNewestFirst(t *testing.T) {
+	s := memory.NewStore()
+
+	for i := 0; i < 15; i++ {
+		id := mockingbird.ULID(fmt.Sprintf("id-%d", i))
+		testdata.AssertNil(t, s.SaveTestResult(mockingbird.TestResult{ID: id}))
+	}
+
+	page, err := s.ListTestResults(mockingbird.ListTestsQuery{})
+	testdata.AssertNil(t, err)
+
+	if want, got := 10, len(page.TestResults); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := mockingbird.ULID("id-14"), page.TestResults[0].ID; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	if want, got := "id-5", page.NextPageToken; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	page, err = s.ListTestResults(mockingbird.ListTestsQuery{PageToken: page.NextPageToken})
+	testdata.AssertNil(t, err)
+
+	if want, got := 5, len(page.TestResults); want != got {
+		t.Fatalf("\nWant: %d\n Got: %d\n", want, got)
+	}
+	if want, got := "", page.NextPageToken; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestStore_ListTestResults_FiltersAndPages(t *testing.T) {
+	s := memory.NewStore()
+
+	for i := 0; i < 9; i++ {
+		tr := mockingbird.TestResult{
+			ID:        mockingbird.ULID(fmt.Sprintf("id-%d", i)),
+			TestSuite: "google:test",
+			State:     mockingbird.SUCCESSFUL,
+			Log:       "PASS",
+		}
+		if i%3 == 0 {
+			tr.State = mockingbird.FAILED
+			tr.Log = "--- FAIL: TestSearch"
+		}
+		if i == 8 {
+			tr.TestSuite = mockingbird.FullTestSuite
+		}
+		testdata.AssertNil(t, s.SaveTestResult(tr))
+	}
+
+	q := mockingbird.ListTestsQuery{TestSuite: "google:test", State: mockingbird.FAILED, Text: "fail: testsearch", PageSize: 2}
+	page, err := s.ListTestResults(q)
+	testdata.AssertNil(t, err)
+
+	var got []string
+	for _, tr := range page.TestResults {
+		got = append(got, string(tr.ID))
+	}
+	if want := fmt.Sprint([]string{"id-6", "id-3"}); want != fmt.Sprint(got) {
+		t.Errorf("\nWant: %s\n Got: %v\n", want, got)
+	}
+	if want, got := "id-3", page.NextPageToken; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	q.PageToken = page.NextPageToken
+	page, err = s.ListTestResults(q)
+	testdata.AssertNil(t, err)
+
+	if len(page.TestResults) != 1 || page.TestResults[0].ID != "id-0" || page.NextPageToken != "" {
+		t.Errorf("\nWant: [id-0] and no next page\n Got: %+v\n", page)
+	}
+}
@@ -74,18 +74,65 @@ func (m *AppMockingbird) Dashboard() (mockingbird.Dashboard, error) {
 
 			SlowestTestSuiteRunTime: 255 * time.Second,
 			SlowestTestSuiteName:    TestAll,
+
+			Suites: map[mockingbird.TestSuite]mockingbird.SuiteStats{
+				TestAll: {
+					TestSuite:         TestAll,
+					RunCounter:        9840,
+					SuccessRate:       99,
+					LatestDoneID:      ULID3,
+					LatestDoneState:   mockingbird.FAILED,
+					LatestDoneRunTime: 150 * time.Second,
+					P50RunTime:        120 * time.Second,
+					P90RunTime:        150 * time.Second,
+					P99RunTime:        200 * time.Second,
+					MaxRunTime:        255 * time.Second,
+					RunTimes:          []time.Duration{120 * time.Second, 150 * time.Second},
+				},
+			},
+		},
+		Windows: []mockingbird.WindowStats{
+			{
+				Window: mockingbird.Window{Runs: 2},
+				Suites: []mockingbird.WindowSuiteStats{
+					{
+						TestSuite:      TestAll,
+						RunCounter:     2,
+						SuccessCounter: 1,
+						SuccessRate:    50,
+						P50RunTime:     125 * time.Second,
+						P90RunTime:     150 * time.Second,
+						MaxRunTime:     150 * time.Second,
+						Trend: []mockingbird.TrendPoint{
+							{StartTime: m.Now, RunCounter: 1, SuccessRate: 100, MeanRunTime: 100 * time.Second},
+							{StartTime: m.Now.Add(time.Hour), RunCounter: 1, SuccessRate: 0, MeanRunTime: 150 * time.Second},
+						},
+					},
+				},
+			},
 		},
 	}
 	return d, nil
 }
 
 // ListTests returns a list of test results
-func (m *AppMockingbird) ListTests(pageToken string) (*mockingbird.TestResults, error) {
-	if pageToken == "error" {
+func (m *AppMockingbird) ListTests(q mockingbird.ListTestsQuery) (*mockingbird.TestResults, error) {
+	if q.PageToken == "error" {
 		return nil, errors.New("server error")
 	}
 
-	return m.listTests(), nil
+	trs := m.listTests()
+	if !q.IsFiltered() {
+		return trs, nil
+	}
+
+	filtered := &mockingbird.TestResults{}
+	for _, tr := range trs.TestResults {
+		if q.Match(tr) {
+			filtered.TestResults = append(filtered.TestResults, tr)
+		}
+	}
+	return filtered, nil
 }
 
 // ShowTest returns the test result for the given id
@@ -105,12 +152,19 @@ func (m *AppMockingbird) ShowTestSuites() []mockingbird.TestSuite {
 	return testSuites
 }
 
+// ShowSchedules returns the scheduled test suites
+func (m *AppMockingbird) ShowSchedules() []mockingbird.Schedule {
+	return []mockingbird.Schedule{
+		{TestSuite: TestAll, Expression: "*/5 * * * *", Next: m.Now.Add(5 * time.Minute)},
+	}
+}
+
 //
 // Executes a test suite
 //
 
 // RunTest executes the given test suite
-func (m *AppMockingbird) RunTest(s mockingbird.TestSuite) (mockingbird.ULID, error) {
+func (m *AppMockingbird) RunTest(s mockingbird.TestSuite, user string) (mockingbird.ULID, error) {
 	switch s {
 	case TestRegistration:
 		return ULID1, nil
@@ -124,6 +178,52 @@ func (m *AppMockingbird) RunTest(s mockingbird.TestSuite) (mockingbird.ULID, err
 	}
 }
 
+// RunDeploy executes the test suites of a deployment; only the service api has test suites
+func (m *AppMockingbird) RunDeploy(d mockingbird.Deployment) ([]mockingbird.ULID, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	if d.Service != "api" {
+		return nil, nil
+	}
+	return []mockingbird.ULID{ULID3}, nil
+}
+
+// CancelTest cancels the test suite with the given id
+func (m *AppMockingbird) CancelTest(id mockingbird.ULID) error {
+	if _, err := m.ShowTest(id); err != nil {
+		return err
+	}
+	return nil
+}
+
+//
+// Fetches the notification delivery log
+//
+
+// ShowDeliveries returns a delivered and a failed notification
+func (m *AppMockingbird) ShowDeliveries() []mockingbird.Delivery {
+	return []mockingbird.Delivery{
+		{
+			TestResultID: ULID3,
+			TestSuite:    TestAll,
+			Event:        "failure",
+			Notifier:     "webhook https://hooks.example.com/mockingbird",
+			Attempts:     1,
+			Time:         m.Now,
+		},
+		{
+			TestResultID: ULID3,
+			TestSuite:    TestAll,
+			Event:        "failure",
+			Notifier:     "webhook https://down.example.com/mockingbird",
+			Attempts:     4,
+			Error:        "POST https://down.example.com/mockingbird returned 503",
+			Time:         m.Now,
+		},
+	}
+}
+
 //
 // PRIVATE
 //
@@ -33,7 +33,7 @@ func (a HTMLAdapter) Dashboard() (code int, body []byte, err error) {
 }
 
 // ListTests returns the test results page
-func (a HTMLAdapter) ListTests(pageToken string) (code int, body []byte, err error) {
+func (a HTMLAdapter) ListTests(q mockingbird.ListTestsQuery) (code int, body []byte, err error) {
 	b := append(a.Body, "list test result page"...)
 
 	if a.Err != nil {
@@ -65,7 +65,7 @@ func (a HTMLAdapter) ShowTest(id mockingbird.ULID) (code int, body []byte, err e
 }
 
 // RunTest starts a test suite
-func (a HTMLAdapter) RunTest(ts mockingbird.TestSuite) (id mockingbird.ULID, code int, cody []byte, err error) {
+func (a HTMLAdapter) RunTest(ts mockingbird.TestSuite, user string) (id mockingbird.ULID, code int, cody []byte, err error) {
 	if ts == "" {
 		return "", http.StatusNotFound, []byte("RunTest failed with 404 not found"), errs.NotFound("Not found")
 	}
@@ -77,6 +77,27 @@ func (a HTMLAdapter) RunTest(ts mockingbird.TestSuite) (id mockingbird.ULID, cod
 	return "test-suite-id", a.Code, b, a.Err
 }
 
+// CancelTest cancels a test suite
+func (a HTMLAdapter) CancelTest(id mockingbird.ULID) (code int, body []byte, err error) {
+	if a.IDErr != nil {
+		b := append(a.Body, "HasIdError for "...)
+		b = append(b, id...)
+		return a.Code, b, a.IDErr
+	}
+
+	b := a.Body
+	if a.Err != nil {
+		b = append(b, "with cancelTest error"...)
+	}
+	return a.Code, b, a.Err
+}
+
+// ShowDeliveries returns the notification delivery log page
+func (a HTMLAdapter) ShowDeliveries() (code int, body []byte, err error) {
+	b := append(a.Body, "notifications page"...)
+	return a.Code, b, a.Err
+}
+
 //
 // Error pages
 //
@@ -92,3 +113,9 @@ func (a HTMLAdapter) InvalidURL() (body []byte) {
 	b := append(a.Body, "Invalid URL page"...)
 	return b
 }
+
+// InternalError returns the internal error page
+func (a HTMLAdapter) InternalError() (body []byte) {
+	b := append(a.Body, "Internal Error page"...)
+	return b
+}
@@ -0,0 +1,109 @@
+package mock
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// JSONAdapter is used for tests
+type JSONAdapter struct {
+	Code  int
+	Err   error
+	IDErr error
+}
+
+// Verifies that JSONAdapter implements mockingbird.JSONAdapter interface
+var _ mockingbird.JSONAdapter = JSONAdapter{}
+
+//
+// Business  Logic
+//
+
+// Dashboard returns the stats
+func (a JSONAdapter) Dashboard() (code int, body []byte, err error) {
+	return a.Code, a.body("dashboard"), a.Err
+}
+
+// ListTests returns the test results
+func (a JSONAdapter) ListTests(q mockingbird.ListTestsQuery) (code int, body []byte, err error) {
+	return a.Code, a.body("list test results"), a.Err
+}
+
+// ShowTestSuites returns the test suites
+func (a JSONAdapter) ShowTestSuites() (code int, body []byte, err error) {
+	return a.Code, a.body("test suites"), a.Err
+}
+
+// ShowTest returns the test result
+func (a JSONAdapter) ShowTest(id mockingbird.ULID) (code int, body []byte, err error) {
+	if a.IDErr != nil {
+		return a.Code, a.body("HasIdError for " + string(id)), a.IDErr
+	}
+	return a.Code, a.body("test result for id=" + string(id)), a.Err
+}
+
+// RunTest starts a test suite
+func (a JSONAdapter) RunTest(ts mockingbird.TestSuite, user string) (id mockingbird.ULID, code int, body []byte, err error) {
+	if ts == "" {
+		return "", http.StatusBadRequest, a.body("RunTest failed with 400 bad request"), errors.New("test_suite is required")
+	}
+	if user != "" {
+		return "test-suite-id", a.Code, a.body("run test " + string(ts) + " by " + user), a.Err
+	}
+	return "test-suite-id", a.Code, a.body("run test " + string(ts)), a.Err
+}
+
+// RunDeploy starts the test suites of a deployment
+func (a JSONAdapter) RunDeploy(payload []byte) (code int, body []byte, err error) {
+	if len(payload) == 0 {
+		return http.StatusBadRequest, a.body("RunDeploy failed with 400 bad request"), errors.New("payload is required")
+	}
+	return a.Code, a.body("run deploy " + string(payload)), a.Err
+}
+
+// CancelTest cancels a test suite
+func (a JSONAdapter) CancelTest(id mockingbird.ULID) (code int, body []byte, err error) {
+	if a.IDErr != nil {
+		return a.Code, a.body("HasIdError for " + string(id)), a.IDErr
+	}
+	return a.Code, a.body("cancel test " + string(id)), a.Err
+}
+
+// ShowDeliveries returns the notification delivery log
+func (a JSONAdapter) ShowDeliveries() (code int, body []byte, err error) {
+	return a.Code, a.body("notifications"), a.Err
+}
+
+//
+// Error bodies
+//
+
+// ErrorNotFound returns a not found error
+func (a JSONAdapter) ErrorNotFound() (body []byte) {
+	return a.body("Not Found")
+}
+
+// InvalidURL returns an invalid URL error
+func (a JSONAdapter) InvalidURL() (body []byte) {
+	return a.body("Invalid URL")
+}
+
+// InternalError returns an internal error
+func (a JSONAdapter) InternalError() (body []byte) {
+	return a.body("Internal Error")
+}
+
+//
+// PRIVATE
+//
+
+func (a JSONAdapter) body(msg string) []byte {
+	if a.Err != nil {
+		msg += " with error"
+	}
+	return []byte(fmt.Sprintf(`{"mock":%q}`, msg))
+}
@@ -14,21 +14,21 @@ type Log struct {
 var _ mockingbird.Log = &Log{}
 
 // Error records error messages
-func (l *Log) Error(msg string) {
+func (l *Log) Error(msg string, fields ...mockingbird.Field) {
 	l.ErrorLog = append(l.ErrorLog, msg)
 }
 
 // Notice records notice messages
-func (l *Log) Notice(msg string) {
-	l.InfoLog = append(l.NoticeLog, msg)
+func (l *Log) Notice(msg string, fields ...mockingbird.Field) {
+	l.NoticeLog = append(l.NoticeLog, msg)
 }
 
 // Info records info messages
-func (l *Log) Info(msg string) {
+func (l *Log) Info(msg string, fields ...mockingbird.Field) {
 	l.InfoLog = append(l.InfoLog, msg)
 }
 
 // Debug records debug messages
-func (l *Log) Debug(msg string) {
-	l.ErrorLog = append(l.DebugLog, msg)
+func (l *Log) Debug(msg string, fields ...mockingbird.Field) {
+	l.DebugLog = append(l.DebugLog, msg)
 }
@@ -0,0 +1,31 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// LogStream is used for tests
+type LogStream struct {
+	Chunks []string
+	Err    error
+}
+
+// Verifies that mock.LogStream implements mockingbird.LogStream interface
+var _ mockingbird.LogStream = LogStream{}
+
+// FollowLog calls fn with each chunk
+func (s LogStream) FollowLog(ctx context.Context, id mockingbird.ULID, offset int, fn func(chunk string, offset int) error) error {
+	if s.Err != nil {
+		return s.Err
+	}
+
+	for _, c := range s.Chunks {
+		offset += len(c)
+		if err := fn(c, offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
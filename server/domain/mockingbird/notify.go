@@ -0,0 +1,162 @@
+package mockingbird
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NotifyFilter selects the finished test results a subscription is notified of
+type NotifyFilter string
+
+// The possible notify filters
+const (
+	FAILURES   NotifyFilter = "failures"   // failed and timed out runs
+	RECOVERIES              = "recoveries" // successful runs after a failed or timed out run
+	ALERTS                  = "alerts"     // failures and recoveries
+	ALL_RUNS                = "all"        // every finished run
+)
+
+// ParseNotifyFilter returns the notify filter of s, e.g: failures
+func ParseNotifyFilter(s string) (NotifyFilter, error) {
+	switch f := NotifyFilter(s); f {
+	case FAILURES, RECOVERIES, ALERTS, ALL_RUNS:
+		return f, nil
+	default:
+		const format = "notify filter %q must be one of %s, %s, %s, %s"
+		return "", fmt.Errorf(format, s, FAILURES, RECOVERIES, ALERTS, ALL_RUNS)
+	}
+}
+
+// Match returns true if the notification passes the filter
+func (f NotifyFilter) Match(n Notification) bool {
+	switch f {
+	case FAILURES:
+		return n.IsFailure()
+	case RECOVERIES:
+		return n.IsRecovery()
+	case ALERTS:
+		return n.IsFailure() || n.IsRecovery()
+	case ALL_RUNS:
+		return true
+	default:
+		return false
+	}
+}
+
+// Notification is sent when a test suite has finished
+type Notification struct {
+	Result TestResult
+
+	// Previous is the state of the previous run of the test suite, empty on its first run
+	Previous State
+
+	// URL is the link to the test result page
+	URL string
+}
+
+// IsFailure returns true if the run failed or timed out
+func (n Notification) IsFailure() bool {
+	return isFailure(n.Result.State)
+}
+
+// IsRecovery returns true if the run succeeded after a failed or timed out run
+func (n Notification) IsRecovery() bool {
+	return n.Result.State == SUCCESSFUL && isFailure(n.Previous)
+}
+
+// IsRepeatedFailure returns true if the run and the previous run failed or timed out
+func (n Notification) IsRepeatedFailure() bool {
+	return n.IsFailure() && isFailure(n.Previous)
+}
+
+// Event returns the kind of the notification: failure, recovery or run
+func (n Notification) Event() string {
+	switch {
+	case n.IsFailure():
+		return "failure"
+	case n.IsRecovery():
+		return "recovery"
+	default:
+		return "run"
+	}
+}
+
+// Outcome returns the outcome of the run, e.g: passed, failed, failed again, recovered, timed out
+func (n Notification) Outcome() string {
+	state := strings.Replace(string(n.Result.State), "_", " ", -1)
+	switch {
+	case n.IsRepeatedFailure():
+		return state + " again"
+	case n.IsRecovery():
+		return "recovered"
+	case n.Result.State == SUCCESSFUL:
+		return "passed"
+	default:
+		return state
+	}
+}
+
+// LogTail returns the last lines of the log of the run
+func (n Notification) LogTail(lines int) string {
+	log := strings.Split(strings.TrimRight(n.Result.Log, "\n"), "\n")
+	if len(log) > lines {
+		log = log[len(log)-lines:]
+	}
+	return strings.Join(log, "\n")
+}
+
+// Notifier sends notifications, e.g: as a webhook
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+
+	// String returns where the notifications are sent; it is shown in the delivery log
+	String() string
+}
+
+// Subscription notifies the notifier of the notifications that pass the filter
+type Subscription struct {
+	Filter   NotifyFilter
+	Notifier Notifier
+
+	// TestSuites are the test suites of the subscription; empty is all test suites
+	TestSuites []TestSuite
+}
+
+// Match returns true if the notification is of the test suites and passes the filter
+func (s Subscription) Match(n Notification) bool {
+	if !s.Filter.Match(n) {
+		return false
+	}
+	if len(s.TestSuites) == 0 {
+		return true
+	}
+
+	for _, suite := range s.TestSuites {
+		if suite == n.Result.TestSuite {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is an entry in the delivery log of the notifications
+type Delivery struct {
+	TestResultID ULID
+	TestSuite    TestSuite
+	Event        string
+	Notifier     string
+	Attempts     int
+	Error        string // the error of the last attempt, empty when the notification was delivered
+	Time         time.Time
+}
+
+// IsDelivered returns true if the notification was delivered
+func (d Delivery) IsDelivered() bool {
+	return d.Error == ""
+}
+
+func isFailure(s State) bool {
+	return s == FAILED || s == TIMED_OUT
+}
@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/mail"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// Renderer renders the HTML part of an email report, e.g: *html.Template
+type Renderer interface {
+	EmailReport(n mockingbird.Notification) ([]byte, error)
+}
+
+// Email sends notifications as multipart text and HTML reports over SMTP
+type Email struct {
+	SMTP mail.Config
+	From string
+	To   []string
+	Tmpl Renderer
+}
+
+// Verifies that Email implements mockingbird.Notifier interface
+var _ mockingbird.Notifier = Email{}
+
+// Notify sends the email report of the notification to the recipients
+func (e Email) Notify(ctx context.Context, n mockingbird.Notification) error {
+	html, err := e.Tmpl.EmailReport(n)
+	if err != nil {
+		return errors.Wrap(err, "Tmpl.EmailReport() failed")
+	}
+
+	msg := mail.Message{
+		From:    e.From,
+		To:      e.To,
+		Subject: Subject(n),
+		Text:    Text(n),
+		HTML:    html,
+	}
+	return mail.Send(ctx, e.SMTP, msg)
+}
+
+// String returns the recipients
+func (e Email) String() string {
+	return "email " + strings.Join(e.To, ", ")
+}
+
+// Subject returns the subject of the email report, e.g: [mockingbird] all:test failed
+func Subject(n mockingbird.Notification) string {
+	return fmt.Sprintf("[mockingbird] %s %s", n.Result.TestSuite, n.Outcome())
+}
+
+// Text returns the text part of the email report
+//
+// Usage:
+//
+//        all:test failed
+//
+//        State:      failed
+//        Previous:   successful
+//        Duration:   1m30s
+//        ...
+//
+func Text(n mockingbird.Notification) []byte {
+	tr := n.Result
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s\n\n", tr.TestSuite, n.Outcome())
+
+	row := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%-12s%s\n", name+":", value)
+		}
+	}
+	row("State", string(tr.State))
+	row("Previous", string(n.Previous))
+	row("Duration", tr.RunTime.String())
+	row("Started", tr.StartTime.UTC().Format(time.RFC1123))
+	row("Trigger", string(tr.Trigger))
+	if d := tr.Deployment; d != nil {
+		row("Deployment", strings.TrimSpace(fmt.Sprintf("%s %s %s", d.Service, d.Version, d.Environment)))
+	}
+
+	if log := n.LogTail(logLines); log != "" {
+		fmt.Fprintf(&b, "\nThe last %d lines of the log:\n\n%s\n", logLines, log)
+	}
+	fmt.Fprintf(&b, "\n%s\n", n.URL)
+	return b.Bytes()
+}
+
+// EmailConfig defines the SMTP server and the recipients of the email reports
+type EmailConfig struct {
+	SMTP  mail.Config
+	From  string
+	Rules []EmailRule
+}
+
+// EmailRule sends the email reports of the test suites, or all when empty, to the recipients
+type EmailRule struct {
+	TestSuites []mockingbird.TestSuite `json:"test_suites"`
+	To         []string                `json:"to"`
+}
+
+// ParseEmailConfig returns the email config of a JSON document; the
+// password is not part of the document, set it on SMTP.Password
+//
+// Usage:
+//
+//        {
+//          "host": "smtp.example.com",
+//          "port": 587,
+//          "starttls": true,
+//          "username": "mockingbird",
+//          "from": "Mockingbird <mockingbird@example.com>",
+//          "rules": [
+//            {"test_suites": ["all:test"], "to": ["qa@example.com"]}
+//          ]
+//        }
+//
+func ParseEmailConfig(r io.Reader) (EmailConfig, error) {
+	var doc struct {
+		Host     string      `json:"host"`
+		Port     int         `json:"port"`
+		StartTLS bool        `json:"starttls"`
+		Username string      `json:"username"`
+		From     string      `json:"from"`
+		Rules    []EmailRule `json:"rules"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return EmailConfig{}, errors.Wrap(err, "json.Decode(config) failed")
+	}
+
+	if doc.Host == "" {
+		return EmailConfig{}, errors.New("email config has no host")
+	}
+	if doc.Port == 0 {
+		return EmailConfig{}, errors.New("email config has no port")
+	}
+	if doc.From == "" {
+		return EmailConfig{}, errors.New("email config has no from address")
+	}
+	for i, rule := range doc.Rules {
+		if len(rule.To) == 0 {
+			return EmailConfig{}, errors.Errorf("rule %d has no recipients", i+1)
+		}
+	}
+
+	c := EmailConfig{
+		SMTP:  mail.Config{Host: doc.Host, Port: doc.Port, Username: doc.Username, StartTLS: doc.StartTLS},
+		From:  doc.From,
+		Rules: doc.Rules,
+	}
+	return c, nil
+}
+
+// Subscriptions returns a subscription to the failures and recoveries per rule
+func (c EmailConfig) Subscriptions(tmpl Renderer) []mockingbird.Subscription {
+	subs := make([]mockingbird.Subscription, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		subs = append(subs, mockingbird.Subscription{
+			Filter:     mockingbird.ALERTS,
+			TestSuites: rule.TestSuites,
+			Notifier:   Email{SMTP: c.SMTP, From: c.From, To: rule.To, Tmpl: tmpl},
+		})
+	}
+	return subs
+}
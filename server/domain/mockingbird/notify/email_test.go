@@ -0,0 +1,182 @@
+package notify_test
+
+import (
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"testing"
+
+	"github.com/unders/mockingbird/server/pkg/mail"
+	"github.com/unders/mockingbird/server/pkg/mail/mailtest"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/html"
+	"github.com/unders/mockingbird/server/domain/mockingbird/notify"
+)
+
+const templateDir = "../../../../web/mockingbird/tmpl"
+
+func TestEmail_Notify_SendsTextAndHTMLReport(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+
+	tmpl, err := html.NewTemplate(templateDir)
+	testdata.AssertNil(t, err)
+
+	n := notification
+	n.Result.Log = "=== RUN   TestSearch\n--- FAIL: TestSearch (0.01s)\n    search_test.go:12: <title> is missing\n"
+
+	email := notify.Email{
+		SMTP: mail.Config{Host: srv.Host(), Port: srv.Port()},
+		From: "Mockingbird <mockingbird@example.com>",
+		To:   []string{"qa@example.com"},
+		Tmpl: tmpl,
+	}
+	testdata.AssertNil(t, email.Notify(context.Background(), n))
+
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("\nWant: 1 message\n Got: %d\n", len(msgs))
+	}
+	m, err := netmail.ReadMessage(strings.NewReader(msgs[0].Data))
+	testdata.AssertNil(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	testdata.AssertNil(t, err)
+	if want := "[mockingbird] all:test failed"; want != subject {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, subject)
+	}
+
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	testdata.AssertNil(t, err)
+	r := multipart.NewReader(m.Body, params["boundary"])
+
+	text := nextPart(t, r)
+	for _, want := range []string{
+		"all:test failed\n",
+		"State:      failed\n",
+		"Previous:   successful\n",
+		"Duration:   1m30s\n",
+		"Trigger:    scheduled\n",
+		"search_test.go:12: <title> is missing\n",
+		n.URL,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part\nWant: %s\n Got: %s\n", want, text)
+		}
+	}
+
+	body := nextPart(t, r)
+	for _, want := range []string{
+		"all:test failed</h1>",
+		"search_test.go:12: &lt;title&gt; is missing",
+		`href="` + n.URL + `"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("HTML part\nWant: %s\n Got: %s\n", want, body)
+		}
+	}
+}
+
+func TestSubject(t *testing.T) {
+	recovery := notification
+	recovery.Result.State = mockingbird.SUCCESSFUL
+	recovery.Previous = mockingbird.TIMED_OUT
+
+	repeated := notification
+	repeated.Previous = mockingbird.FAILED
+
+	testCases := []struct {
+		n    mockingbird.Notification
+		want string
+	}{
+		{n: notification, want: "[mockingbird] all:test failed"},
+		{n: recovery, want: "[mockingbird] all:test recovered"},
+		{n: repeated, want: "[mockingbird] all:test failed again"},
+	}
+	for _, tc := range testCases {
+		if got := notify.Subject(tc.n); tc.want != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", tc.want, got)
+		}
+	}
+}
+
+func TestParseEmailConfig(t *testing.T) {
+	doc := `{
+		"host": "smtp.example.com",
+		"port": 587,
+		"starttls": true,
+		"username": "mockingbird",
+		"from": "Mockingbird <mockingbird@example.com>",
+		"rules": [
+			{"test_suites": ["all:test"], "to": ["qa@example.com", "ops@example.com"]},
+			{"to": ["lead@example.com"]}
+		]
+	}`
+	c, err := notify.ParseEmailConfig(strings.NewReader(doc))
+	testdata.AssertNil(t, err)
+
+	want := "{Host:smtp.example.com Port:587 Username:mockingbird StartTLS:true}"
+	if got := c.SMTP.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	subs := c.Subscriptions(nil)
+	if len(subs) != 2 {
+		t.Fatalf("\nWant: 2 subscriptions\n Got: %d\n", len(subs))
+	}
+	for i, want := range []struct {
+		notifier string
+		suites   int
+	}{
+		{notifier: "email qa@example.com, ops@example.com", suites: 1},
+		{notifier: "email lead@example.com", suites: 0},
+	} {
+		s := subs[i]
+		if got := s.Notifier.String(); want.notifier != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", want.notifier, got)
+		}
+		if got := len(s.TestSuites); want.suites != got {
+			t.Errorf("\nWant: %d\n Got: %d\n", want.suites, got)
+		}
+		if s.Filter != mockingbird.ALERTS {
+			t.Errorf("\nWant: %s\n Got: %s\n", mockingbird.ALERTS, s.Filter)
+		}
+	}
+}
+
+func TestParseEmailConfig_WhenInvalid_ReturnsError(t *testing.T) {
+	testCases := []struct {
+		name string
+		doc  string
+	}{
+		{name: "invalid JSON", doc: `{"host": `},
+		{name: "no host", doc: `{"port": 25, "from": "mockingbird@example.com"}`},
+		{name: "no port", doc: `{"host": "smtp.example.com", "from": "mockingbird@example.com"}`},
+		{name: "no from", doc: `{"host": "smtp.example.com", "port": 25}`},
+		{
+			name: "no recipients",
+			doc:  `{"host": "smtp.example.com", "port": 25, "from": "mockingbird@example.com", "rules": [{"test_suites": ["all:test"]}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		if _, err := notify.ParseEmailConfig(strings.NewReader(tc.doc)); err == nil {
+			t.Errorf("%s\nWant: error\n Got: nil\n", tc.name)
+		}
+	}
+}
+
+func nextPart(t *testing.T, r *multipart.Reader) string {
+	t.Helper()
+
+	part, err := r.NextPart()
+	testdata.AssertNil(t, err)
+	b, err := ioutil.ReadAll(part)
+	testdata.AssertNil(t, err)
+	return string(b)
+}
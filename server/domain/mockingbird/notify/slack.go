@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+// logLines is the number of log lines at the end of the log that a chat message or email shows
+const logLines = 20
+
+// Slack posts notifications as chat messages to a Slack-compatible incoming webhook
+//
+// Note:
+//
+//        The message has the plain text and attachment fields that
+//        Slack, Mattermost and Rocket.Chat all support. Owner is
+//        mentioned when a test suite fails twice in a row, e.g: <@U024BE7LH>,
+//        <!subteam^SAZ94GDB8> or <!here>.
+//
+type Slack struct {
+	URL     string
+	Channel string // overrides the channel of the webhook when set, e.g: #alerts
+	Owner   string
+	Client  *http.Client // http.DefaultClient when nil
+}
+
+// Verifies that Slack implements mockingbird.Notifier interface
+var _ mockingbird.Notifier = Slack{}
+
+// Notify posts the chat message of the notification; a response that is not 2xx is an error
+func (s Slack) Notify(ctx context.Context, n mockingbird.Notification) error {
+	payload, err := s.Payload(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "http.NewRequest() failed")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	c := s.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	return post(c, req)
+}
+
+// String returns the host and channel, the path of an incoming webhook URL is its token
+func (s Slack) String() string {
+	host := "(invalid URL)"
+	if u, err := url.Parse(s.URL); err == nil {
+		host = u.Host
+	}
+
+	if s.Channel == "" {
+		return "slack " + host
+	}
+	return "slack " + host + " " + s.Channel
+}
+
+// Payload returns the incoming webhook payload of the notification
+//
+// Usage:
+//
+//        {
+//          "text": ":x: *all:test* failed in 1m30s <@U024BE7LH>",
+//          "attachments": [{"color": "danger", "title": "01BX5ZZKBKACTAV9WEVGEMMVS0", ...}]
+//        }
+//
+func (s Slack) Payload(n mockingbird.Notification) ([]byte, error) {
+	tr := n.Result
+
+	text := fmt.Sprintf("%s *%s* %s in %s", stateIcon(tr.State), escape(string(tr.TestSuite)), n.Outcome(), tr.RunTime)
+	if n.IsRepeatedFailure() && s.Owner != "" {
+		// the owner is a mention, so it is not escaped
+		text += " " + s.Owner
+	}
+
+	fields := []slackField{
+		{Title: "State", Value: string(tr.State), Short: true},
+		{Title: "Duration", Value: tr.RunTime.String(), Short: true},
+	}
+	if tr.Trigger != "" {
+		fields = append(fields, slackField{Title: "Trigger", Value: string(tr.Trigger), Short: true})
+	}
+	if d := tr.Deployment; d != nil {
+		deployment := strings.TrimSpace(fmt.Sprintf("%s %s %s", d.Service, d.Version, d.Environment))
+		fields = append(fields, slackField{Title: "Deployment", Value: escape(deployment), Short: true})
+	}
+
+	attachment := slackAttachment{
+		Fallback:   fmt.Sprintf("%s %s: %s", tr.TestSuite, n.Outcome(), n.URL),
+		Color:      stateColor(tr.State),
+		Title:      string(tr.ID),
+		TitleLink:  n.URL,
+		Fields:     fields,
+		MarkdownIn: []string{"text"},
+	}
+	if log := n.LogTail(logLines); log != "" {
+		attachment.Text = "```\n" + escape(strings.Replace(log, "```", "'''", -1)) + "\n```"
+	}
+
+	msg := slackMessage{Channel: s.Channel, Text: text, Attachments: []slackAttachment{attachment}}
+	b, err := json.Marshal(msg)
+	return b, errors.Wrap(err, "json.Marshal(message) failed")
+}
+
+//
+// PRIVATE
+//
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Fallback   string       `json:"fallback"`
+	Color      string       `json:"color"`
+	Title      string       `json:"title"`
+	TitleLink  string       `json:"title_link"`
+	Fields     []slackField `json:"fields"`
+	Text       string       `json:"text,omitempty"`
+	MarkdownIn []string     `json:"mrkdwn_in"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func stateIcon(s mockingbird.State) string {
+	switch s {
+	case mockingbird.SUCCESSFUL:
+		return ":white_check_mark:"
+	case mockingbird.FAILED:
+		return ":x:"
+	case mockingbird.TIMED_OUT:
+		return ":hourglass:"
+	case mockingbird.INTERRUPTED:
+		return ":warning:"
+	default:
+		return ":grey_question:"
+	}
+}
+
+func stateColor(s mockingbird.State) string {
+	switch s {
+	case mockingbird.SUCCESSFUL:
+		return "good"
+	case mockingbird.FAILED, mockingbird.TIMED_OUT:
+		return "danger"
+	default:
+		return "warning"
+	}
+}
+
+// escape escapes the control characters of the message format
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// ParseSlackRules returns a subscription per rule of a JSON document
+// whose environment is env or empty; the filter is failures by default
+//
+// Usage:
+//
+//        [
+//          {
+//            "environment": "prod",
+//            "test_suites": ["all:test"],
+//            "filter": "failures",
+//            "webhook_url": "https://hooks.slack.com/services/T000/B000/XXXX",
+//            "channel": "#alerts",
+//            "owner": "<!subteam^SAZ94GDB8>"
+//          }
+//        ]
+//
+func ParseSlackRules(r io.Reader, env mockingbird.Env) ([]mockingbird.Subscription, error) {
+	var rules []struct {
+		Environment mockingbird.Env         `json:"environment"`
+		TestSuites  []mockingbird.TestSuite `json:"test_suites"`
+		Filter      string                  `json:"filter"`
+		WebhookURL  string                  `json:"webhook_url"`
+		Channel     string                  `json:"channel"`
+		Owner       string                  `json:"owner"`
+	}
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, errors.Wrap(err, "json.Decode(rules) failed")
+	}
+
+	var subs []mockingbird.Subscription
+	for i, rule := range rules {
+		if rule.WebhookURL == "" {
+			return nil, errors.Errorf("rule %d has no webhook_url", i+1)
+		}
+		filter := mockingbird.FAILURES
+		if rule.Filter != "" {
+			var err error
+			if filter, err = mockingbird.ParseNotifyFilter(rule.Filter); err != nil {
+				return nil, errors.Wrapf(err, "rule %d", i+1)
+			}
+		}
+		if rule.Environment != "" && rule.Environment != env {
+			continue
+		}
+
+		subs = append(subs, mockingbird.Subscription{
+			Filter:     filter,
+			TestSuites: rule.TestSuites,
+			Notifier:   Slack{URL: rule.WebhookURL, Channel: rule.Channel, Owner: rule.Owner},
+		})
+	}
+	return subs, nil
+}
@@ -0,0 +1,158 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unders/mockingbird/server/pkg/testdata"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/notify"
+)
+
+func TestSlack_Notify_PostsTheChatMessage(t *testing.T) {
+	var got []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var err error
+		got, err = ioutil.ReadAll(req.Body)
+		testdata.AssertNil(t, err)
+	}))
+	defer receiver.Close()
+
+	slack := notify.Slack{URL: receiver.URL + "/services/T000/B000/XXXX", Channel: "#alerts", Owner: "<@U024BE7LH>"}
+	if want, got := "slack "+strings.TrimPrefix(receiver.URL, "http://")+" #alerts", slack.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	n := notification
+	n.Result.Log = "--- FAIL: TestSearch (0.00s)\n    search_test.go:9: got <nil>\nFAIL\n"
+	testdata.AssertNil(t, slack.Notify(context.Background(), n))
+
+	want := `{"channel":"#alerts","text":":x: *all:test* failed in 1m30s",` +
+		`"attachments":[{"fallback":"all:test failed: https://mockingbird.example.com/tests/01BX5ZZKBKACTAV9WEVGEMMVS0",` +
+		`"color":"danger","title":"01BX5ZZKBKACTAV9WEVGEMMVS0",` +
+		`"title_link":"https://mockingbird.example.com/tests/01BX5ZZKBKACTAV9WEVGEMMVS0",` +
+		`"fields":[{"title":"State","value":"failed","short":true},{"title":"Duration","value":"1m30s","short":true},` +
+		`{"title":"Trigger","value":"scheduled","short":true}],` +
+		`"text":"` + "```" + `\n--- FAIL: TestSearch (0.00s)\n    search_test.go:9: got \u0026lt;nil\u0026gt;\nFAIL\n` + "```" + `",` +
+		`"mrkdwn_in":["text"]}]}`
+	if want != string(got) {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestSlack_Notify_WhenItFails_ReturnsErrorWithoutTheToken(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer receiver.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	for _, url := range []string{receiver.URL, down.URL} {
+		slack := notify.Slack{URL: url + "/services/T000/B000/XXXX"}
+		err := slack.Notify(context.Background(), notification)
+		if err == nil {
+			t.Fatalf("%s\nWant: error\n Got: nil\n", url)
+		}
+		if strings.Contains(err.Error(), "/services/") || strings.Contains(err.Error(), "XXXX") {
+			t.Errorf("\nWant: no token\n Got: %s\n", err)
+		}
+		if !strings.HasPrefix(err.Error(), "POST "+url+" ") {
+			t.Errorf("\nWant: POST %s ...\n Got: %s\n", url, err)
+		}
+	}
+}
+
+func TestSlack_Payload(t *testing.T) {
+	var (
+		successful mockingbird.State = mockingbird.SUCCESSFUL
+		failed     mockingbird.State = mockingbird.FAILED
+		timedOut   mockingbird.State = mockingbird.TIMED_OUT
+	)
+
+	testCases := []struct {
+		state, previous mockingbird.State
+		wantText        string
+	}{
+		{state: failed, previous: successful, wantText: ":x: *all:test* failed in 1m30s"},
+		{state: failed, previous: timedOut, wantText: ":x: *all:test* failed again in 1m30s <@U024BE7LH>"},
+		{state: timedOut, previous: failed, wantText: ":hourglass: *all:test* timed out again in 1m30s <@U024BE7LH>"},
+		{state: successful, previous: failed, wantText: ":white_check_mark: *all:test* recovered in 1m30s"},
+		{state: successful, previous: successful, wantText: ":white_check_mark: *all:test* passed in 1m30s"},
+	}
+
+	for _, tc := range testCases {
+		n := notification
+		n.Result.State, n.Previous = tc.state, tc.previous
+
+		b, err := notify.Slack{Owner: "<@U024BE7LH>"}.Payload(n)
+		testdata.AssertNil(t, err)
+
+		msg := struct{ Text string }{}
+		testdata.AssertNil(t, json.Unmarshal(b, &msg))
+		if tc.wantText != msg.Text {
+			t.Errorf("\nWant: %s\n Got: %s\n", tc.wantText, msg.Text)
+		}
+	}
+}
+
+func TestSlack_Payload_ShowsTheLast20LogLines(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 25; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	n := notification
+	n.Result.Log = strings.Join(lines, "\n") + "\n"
+
+	b, err := notify.Slack{}.Payload(n)
+	testdata.AssertNil(t, err)
+
+	msg := struct{ Attachments []struct{ Text string } }{}
+	testdata.AssertNil(t, json.Unmarshal(b, &msg))
+
+	want := "```\n" + strings.Join(lines[5:], "\n") + "\n```"
+	if got := msg.Attachments[0].Text; want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestParseSlackRules_ReturnsTheSubscriptionsOfTheEnvironment(t *testing.T) {
+	subs, err := notify.ParseSlackRules(strings.NewReader(`[
+		{"environment": "prod", "test_suites": ["all:test"], "webhook_url": "https://chat.example.com/hooks/1", "owner": "<!here>"},
+		{"environment": "stag", "webhook_url": "https://chat.example.com/hooks/2"},
+		{"filter": "recoveries", "webhook_url": "https://chat.example.com/hooks/3", "channel": "#tests"}
+	]`), mockingbird.PROD)
+	testdata.AssertNil(t, err)
+
+	want := []mockingbird.Subscription{
+		{
+			Filter:     mockingbird.FAILURES,
+			TestSuites: []mockingbird.TestSuite{"all:test"},
+			Notifier:   notify.Slack{URL: "https://chat.example.com/hooks/1", Owner: "<!here>"},
+		},
+		{
+			Filter:   mockingbird.RECOVERIES,
+			Notifier: notify.Slack{URL: "https://chat.example.com/hooks/3", Channel: "#tests"},
+		},
+	}
+	if !reflect.DeepEqual(want, subs) {
+		t.Errorf("\nWant: %+v\n Got: %+v\n", want, subs)
+	}
+}
+
+func TestParseSlackRules_WhenInvalid_ReturnsError(t *testing.T) {
+	for _, s := range []string{"", "{}", `[{"filter": "failures"}]`, `[{"filter": "never", "webhook_url": "https://chat.example.com"}]`} {
+		if _, err := notify.ParseSlackRules(strings.NewReader(s), mockingbird.PROD); err == nil {
+			t.Errorf("%q\nWant: error\n Got: nil\n", s)
+		}
+	}
+}
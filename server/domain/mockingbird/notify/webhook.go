@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/signature"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/json"
+)
+
+// EventHeader is the HTTP header of the notification event: failure, recovery or run
+const EventHeader = "X-Mockingbird-Event"
+
+// Webhook posts notifications as JSON to a URL
+//
+// Note:
+//
+//        The timestamp and the body are signed with HMAC-SHA256 and the
+//        secret, see package signature, and sent in the X-Mockingbird-Timestamp
+//        and X-Mockingbird-Signature headers; they are not signed when the
+//        secret is empty.
+//
+type Webhook struct {
+	URL    string
+	Secret []byte
+	Client *http.Client // http.DefaultClient when nil
+}
+
+// Verifies that Webhook implements mockingbird.Notifier interface
+var _ mockingbird.Notifier = Webhook{}
+
+// Notify posts the notification; a response that is not 2xx is an error
+func (w Webhook) Notify(ctx context.Context, n mockingbird.Notification) error {
+	payload, err := json.Notification(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "http.NewRequest() failed")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, n.Event())
+	if len(w.Secret) > 0 {
+		timestamp := signature.Timestamp(time.Now())
+		req.Header.Set(signature.TimestampHeader, timestamp)
+		req.Header.Set(signature.Header, signature.Sign(w.Secret, timestamp, payload))
+	}
+
+	return post(w.client(), req)
+}
+
+// String returns the URL without its credentials and query, they may have a token
+func (w Webhook) String() string {
+	return "webhook " + redact(w.URL)
+}
+
+//
+// PRIVATE
+//
+
+// maxResponse is the max number of bytes of a response body that are read
+const maxResponse = 1 << 10
+
+func (w Webhook) client() *http.Client {
+	if w.Client != nil {
+		return w.Client
+	}
+	return http.DefaultClient
+}
+
+// post sends the request and returns an error if the response is not 2xx
+//
+// Note:
+//
+//        The errors show the scheme and host of the URL only: the path of
+//        a Slack incoming webhook is its token, and the errors are shown
+//        in the delivery log and written to the log.
+//
+func post(c *http.Client, req *http.Request) error {
+	origin := req.URL.Scheme + "://" + req.URL.Host
+
+	resp, err := c.Do(req)
+	if err != nil {
+		// the message of a *url.Error has the whole URL
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return errors.Wrapf(err, "POST %s failed", origin)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s returned %d %s", origin, resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+func redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "(invalid URL)"
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
@@ -0,0 +1,92 @@
+package notify_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/signature"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/notify"
+)
+
+var notification = mockingbird.Notification{
+	Result: mockingbird.TestResult{
+		ID:        "01BX5ZZKBKACTAV9WEVGEMMVS0",
+		Status:    mockingbird.DONE,
+		State:     mockingbird.FAILED,
+		TestSuite: "all:test",
+		StartTime: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+		RunTime:   90 * time.Second,
+		Trigger:   mockingbird.SCHEDULED,
+	},
+	Previous: mockingbird.SUCCESSFUL,
+	URL:      "https://mockingbird.example.com/tests/01BX5ZZKBKACTAV9WEVGEMMVS0",
+}
+
+func TestWebhook_Notify_PostsSignedJSON(t *testing.T) {
+	secret := []byte("secret")
+
+	var got struct {
+		contentType, event string
+		valid              bool
+		body               string
+	}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, err := ioutil.ReadAll(req.Body)
+		testdata.AssertNil(t, err)
+
+		got.contentType = req.Header.Get("Content-Type")
+		got.event = req.Header.Get(notify.EventHeader)
+		timestamp := req.Header.Get(signature.TimestampHeader)
+		got.valid = signature.Valid(secret, b, timestamp, req.Header.Get(signature.Header), time.Now())
+		got.body = string(b)
+	}))
+	defer receiver.Close()
+
+	hook := notify.Webhook{URL: receiver.URL + "/hook", Secret: secret}
+	testdata.AssertNil(t, hook.Notify(context.Background(), notification))
+
+	if want := "application/json"; want != got.contentType {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got.contentType)
+	}
+	if want := "failure"; want != got.event {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got.event)
+	}
+	testdata.AssertTrue(t, got.valid)
+
+	want := `{"version":1,"event":"failure","previous_state":"successful",` +
+		`"test_result":{"id":"01BX5ZZKBKACTAV9WEVGEMMVS0","status":"done","state":"failed","test_suite":"all:test",` +
+		`"start_time":"2019-01-02T03:04:05Z","run_time_ms":90000,"trigger":"scheduled"},` +
+		`"url":"https://mockingbird.example.com/tests/01BX5ZZKBKACTAV9WEVGEMMVS0"}`
+	if want != got.body {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got.body)
+	}
+}
+
+func TestWebhook_Notify_WhenNot2xx_ReturnsError(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	hook := notify.Webhook{URL: receiver.URL + "/hook?token=t0ken"}
+	err := hook.Notify(context.Background(), notification)
+	if err == nil {
+		t.Fatal("\nWant: error\n Got: nil\n")
+	}
+
+	want := "POST " + receiver.URL + " returned 503 try later"
+	if got := err.Error(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+	if strings.Contains(hook.String(), "t0ken") {
+		t.Errorf("\nWant: no token\n Got: %s\n", hook.String())
+	}
+}
@@ -0,0 +1,94 @@
+package mockingbird_test
+
+import (
+	"testing"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+func TestNotifyFilter_Match(t *testing.T) {
+	var (
+		successful mockingbird.State = mockingbird.SUCCESSFUL
+		failed     mockingbird.State = mockingbird.FAILED
+		timedOut   mockingbird.State = mockingbird.TIMED_OUT
+	)
+
+	testCases := []struct {
+		state, previous mockingbird.State
+		wantEvent       string
+		wantOutcome     string
+		want            map[mockingbird.NotifyFilter]bool
+	}{
+		{
+			state: failed, previous: successful, wantEvent: "failure", wantOutcome: "failed",
+			want: map[mockingbird.NotifyFilter]bool{
+				mockingbird.FAILURES: true, mockingbird.RECOVERIES: false, mockingbird.ALERTS: true, mockingbird.ALL_RUNS: true,
+			},
+		},
+		{
+			state: timedOut, previous: "", wantEvent: "failure", wantOutcome: "timed out",
+			want: map[mockingbird.NotifyFilter]bool{
+				mockingbird.FAILURES: true, mockingbird.RECOVERIES: false, mockingbird.ALERTS: true, mockingbird.ALL_RUNS: true,
+			},
+		},
+		{
+			state: successful, previous: timedOut, wantEvent: "recovery", wantOutcome: "recovered",
+			want: map[mockingbird.NotifyFilter]bool{
+				mockingbird.FAILURES: false, mockingbird.RECOVERIES: true, mockingbird.ALERTS: true, mockingbird.ALL_RUNS: true,
+			},
+		},
+		{
+			state: successful, previous: successful, wantEvent: "run", wantOutcome: "passed",
+			want: map[mockingbird.NotifyFilter]bool{
+				mockingbird.FAILURES: false, mockingbird.RECOVERIES: false, mockingbird.ALERTS: false, mockingbird.ALL_RUNS: true,
+			},
+		},
+		{
+			state: failed, previous: timedOut, wantEvent: "failure", wantOutcome: "failed again",
+			want: map[mockingbird.NotifyFilter]bool{
+				mockingbird.FAILURES: true, mockingbird.RECOVERIES: false, mockingbird.ALERTS: true, mockingbird.ALL_RUNS: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		n := mockingbird.Notification{Result: mockingbird.TestResult{State: tc.state}, Previous: tc.previous}
+		if got := n.Event(); tc.wantEvent != got {
+			t.Errorf("%s after %q\nWant: %s\n Got: %s\n", tc.state, tc.previous, tc.wantEvent, got)
+		}
+		if got := n.Outcome(); tc.wantOutcome != got {
+			t.Errorf("%s after %q\nWant: %s\n Got: %s\n", tc.state, tc.previous, tc.wantOutcome, got)
+		}
+		for filter, want := range tc.want {
+			if got := filter.Match(n); want != got {
+				t.Errorf("%s after %q: %s\nWant: %t\n Got: %t\n", tc.state, tc.previous, filter, want, got)
+			}
+		}
+	}
+}
+
+func TestSubscription_Match_WhenItHasTestSuites_MatchesThemOnly(t *testing.T) {
+	sub := mockingbird.Subscription{Filter: mockingbird.ALL_RUNS, TestSuites: []mockingbird.TestSuite{"all:test"}}
+
+	testCases := []struct {
+		suite mockingbird.TestSuite
+		want  bool
+	}{
+		{suite: "all:test", want: true},
+		{suite: "google:test", want: false},
+	}
+	for _, tc := range testCases {
+		n := mockingbird.Notification{Result: mockingbird.TestResult{TestSuite: tc.suite, State: mockingbird.FAILED}}
+		if got := sub.Match(n); tc.want != got {
+			t.Errorf("%s\nWant: %t\n Got: %t\n", tc.suite, tc.want, got)
+		}
+	}
+}
+
+func TestParseNotifyFilter_WhenInvalid_ReturnsError(t *testing.T) {
+	for _, s := range []string{"", "failure", "ALL"} {
+		if _, err := mockingbird.ParseNotifyFilter(s); err == nil {
+			t.Errorf("%q\nWant: error\n Got: nil\n", s)
+		}
+	}
+}
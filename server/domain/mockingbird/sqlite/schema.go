@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// migrations are applied in order; never edit a released migration,
+// append a new one instead.
+var migrations = []string{
+	// 1: runs, logs, per-suite aggregates and stats
+	`
+	CREATE TABLE runs (
+		id         TEXT    PRIMARY KEY,
+		suite      TEXT    NOT NULL,
+		status     TEXT    NOT NULL,
+		state      TEXT    NOT NULL,
+		start_time INTEGER NOT NULL DEFAULT 0, -- unix nanoseconds
+		run_time   INTEGER NOT NULL DEFAULT 0, -- nanoseconds
+		doc        TEXT    NOT NULL            -- mockingbird.TestResult as JSON, without the log
+	);
+	CREATE INDEX runs_suite_idx      ON runs (suite, id);
+	CREATE INDEX runs_state_idx      ON runs (state, id);
+	CREATE INDEX runs_start_time_idx ON runs (start_time);
+
+	CREATE TABLE logs (
+		run_id TEXT PRIMARY KEY REFERENCES runs (id) ON DELETE CASCADE,
+		log    TEXT NOT NULL
+	);
+
+	CREATE TABLE suite_stats (
+		suite           TEXT    PRIMARY KEY,
+		run_counter     INTEGER NOT NULL DEFAULT 0,
+		success_counter INTEGER NOT NULL DEFAULT 0,
+		last_id         TEXT    NOT NULL DEFAULT '',
+		last_state      TEXT    NOT NULL DEFAULT '',
+		last_run_time   INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE stats (
+		id  INTEGER PRIMARY KEY CHECK (id = 1),
+		doc TEXT    NOT NULL
+	);
+	`,
+
+	// 2: queued and running test results, in the order they were enqueued
+	`
+	CREATE TABLE queue (
+		seq    INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id TEXT    NOT NULL UNIQUE REFERENCES runs (id) ON DELETE CASCADE
+	);
+	`,
+
+	// 3: the per-suite aggregates are served from the stats document
+	`
+	DROP TABLE suite_stats;
+	`,
+}
+
+// migrate creates the schema_migrations table and applies all
+// migrations that are not yet applied, each in its own transaction
+func migrate(db *sql.DB) error {
+	const create = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	if _, err := db.Exec(create); err != nil {
+		return errors.Wrap(err, "create schema_migrations failed")
+	}
+
+	var version int
+	const current = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	if err := db.QueryRow(current).Scan(&version); err != nil {
+		return errors.Wrap(err, "select schema version failed")
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if err := apply(db, i+1, migrations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(db *sql.DB, version int, stmt string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "db.Begin() failed")
+	}
+
+	if _, err := tx.Exec(stmt); err != nil {
+		_ = tx.Rollback()
+		return errors.Wrapf(err, "migration %d failed", version)
+	}
+
+	const insert = `INSERT INTO schema_migrations (version) VALUES (?)`
+	if _, err := tx.Exec(insert, version); err != nil {
+		_ = tx.Rollback()
+		return errors.Wrapf(err, "record migration %d failed", version)
+	}
+
+	return errors.Wrapf(tx.Commit(), "commit migration %d failed", version)
+}
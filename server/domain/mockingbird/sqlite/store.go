@@ -0,0 +1,307 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	// registers the sqlite3 database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/pkg/errs"
+)
+
+const defaultPageSize = 10
+
+// Store implements the mockingbird.Store interface on top of SQLite
+//
+// Note:
+//
+//        ListTestResults pages with a keyset cursor on the run ID (a
+//        ULID), so a page token stays valid across restarts and when
+//        other test results are added or deleted.
+//
+type Store struct {
+	db       *sql.DB
+	PageSize int
+}
+
+// Verifies that *Store implements mockingbird.Store interface
+var _ mockingbird.Store = &Store{}
+
+// Open opens, creates or migrates the database at path
+//
+// Usage:
+//
+//         s, err := sqlite.Open("mockingbird.db")
+//         if err != nil {
+//             return err
+//         }
+//         defer s.Close()
+//
+func Open(path string) (*Store, error) {
+	// escaped, so a ? or # in the path does not start the query of the URI
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000", url.PathEscape(path))
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sql.Open(%s) failed", path)
+	}
+
+	// SQLite allows one writer at a time
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "migrate(%s) failed", path)
+	}
+
+	return &Store{db: db, PageSize: defaultPageSize}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+//
+// Test results
+//
+
+// SaveTestResult inserts or updates the test result and its log
+func (s *Store) SaveTestResult(tr mockingbird.TestResult) error {
+	log := tr.Log
+	tr.Log = ""
+	doc, err := json.Marshal(tr)
+	if err != nil {
+		return errors.Wrapf(err, "json.Marshal(test result %s) failed", tr.ID)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "s.db.Begin() failed")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	const upsertRun = `
+	INSERT INTO runs (id, suite, status, state, start_time, run_time, doc)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (id) DO UPDATE SET
+		suite = excluded.suite,
+		status = excluded.status,
+		state = excluded.state,
+		start_time = excluded.start_time,
+		run_time = excluded.run_time,
+		doc = excluded.doc`
+	_, err = tx.Exec(upsertRun, tr.ID, tr.TestSuite, tr.Status, tr.State,
+		unixNano(tr), int64(tr.RunTime), string(doc))
+	if err != nil {
+		return errors.Wrapf(err, "upsert run %s failed", tr.ID)
+	}
+
+	if log != "" {
+		const upsertLog = `
+		INSERT INTO logs (run_id, log) VALUES (?, ?)
+		ON CONFLICT (run_id) DO UPDATE SET log = excluded.log`
+		if _, err := tx.Exec(upsertLog, tr.ID, log); err != nil {
+			return errors.Wrapf(err, "upsert log %s failed", tr.ID)
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "tx.Commit() failed")
+}
+
+// GetTestResult returns the test result, including its log, for the given id
+func (s *Store) GetTestResult(id mockingbird.ULID) (mockingbird.TestResult, error) {
+	tr := mockingbird.TestResult{}
+
+	var doc string
+	var log sql.NullString
+	const query = `
+	SELECT runs.doc, logs.log
+	FROM runs LEFT JOIN logs ON logs.run_id = runs.id
+	WHERE runs.id = ?`
+	err := s.db.QueryRow(query, id).Scan(&doc, &log)
+	if err == sql.ErrNoRows {
+		msg := fmt.Sprintf("test result %s not found", id)
+		return tr, errs.NotFound(msg)
+	}
+	if err != nil {
+		return tr, errors.Wrapf(err, "select run %s failed", id)
+	}
+
+	if err := json.Unmarshal([]byte(doc), &tr); err != nil {
+		return tr, errors.Wrapf(err, "json.Unmarshal(run %s) failed", id)
+	}
+	tr.Log = log.String
+
+	return tr, nil
+}
+
+// ListTestResults returns a page of the test results that match the query, newest first
+//
+// Note:
+//
+//        The page token is the ID of the last test result on the
+//        previous page. Logs are not included.
+//
+func (s *Store) ListTestResults(q mockingbird.ListTestsQuery) (*mockingbird.TestResults, error) {
+	size := q.Size(s.pageSize())
+
+	where := []string{"1 = 1"}
+	var args []interface{}
+	filter := func(cond string, arg interface{}) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+
+	if q.PageToken != "" {
+		filter("id < ?", q.PageToken)
+	}
+	if q.TestSuite != "" {
+		filter("suite = ?", string(q.TestSuite))
+	}
+	if q.State != "" {
+		filter("state = ?", string(q.State))
+	}
+	if q.Status != "" {
+		filter("status = ?", string(q.Status))
+	}
+	if !q.StartedAfter.IsZero() {
+		filter("start_time >= ?", q.StartedAfter.UnixNano())
+	}
+	if !q.StartedBefore.IsZero() {
+		filter("start_time < ?", q.StartedBefore.UnixNano())
+	}
+	if q.Text != "" {
+		filter("EXISTS (SELECT 1 FROM logs WHERE run_id = runs.id AND instr(lower(log), lower(?)) > 0)", q.Text)
+	}
+
+	query := fmt.Sprintf(`
+	SELECT doc FROM runs
+	WHERE %s
+	ORDER BY id DESC
+	LIMIT ?`, strings.Join(where, " AND "))
+	rows, err := s.db.Query(query, append(args, size+1)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "select runs failed")
+	}
+	defer func() { _ = rows.Close() }()
+
+	trs := []mockingbird.TestResult{}
+	for rows.Next() {
+		var doc string
+		if err := rows.Scan(&doc); err != nil {
+			return nil, errors.Wrap(err, "rows.Scan() failed")
+		}
+
+		tr := mockingbird.TestResult{}
+		if err := json.Unmarshal([]byte(doc), &tr); err != nil {
+			return nil, errors.Wrap(err, "json.Unmarshal(run) failed")
+		}
+		trs = append(trs, tr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "rows.Err() failed")
+	}
+
+	nextPageToken := ""
+	if len(trs) > size {
+		trs = trs[:size]
+		nextPageToken = string(trs[size-1].ID)
+	}
+
+	return &mockingbird.TestResults{NextPageToken: nextPageToken, TestResults: trs}, nil
+}
+
+//
+// Stats
+//
+
+// SaveStats replaces the stats
+func (s *Store) SaveStats(stats mockingbird.Stats) error {
+	doc, err := json.Marshal(stats)
+	if err != nil {
+		return errors.Wrap(err, "json.Marshal(stats) failed")
+	}
+
+	const upsert = `
+	INSERT INTO stats (id, doc) VALUES (1, ?)
+	ON CONFLICT (id) DO UPDATE SET doc = excluded.doc`
+	_, err = s.db.Exec(upsert, string(doc))
+	return errors.Wrap(err, "upsert stats failed")
+}
+
+// GetStats returns the stats
+func (s *Store) GetStats() (mockingbird.Stats, error) {
+	stats := mockingbird.Stats{}
+
+	var doc string
+	err := s.db.QueryRow(`SELECT doc FROM stats WHERE id = 1`).Scan(&doc)
+	if err == sql.ErrNoRows {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, errors.Wrap(err, "select stats failed")
+	}
+
+	err = json.Unmarshal([]byte(doc), &stats)
+	return stats, errors.Wrap(err, "json.Unmarshal(stats) failed")
+}
+
+//
+// Queue
+//
+
+// Enqueue appends id to the queue
+func (s *Store) Enqueue(id mockingbird.ULID) error {
+	_, err := s.db.Exec(`INSERT OR IGNORE INTO queue (run_id) VALUES (?)`, id)
+	return errors.Wrapf(err, "insert queue %s failed", id)
+}
+
+// Dequeue removes id from the queue
+func (s *Store) Dequeue(id mockingbird.ULID) error {
+	_, err := s.db.Exec(`DELETE FROM queue WHERE run_id = ?`, id)
+	return errors.Wrapf(err, "delete queue %s failed", id)
+}
+
+// Queue returns the queued IDs, oldest first
+func (s *Store) Queue() ([]mockingbird.ULID, error) {
+	rows, err := s.db.Query(`SELECT run_id FROM queue ORDER BY seq`)
+	if err != nil {
+		return nil, errors.Wrap(err, "select queue failed")
+	}
+	defer func() { _ = rows.Close() }()
+
+	q := []mockingbird.ULID{}
+	for rows.Next() {
+		var id mockingbird.ULID
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err, "rows.Scan() failed")
+		}
+		q = append(q, id)
+	}
+
+	return q, errors.Wrap(rows.Err(), "rows.Err() failed")
+}
+
+//
+// PRIVATE
+//
+
+func (s *Store) pageSize() int {
+	if s.PageSize < 1 {
+		return defaultPageSize
+	}
+	return s.PageSize
+}
+
+func unixNano(tr mockingbird.TestResult) int64 {
+	if tr.StartTime.IsZero() {
+		return 0
+	}
+	return tr.StartTime.UnixNano()
+}
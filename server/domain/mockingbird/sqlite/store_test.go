@@ -0,0 +1,268 @@
+package sqlite_test
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+	"github.com/unders/mockingbird/server/domain/mockingbird/sqlite"
+	"github.com/unders/mockingbird/server/pkg/errs"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestStore_SaveTestResult_StoresLog(t *testing.T) {
+	dir, s := openStore(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	defer func() { _ = s.Close() }()
+
+	id := newID(t, time.Now())
+	tr := mockingbird.TestResult{ID: id, TestSuite: "google:test", Status: mockingbird.QUEUED}
+	testdata.AssertNil(t, s.SaveTestResult(tr))
+
+	tr.Status = mockingbird.DONE
+	tr.State = mockingbird.SUCCESSFUL
+	tr.Log = "--- PASS: TestSearch"
+	testdata.AssertNil(t, s.SaveTestResult(tr))
+
+	got, err := s.GetTestResult(id)
+	testdata.AssertNil(t, err)
+	if tr.Log != got.Log {
+		t.Errorf("\nWant: %s\n Got: %s\n", tr.Log, got.Log)
+	}
+	if tr.State != got.State {
+		t.Errorf("\nWant: %s\n Got: %s\n", tr.State, got.State)
+	}
+
+	_, err = s.GetTestResult(newID(t, time.Now()))
+	testdata.AssertTrue(t, errs.IsNotFound(err))
+}
+
+func TestOpen_WhenThePathHasURICharacters_OpensTheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	testdata.AssertNil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "mocking bird?#%.db")
+	s, err := sqlite.Open(path)
+	testdata.AssertNil(t, err)
+	testdata.AssertNil(t, s.Close())
+
+	_, err = os.Stat(path)
+	testdata.AssertNil(t, err)
+}
+
+func TestOpen_WhenTheSchemaIsVersion2_DropsTheSuiteStatsTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mockingbird-sqlite-")
+	testdata.AssertNil(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "mockingbird.db")
+	db, err := sql.Open("sqlite3", path)
+	testdata.AssertNil(t, err)
+	defer func() { _ = db.Close() }()
+
+	// the tables of migration 2 that migration 3 changes
+	_, err = db.Exec(`
+	CREATE TABLE schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO schema_migrations (version) VALUES (1), (2);
+	CREATE TABLE suite_stats (suite TEXT PRIMARY KEY);
+	INSERT INTO suite_stats (suite) VALUES ('google:test');
+	`)
+	testdata.AssertNil(t, err)
+
+	s, err := sqlite.Open(path)
+	testdata.AssertNil(t, err)
+	testdata.AssertNil(t, s.Close())
+
+	var version int
+	testdata.AssertNil(t, db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version))
+	if 3 != version {
+		t.Errorf("\nWant: %d\n Got: %d\n", 3, version)
+	}
+
+	var tables int
+	const query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'suite_stats'`
+	testdata.AssertNil(t, db.QueryRow(query).Scan(&tables))
+	if 0 != tables {
+		t.Errorf("\nWant: %d\n Got: %d\n", 0, tables)
+	}
+}
+
+func TestStore_ListTestResults_PageTokenIsStableAcrossRestarts(t *testing.T) {
+	dir, s := openStore(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	s.PageSize = 2
+
+	start := time.Now()
+	var ids []mockingbird.ULID
+	for i := 0; i < 5; i++ {
+		id := newID(t, start.Add(time.Duration(i)*time.Second))
+		ids = append(ids, id)
+		tr := mockingbird.TestResult{ID: id, TestSuite: "all:test", Log: "log"}
+		testdata.AssertNil(t, s.SaveTestResult(tr))
+	}
+
+	page, err := s.ListTestResults(mockingbird.ListTestsQuery{})
+	testdata.AssertNil(t, err)
+	assertIDs(t, []mockingbird.ULID{ids[4], ids[3]}, page.TestResults)
+	testdata.AssertNil(t, s.Close())
+
+	// delete a test result on the next page, then reopen the database
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "mockingbird.db"))
+	testdata.AssertNil(t, err)
+	_, err = db.Exec(`DELETE FROM runs WHERE id = ?`, ids[2])
+	testdata.AssertNil(t, err)
+	testdata.AssertNil(t, db.Close())
+
+	s, err = sqlite.Open(filepath.Join(dir, "mockingbird.db"))
+	testdata.AssertNil(t, err)
+	defer func() { _ = s.Close() }()
+	s.PageSize = 2
+
+	page, err = s.ListTestResults(mockingbird.ListTestsQuery{PageToken: page.NextPageToken})
+	testdata.AssertNil(t, err)
+	assertIDs(t, []mockingbird.ULID{ids[1], ids[0]}, page.TestResults)
+	if page.NextPageToken != "" {
+		t.Errorf("\nWant: \n Got: %s\n", page.NextPageToken)
+	}
+}
+
+func TestStore_ListTestResults_Filters(t *testing.T) {
+	dir, s := openStore(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	defer func() { _ = s.Close() }()
+
+	start := time.Date(2019, 1, 28, 12, 0, 0, 0, time.UTC)
+	var ids []mockingbird.ULID
+	for i := 0; i < 6; i++ {
+		startTime := start.AddDate(0, 0, i)
+		id := newID(t, startTime)
+		ids = append(ids, id)
+
+		tr := mockingbird.TestResult{ID: id, TestSuite: "google:test", State: mockingbird.SUCCESSFUL, StartTime: startTime, Log: "PASS"}
+		if i%2 == 0 {
+			tr.State = mockingbird.FAILED
+			tr.Log = "--- FAIL: TestSearch"
+		}
+		if i == 4 {
+			tr.TestSuite = mockingbird.FullTestSuite
+		}
+		testdata.AssertNil(t, s.SaveTestResult(tr))
+	}
+
+	testCases := []struct {
+		q    mockingbird.ListTestsQuery
+		want []mockingbird.ULID
+	}{
+		{
+			q:    mockingbird.ListTestsQuery{TestSuite: "google:test", State: mockingbird.FAILED},
+			want: []mockingbird.ULID{ids[2], ids[0]},
+		},
+		{
+			q:    mockingbird.ListTestsQuery{Text: "fail: testsearch"},
+			want: []mockingbird.ULID{ids[4], ids[2], ids[0]},
+		},
+		{
+			q: mockingbird.ListTestsQuery{
+				StartedAfter:  time.Date(2019, 1, 29, 0, 0, 0, 0, time.UTC),
+				StartedBefore: time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: []mockingbird.ULID{ids[3], ids[2], ids[1]},
+		},
+		{
+			q:    mockingbird.ListTestsQuery{PageSize: 1, State: mockingbird.SUCCESSFUL, PageToken: string(ids[5])},
+			want: []mockingbird.ULID{ids[3]},
+		},
+	}
+
+	for _, tc := range testCases {
+		page, err := s.ListTestResults(tc.q)
+		testdata.AssertNil(t, err)
+		assertIDs(t, tc.want, page.TestResults)
+	}
+}
+
+func TestStore_Stats(t *testing.T) {
+	dir, s := openStore(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	defer func() { _ = s.Close() }()
+
+	stats, err := s.GetStats()
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, stats.TestSuiteRunCounter == 0)
+
+	stats.TestSuiteRunCounter = 7
+	testdata.AssertNil(t, s.SaveStats(stats))
+
+	stats, err = s.GetStats()
+	testdata.AssertNil(t, err)
+	testdata.AssertTrue(t, stats.TestSuiteRunCounter == 7)
+}
+
+func TestStore_Queue_KeepsEnqueueOrder(t *testing.T) {
+	dir, s := openStore(t)
+	defer func() { _ = os.RemoveAll(dir) }()
+	defer func() { _ = s.Close() }()
+
+	start := time.Now()
+	a, b, c := newID(t, start.Add(2*time.Second)), newID(t, start), newID(t, start.Add(time.Second))
+	for _, id := range []mockingbird.ULID{a, b, c} {
+		testdata.AssertNil(t, s.SaveTestResult(mockingbird.TestResult{ID: id}))
+		testdata.AssertNil(t, s.Enqueue(id))
+	}
+	testdata.AssertNil(t, s.Enqueue(a))
+	testdata.AssertNil(t, s.Dequeue(b))
+
+	q, err := s.Queue()
+	testdata.AssertNil(t, err)
+	if len(q) != 2 || q[0] != a || q[1] != c {
+		t.Errorf("\nWant: [%s %s]\n Got: %v\n", a, c, q)
+	}
+}
+
+func openStore(t *testing.T) (string, *sqlite.Store) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "mockingbird-sqlite-")
+	testdata.AssertNil(t, err)
+
+	s, err := sqlite.Open(filepath.Join(dir, "mockingbird.db"))
+	testdata.AssertNil(t, err)
+
+	return dir, s
+}
+
+func assertIDs(t *testing.T, want []mockingbird.ULID, trs []mockingbird.TestResult) {
+	t.Helper()
+
+	var got []mockingbird.ULID
+	for _, tr := range trs {
+		got = append(got, tr.ID)
+	}
+	if len(want) != len(got) {
+		t.Fatalf("\nWant: %v\n Got: %v\n", want, got)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Errorf("\nWant: %v\n Got: %v\n", want, got)
+		}
+	}
+}
+
+func newID(t *testing.T, now time.Time) mockingbird.ULID {
+	t.Helper()
+
+	id, err := ulid.New(ulid.Timestamp(now), rand.Reader)
+	testdata.AssertNil(t, err)
+	return mockingbird.ULID(id.String())
+}
@@ -0,0 +1,47 @@
+package mockingbird
+
+// StoreKind defines the different kinds of result stores
+type StoreKind string
+
+// Specifies the result stores
+const (
+	MemoryStore StoreKind = "memory"
+	FileStore             = "file"
+	S3Store               = "s3"
+	SQLiteStore           = "sqlite"
+)
+
+// Store defines the interface for persisting test results and stats
+//
+// Note:
+//
+//        ListTestResults returns the newest test results that match the
+//        query first; pass TestResults.NextPageToken, with the same
+//        filters, to fetch the next page. A page can have fewer test
+//        results than the page size and still have a next page; the
+//        last page has no NextPageToken.
+//
+//        Queue returns the IDs of the queued and running test results
+//        in the order they were enqueued; it survives a restart.
+//
+type Store interface {
+	//
+	// Test results
+	//
+	SaveTestResult(tr TestResult) error
+	GetTestResult(id ULID) (TestResult, error)
+	ListTestResults(q ListTestsQuery) (*TestResults, error)
+
+	//
+	// Stats
+	//
+	SaveStats(s Stats) error
+	GetStats() (Stats, error)
+
+	//
+	// Queue
+	//
+	Enqueue(id ULID) error
+	Dequeue(id ULID) error
+	Queue() ([]ULID, error)
+}
@@ -0,0 +1,41 @@
+package mockingbird
+
+import "time"
+
+// CaseStatus is the outcome of a test package or test case
+type CaseStatus string
+
+// The possible statuses a test package or test case can have
+const (
+	RUN  CaseStatus = "run" // started, but it never finished
+	PASS            = "pass"
+	FAIL            = "fail"
+	SKIP            = "skip"
+)
+
+// TestPackage contains the test cases of a Go package
+type TestPackage struct {
+	Name    string
+	Status  CaseStatus
+	Elapsed time.Duration
+	Output  string // output not written by a test case, e.g: ok, FAIL and build errors
+
+	Tests []TestCase
+}
+
+// TestCase contains the result of a test and its subtests
+type TestCase struct {
+	Name    string // the full name, e.g: TestSearch/by_name
+	Status  CaseStatus
+	Elapsed time.Duration
+	Output  string
+
+	Subtests []TestCase
+}
+
+func (p TestPackage) IsFailed() bool {
+	return p.Status == FAIL
+}
+func (c TestCase) IsFailed() bool {
+	return c.Status == FAIL
+}
@@ -0,0 +1,112 @@
+package mockingbird
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// day is the unit of a window in days, e.g: 7d
+const day = 24 * time.Hour
+
+// DefaultWindows are the rolling windows of the dashboard
+var DefaultWindows = []Window{{Duration: day}, {Duration: 7 * day}, {Duration: 30 * day}, {Runs: 50}}
+
+// Window is a rolling window of the test history: the runs that started in
+// the last Duration, or the last Runs runs of each test suite
+type Window struct {
+	Duration time.Duration
+	Runs     int
+}
+
+// String returns the window as it is parsed, e.g: 24h, 7d or 50runs
+func (w Window) String() string {
+	switch {
+	case w.Runs > 0:
+		return fmt.Sprintf("%druns", w.Runs)
+	case w.Duration > day && w.Duration%day == 0:
+		return fmt.Sprintf("%dd", w.Duration/day)
+	default:
+		// 24h0m0s => 24h, 1h30m0s => 1h30m
+		s := w.Duration.String()
+		if strings.HasSuffix(s, "m0s") {
+			s = strings.TrimSuffix(s, "0s")
+		}
+		if strings.HasSuffix(s, "h0m") {
+			s = strings.TrimSuffix(s, "0m")
+		}
+		return s
+	}
+}
+
+// ParseWindow returns the window of a duration, a number of days or a number of runs
+//
+// Usage:
+//
+//        mockingbird.ParseWindow("24h")
+//        mockingbird.ParseWindow("7d")
+//        mockingbird.ParseWindow("50runs")
+//
+func ParseWindow(s string) (Window, error) {
+	w := Window{}
+
+	var err error
+	switch {
+	case strings.HasSuffix(s, "runs"):
+		w.Runs, err = strconv.Atoi(strings.TrimSuffix(s, "runs"))
+	case strings.HasSuffix(s, "d"):
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		w.Duration = time.Duration(days) * day
+	default:
+		w.Duration, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return w, errors.Wrapf(err, "invalid window %s", s)
+	}
+	if w.Runs < 0 || w.Duration < 0 || w == (Window{}) {
+		return w, errors.Errorf("invalid window %s: must be positive", s)
+	}
+
+	return w, nil
+}
+
+// WindowStats shows the stats of the test suites in a rolling window
+type WindowStats struct {
+	Window Window
+	Suites []WindowSuiteStats // sorted by test suite name
+}
+
+// WindowSuiteStats shows the stats of one test suite in a rolling window
+//
+// Note:
+//
+//        Trend splits the window into equal parts, oldest first; a part of
+//        a duration window without runs has a zero RunCounter.
+//
+type WindowSuiteStats struct {
+	TestSuite TestSuite
+
+	RunCounter     float64
+	SuccessCounter float64
+	SuccessRate    float64
+
+	P50RunTime time.Duration
+	P90RunTime time.Duration
+	MaxRunTime time.Duration
+
+	Trend []TrendPoint
+}
+
+// TrendPoint shows the stats of a part of a rolling window
+type TrendPoint struct {
+	StartTime time.Time
+
+	RunCounter  float64
+	SuccessRate float64
+
+	MeanRunTime time.Duration
+}
@@ -0,0 +1,42 @@
+package mockingbird_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/domain/mockingbird"
+)
+
+func TestParseWindow_ReturnsWindow(t *testing.T) {
+	testCases := []struct {
+		s       string
+		want    mockingbird.Window
+		wantStr string
+	}{
+		{s: "24h", want: mockingbird.Window{Duration: 24 * time.Hour}, wantStr: "24h"},
+		{s: "90m", want: mockingbird.Window{Duration: 90 * time.Minute}, wantStr: "1h30m"},
+		{s: "7d", want: mockingbird.Window{Duration: 7 * 24 * time.Hour}, wantStr: "7d"},
+		{s: "50runs", want: mockingbird.Window{Runs: 50}, wantStr: "50runs"},
+	}
+
+	for _, tc := range testCases {
+		got, err := mockingbird.ParseWindow(tc.s)
+		if err != nil {
+			t.Fatalf("%s\nWant: nil\n Got: %s\n", tc.s, err)
+		}
+		if tc.want != got {
+			t.Errorf("%s\nWant: %+v\n Got: %+v\n", tc.s, tc.want, got)
+		}
+		if s := got.String(); tc.wantStr != s {
+			t.Errorf("\nWant: %s\n Got: %s\n", tc.wantStr, s)
+		}
+	}
+}
+
+func TestParseWindow_WhenInvalid_ReturnsError(t *testing.T) {
+	for _, s := range []string{"", "0d", "-1h", "xruns", "week"} {
+		if _, err := mockingbird.ParseWindow(s); err == nil {
+			t.Errorf("%q\nWant: error\n Got: nil\n", s)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package auth authenticates users by the bcrypt hashes of an htpasswd file
+// and rate limits failed logins:
+//
+//      alice:$2a$10$RF2nbaSui818aejC3YHbPOyycfOzmtUEtnchMRNu.eX6WQ0.osyre
+//
+package auth
+
+import "context"
+
+// Authenticator authenticates users by their username and password
+type Authenticator interface {
+	Authenticate(username, password string) bool
+}
+
+// WithUser returns a copy of ctx with the authenticated username
+func WithUser(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, userKey{}, username)
+}
+
+// UserFrom returns the authenticated username in ctx; empty when it has none
+func UserFrom(ctx context.Context) string {
+	username, _ := ctx.Value(userKey{}).(string)
+	return username
+}
+
+//
+// PRIVATE
+//
+
+type userKey struct{}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Verifies that HtpasswdFile implements Authenticator interface
+var _ Authenticator = &HtpasswdFile{}
+
+// HtpasswdFile authenticates the users of an htpasswd file with bcrypt
+// hashes, e.g: created with htpasswd -B
+//
+// Note:
+//
+//        The file is read again when its modification time or size has
+//        changed; the users that were read last are kept when it can not
+//        be read or parsed, e.g: while it is written, see Err.
+//
+type HtpasswdFile struct {
+	path string
+
+	mu      sync.Mutex
+	users   map[string][]byte
+	modTime time.Time
+	size    int64
+	err     error
+}
+
+// NewHtpasswdFile returns the HtpasswdFile of the file at path
+func NewHtpasswdFile(path string) (*HtpasswdFile, error) {
+	f := &HtpasswdFile{path: path}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Authenticate implements Authenticator
+func (f *HtpasswdFile) Authenticate(username, password string) bool {
+	f.mu.Lock()
+	f.err = f.reload()
+	hash, ok := f.users[username]
+	f.mu.Unlock()
+
+	if !ok {
+		// the time of a known user, so the usernames can not be guessed
+		_ = bcrypt.CompareHashAndPassword(unknownUser, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// Err returns the error of the last reload of the file; nil when the
+// users are up to date
+func (f *HtpasswdFile) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// ParseHtpasswd returns the bcrypt hashes by username of the user:hash
+// lines in r; blank lines and lines that start with # are skipped
+func ParseHtpasswd(r io.Reader) (map[string][]byte, error) {
+	users := map[string][]byte{}
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d is not a user:hash pair", n)
+		}
+		username, hash := line[:i], line[i+1:]
+		if !isBcrypt(hash) {
+			return nil, fmt.Errorf("line %d of user %s must have a bcrypt hash", n, username)
+		}
+		if _, ok := users[username]; ok {
+			return nil, fmt.Errorf("line %d has the duplicate user %s", n, username)
+		}
+		users[username] = []byte(hash)
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "s.Scan() failed")
+	}
+
+	return users, nil
+}
+
+//
+// PRIVATE
+//
+
+// unknownUser is the bcrypt hash that a password of an unknown user is compared to
+var unknownUser = []byte("$2a$10$Bk5g2I8Ow26mwwEPVZS6vuF4kNA3l28QBRXxLIiMG0eyz/va5NXoS")
+
+// reload reads the file if it has changed; it is called with the lock held
+func (f *HtpasswdFile) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return errors.Wrapf(err, "os.Stat(%s) failed", f.path)
+	}
+	if f.users != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return errors.Wrapf(err, "os.Open(%s) failed", f.path)
+	}
+	defer file.Close()
+
+	users, err := ParseHtpasswd(file)
+	if err != nil {
+		return errors.Wrapf(err, "ParseHtpasswd(%s) failed", f.path)
+	}
+
+	f.users, f.modTime, f.size = users, info.ModTime(), info.Size()
+	return nil
+}
+
+// isBcrypt returns true if hash has the length and a version prefix of a bcrypt hash
+func isBcrypt(hash string) bool {
+	if len(hash) != 60 {
+		return false
+	}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package auth_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/auth"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+const (
+	alice = "alice:$2a$04$QY941Y6QhxW8f6A1B7v.KOnmpPgSiHwMet.T5VTH6OpDSunvSwHHa" // password
+	bob   = "bob:$2y$04$iPeWi02DEaHOaXL6MDV0SuuEgoc9UDvUqCRZEdSxoomNciq/ObGDC"   // secret
+)
+
+func TestParseHtpasswd(t *testing.T) {
+	users, err := auth.ParseHtpasswd(strings.NewReader("# users\n" + alice + "\n\n" + bob + "\n"))
+	testdata.AssertNil(t, err)
+	if len(users) != 2 || users["alice"] == nil || users["bob"] == nil {
+		t.Errorf("\nWant: alice and bob\n Got: %v\n", users)
+	}
+
+	testCases := []struct {
+		file string
+		want string
+	}{
+		{file: "alice", want: "line 1 is not a user:hash pair"},
+		{file: ":$2a$04$QY941Y6QhxW8f6A1B7v.KOnmpPgSiHwMet.T5VTH6OpDSunvSwHHa", want: "line 1 is not a user:hash pair"},
+		{file: "alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", want: "line 1 of user alice must have a bcrypt hash"},
+		{file: alice + "\n" + alice, want: "line 2 has the duplicate user alice"},
+	}
+
+	for _, tc := range testCases {
+		_, err := auth.ParseHtpasswd(strings.NewReader(tc.file))
+		if err == nil || tc.want != err.Error() {
+			t.Errorf("%s\nWant: %s\n Got: %v\n", tc.file, tc.want, err)
+		}
+	}
+}
+
+func TestHtpasswdFile_Authenticate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	testdata.AssertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "htpasswd")
+	testdata.AssertNil(t, ioutil.WriteFile(path, []byte(alice+"\n"), 0600))
+
+	f, err := auth.NewHtpasswdFile(path)
+	testdata.AssertNil(t, err)
+
+	testCases := []struct {
+		username string
+		password string
+		want     bool
+	}{
+		{username: "alice", password: "password", want: true},
+		{username: "alice", password: "secret", want: false},
+		{username: "bob", password: "secret", want: false},
+		{username: "", password: "", want: false},
+	}
+	for _, tc := range testCases {
+		if got := f.Authenticate(tc.username, tc.password); tc.want != got {
+			t.Errorf("%s:%s\nWant: %t\n Got: %t\n", tc.username, tc.password, tc.want, got)
+		}
+	}
+
+	// reloads the file when it has changed
+	testdata.AssertNil(t, ioutil.WriteFile(path, []byte(bob+"\n"), 0600))
+	future := time.Now().Add(time.Minute)
+	testdata.AssertNil(t, os.Chtimes(path, future, future))
+	if !f.Authenticate("bob", "secret") || f.Authenticate("alice", "password") {
+		t.Errorf("\nWant: bob and not alice\n Got: %v\n", f.Err())
+	}
+
+	// keeps the users when the file can not be parsed
+	testdata.AssertNil(t, ioutil.WriteFile(path, []byte("bob\n"), 0600))
+	testdata.AssertNil(t, os.Chtimes(path, future.Add(time.Minute), future.Add(time.Minute)))
+	if !f.Authenticate("bob", "secret") {
+		t.Errorf("\nWant: bob\n Got: %v\n", f.Err())
+	}
+	testdata.AssertErr(t, f.Err())
+}
+
+func TestNewHtpasswdFile_WhenNoFile_ReturnsError(t *testing.T) {
+	_, err := auth.NewHtpasswdFile(filepath.Join(os.TempDir(), "no-such-htpasswd"))
+	testdata.AssertErr(t, err)
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter rate limits the failed logins per IP address
+//
+// Note:
+//
+//        An IP address is blocked when it has max failed logins within
+//        the window since its first one; it is unblocked when the window
+//        has passed. A successful login resets its failed logins.
+//
+type Limiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]failures // by IP address
+}
+
+// NewLimiter returns a Limiter of max failed logins per window
+func NewLimiter(max int, window time.Duration) *Limiter {
+	return &Limiter{max: max, window: window, now: time.Now, failures: map[string]failures{}}
+}
+
+// Allow returns true if the IP address may log in; otherwise it returns
+// how long until it may
+func (l *Limiter) Allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[ip]
+	if !ok || f.count < l.max {
+		return true, 0
+	}
+
+	wait := f.since.Add(l.window).Sub(l.now())
+	if wait <= 0 {
+		delete(l.failures, ip)
+		return true, 0
+	}
+	return false, wait
+}
+
+// Fail records a failed login of the IP address
+func (l *Limiter) Fail(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	f, ok := l.failures[ip]
+	if !ok || now.Sub(f.since) >= l.window {
+		f = failures{since: now}
+	}
+	f.count++
+	l.failures[ip] = f
+}
+
+// Reset forgets the failed logins of the IP address
+func (l *Limiter) Reset(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, ip)
+}
+
+//
+// PRIVATE
+//
+
+type failures struct {
+	count int
+	since time.Time
+}
+
+// prune forgets the failed logins of the windows that have passed, so the
+// map does not grow with each IP address that ever failed
+func (l *Limiter) prune(now time.Time) {
+	for ip, f := range l.failures {
+		if now.Sub(f.since) >= l.window {
+			delete(l.failures, ip)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_BlocksAnIPAfterMaxFailedLogins(t *testing.T) {
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := NewLimiter(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("10.0.0.1"); !ok {
+			t.Fatalf("attempt %d\nWant: allowed\n Got: blocked\n", i+1)
+		}
+		l.Fail("10.0.0.1")
+	}
+
+	ok, wait := l.Allow("10.0.0.1")
+	if ok || wait != time.Minute {
+		t.Errorf("\nWant: blocked for 1m0s\n Got: ok=%t wait=%s\n", ok, wait)
+	}
+	if ok, _ := l.Allow("10.0.0.2"); !ok {
+		t.Errorf("other IP\nWant: allowed\n Got: blocked\n")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, _ := l.Allow("10.0.0.1"); !ok {
+		t.Errorf("after the window\nWant: allowed\n Got: blocked\n")
+	}
+}
+
+func TestLimiter_Reset_ForgetsTheFailedLogins(t *testing.T) {
+	l := NewLimiter(2, time.Minute)
+	l.Fail("10.0.0.1")
+	l.Reset("10.0.0.1")
+	l.Fail("10.0.0.1")
+
+	if ok, _ := l.Allow("10.0.0.1"); !ok {
+		t.Errorf("\nWant: allowed\n Got: blocked\n")
+	}
+}
+
+func TestLimiter_Fail_PrunesThePassedWindows(t *testing.T) {
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := NewLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Fail("10.0.0.1")
+	now = now.Add(2 * time.Minute)
+	l.Fail("10.0.0.2")
+
+	if _, ok := l.failures["10.0.0.1"]; ok || len(l.failures) != 1 {
+		t.Errorf("\nWant: 10.0.0.2\n Got: %v\n", l.failures)
+	}
+}
@@ -0,0 +1,243 @@
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule returns the next time of a schedule
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// descriptors are the predefined schedules
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse returns the schedule of a cron expression
+//
+// Usage:
+//
+//        cron.Parse("*/5 * * * *")        // every 5 minutes
+//        cron.Parse("0 6-18 * * mon-fri") // every hour from 06:00 to 18:00 on weekdays
+//        cron.Parse("@hourly")
+//        cron.Parse("@every 90s")
+//
+// Note:
+//
+//        An expression has the five fields minute, hour, day of month,
+//        month and day of week. A field is *, a value, a range, a list
+//        or a step, e.g: 5, 1-5, 1,15 or */10. When both day fields are
+//        restricted, a time matches if either of them matches.
+//
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := descriptors[expr]; ok {
+		expr = d
+	}
+
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cron expression %q", expr)
+		}
+		if d < time.Second {
+			return nil, errors.Errorf("invalid cron expression %q: must be at least 1s", expr)
+		}
+		return every(d), nil
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("invalid cron expression %q: must have 5 fields", expr)
+	}
+
+	s := &spec{}
+	var err error
+	if s.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron expression %q", expr)
+	}
+	if s.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron expression %q", expr)
+	}
+	if s.dom, err = parseField(fields[2], doms); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron expression %q", expr)
+	}
+	if s.month, err = parseField(fields[3], months); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron expression %q", expr)
+	}
+	if s.dow, err = parseField(fields[4], dows); err != nil {
+		return nil, errors.Wrapf(err, "invalid cron expression %q", expr)
+	}
+	s.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+//
+// PRIVATE
+//
+
+// every is a schedule with a fixed interval
+type every time.Duration
+
+// Next returns t plus the interval, rounded down to the second
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e)).Truncate(time.Second)
+}
+
+// spec is a schedule of a five field cron expression; each field is a bit set
+type spec struct {
+	minute, hour, dom, month, dow uint64
+
+	// anyDay is true when the day of month or the day of week starts
+	// with *; then both must match, otherwise either
+	anyDay bool
+}
+
+// maxYears is how far Next searches for a matching time, e.g: for 0 0 30 2 *
+const maxYears = 5
+
+// Next returns the first time after t that matches the schedule; the zero time if none matches
+func (s *spec) Next(t time.Time) time.Time {
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.AddDate(maxYears, 0, 0)
+
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *spec) matchDay(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
+
+// bounds are the values a field can have; names are the lower case names of the values
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minutes = bounds{min: 0, max: 59}
+	hours   = bounds{min: 0, max: 23}
+	doms    = bounds{min: 1, max: 31}
+	months  = bounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also sunday
+	dows = bounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// parseField returns the bit set of a comma separated list of ranges with optional steps
+func parseField(field string, b bounds) (uint64, error) {
+	var set uint64
+	for _, r := range strings.Split(field, ",") {
+		bits, err := parseRange(r, b)
+		if err != nil {
+			return 0, err
+		}
+		set |= bits
+	}
+
+	// sunday is both 0 and 7
+	if b.max == 7 && has(set, 7) {
+		set |= 1
+	}
+	return set, nil
+}
+
+func parseRange(r string, b bounds) (uint64, error) {
+	step := 1
+	if i := strings.Index(r, "/"); i >= 0 {
+		var err error
+		if step, err = strconv.Atoi(r[i+1:]); err != nil || step < 1 {
+			return 0, errors.Errorf("invalid step in %q", r)
+		}
+		r = r[:i]
+	}
+
+	first, last := b.min, b.max
+	switch {
+	case r == "*":
+	case strings.Contains(r, "-"):
+		i := strings.Index(r, "-")
+		var err error
+		if first, err = parseValue(r[:i], b); err != nil {
+			return 0, err
+		}
+		if last, err = parseValue(r[i+1:], b); err != nil {
+			return 0, err
+		}
+	default:
+		v, err := parseValue(r, b)
+		if err != nil {
+			return 0, err
+		}
+		first, last = v, v
+		if step > 1 {
+			// 5/10 is 5-max/10
+			last = b.max
+		}
+	}
+	if first > last {
+		return 0, errors.Errorf("invalid range %q", r)
+	}
+
+	var set uint64
+	for v := first; v <= last; v += step {
+		set |= 1 << uint(v)
+	}
+	return set, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, errors.Errorf("%q must be a value from %d to %d", s, b.min, b.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,63 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/cron"
+)
+
+func TestParse_Next(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2019, 1, 30, 10, 7, 30, 0, time.UTC)
+
+	testCases := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "*/5 * * * *", want: time.Date(2019, 1, 30, 10, 10, 0, 0, time.UTC)},
+		{expr: "* * * * *", want: time.Date(2019, 1, 30, 10, 8, 0, 0, time.UTC)},
+		{expr: "7 * * * *", want: time.Date(2019, 1, 30, 11, 7, 0, 0, time.UTC)},
+		{expr: "0 6-18/4 * * *", want: time.Date(2019, 1, 30, 14, 0, 0, 0, time.UTC)},
+		{expr: "15,45 9 * * mon-fri", want: time.Date(2019, 1, 31, 9, 15, 0, 0, time.UTC)},
+		{expr: "0 0 * * sun", want: time.Date(2019, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", want: time.Date(2019, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * mon", want: time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 feb *", want: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "@daily", want: time.Date(2019, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{expr: "@hourly", want: time.Date(2019, 1, 30, 11, 0, 0, 0, time.UTC)},
+		{expr: "@every 90s", want: time.Date(2019, 1, 30, 10, 9, 0, 0, time.UTC)},
+		{expr: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		s, err := cron.Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("%s\nWant: nil\n Got: %s\n", tc.expr, err)
+		}
+		if got := s.Next(now); !tc.want.Equal(got) {
+			t.Errorf("%s\nWant: %s\n Got: %s\n", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestParse_WhenInvalid_ReturnsError(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"x * * * *",
+		"@every 10ms",
+		"@every soon",
+	} {
+		if _, err := cron.Parse(expr); err == nil {
+			t.Errorf("%q\nWant: error\n Got: nil\n", expr)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/uuid"
+)
+
+// Middleware wraps a http.Handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain returns h wrapped in the middlewares; the first middleware is the
+// outermost, so it sees the request first and the response last
+//
+// Usage:
+//
+//        h = handler.Chain(h, handler.RequestID, handler.Timer, handler.AccessLog(logRequest),
+//                handler.Recover(internalError))
+//
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+//
+// Request ID
+//
+
+// RequestIDHeader is the header of the ID that correlates the log messages of a request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestID is the max length of a request ID that is propagated
+const maxRequestID = 128
+
+// RequestID propagates the X-Request-ID header of the request, or a new
+// UUID when it has none, to the request context and the response header
+func RequestID(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			buf, err := uuid.NewV4()
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			id = string(buf)
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(req.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, req.WithContext(ctx))
+	}
+	return http.HandlerFunc(f)
+}
+
+// RequestIDFrom returns the request ID in ctx; empty when it has none
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+//
+// Latency
+//
+
+// Timer records the time the request was received in the request context
+func Timer(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, req *http.Request) {
+		next.ServeHTTP(w, withStart(req))
+	}
+	return http.HandlerFunc(f)
+}
+
+// Latency returns the time since the request was received; zero when the
+// request has no Timer
+func Latency(req *http.Request) time.Duration {
+	start, ok := req.Context().Value(startKey{}).(time.Time)
+	if !ok {
+		return 0
+	}
+	return time.Since(start)
+}
+
+//
+// Access log
+//
+
+// Response describes the response of a request in the access log
+type Response struct {
+	Code    int
+	Size    int64
+	Latency time.Duration
+
+	// Err is the error of the response, see SetError
+	Err error
+}
+
+// AccessLog calls log with the request and its response when the request
+// is done; the latency is from the Timer, if the chain has one before it
+func AccessLog(log func(req *http.Request, resp Response)) Middleware {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, req *http.Request) {
+			if _, ok := req.Context().Value(startKey{}).(time.Time); !ok {
+				req = withStart(req)
+			}
+			e := &entry{}
+			req = req.WithContext(context.WithValue(req.Context(), entryKey{}, e))
+			rw := wrap(w)
+
+			defer func() {
+				log(req, Response{Code: rw.code(), Size: rw.size, Latency: Latency(req), Err: e.err})
+			}()
+			next.ServeHTTP(rw, req)
+		}
+		return http.HandlerFunc(f)
+	}
+}
+
+// SetError records the error of the response to the request in the access log
+func SetError(req *http.Request, err error) {
+	if e, ok := req.Context().Value(entryKey{}).(*entry); ok {
+		e.err = err
+	}
+}
+
+//
+// Panic recovery
+//
+
+// Recover recovers a panic in the handler and calls render with the panic
+// as an error, e.g: to write an internal server error page; the response
+// is left as it is when the handler has written its header
+func Recover(render func(w http.ResponseWriter, req *http.Request, err error)) Middleware {
+	return func(next http.Handler) http.Handler {
+		f := func(w http.ResponseWriter, req *http.Request) {
+			rw := wrap(w)
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				err := errors.Errorf("panic: %v", p)
+				SetError(req, err)
+				if !rw.wroteHeader {
+					render(rw, req, err)
+				}
+			}()
+			next.ServeHTTP(rw, req)
+		}
+		return http.HandlerFunc(f)
+	}
+}
+
+//
+// PRIVATE
+//
+
+type requestIDKey struct{}
+
+type startKey struct{}
+
+type entryKey struct{}
+
+type entry struct {
+	err error
+}
+
+func withStart(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), startKey{}, time.Now()))
+}
+
+// validRequestID returns true if id is not empty, not too long and only has
+// printable ASCII characters, so it is safe to log and to send back
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// responseWriter records the status code and the size of the response
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+// wrap returns w as a responseWriter; w is returned as it is if it is one
+func wrap(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher, e.g: for event streams
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not implemented")
+	}
+	return h.Hijack()
+}
+
+// code returns the status code of the response; 200 when the handler
+// wrote nothing
+func (w *responseWriter) code() int {
+	if !w.wroteHeader {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,175 @@
+package handler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/handler"
+)
+
+func TestChain_CallsTheMiddlewaresInOrder(t *testing.T) {
+	var calls []string
+	m := func(name string) handler.Middleware {
+		return func(next http.Handler) http.Handler {
+			f := func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, req)
+			}
+			return http.HandlerFunc(f)
+		}
+	}
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { calls = append(calls, "handler") })
+
+	handler.Chain(h, m("first"), m("second")).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	want := "first second handler"
+	if got := strings.Join(calls, " "); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	testCases := []struct {
+		name      string
+		header    string
+		propagate bool
+	}{
+		{name: "propagates the request ID", header: "8a7f-1", propagate: true},
+		{name: "generates a request ID when it has none", header: ""},
+		{name: "generates a request ID when it is invalid", header: "8a7f 1"},
+		{name: "generates a request ID when it is too long", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			h := handler.RequestID(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				got = handler.RequestIDFrom(req.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.header != "" {
+				req.Header.Set(handler.RequestIDHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if tc.propagate && tc.header != got {
+				t.Errorf("\nWant: %s\n Got: %s\n", tc.header, got)
+			}
+			if !tc.propagate && len(got) != 36 {
+				t.Errorf("\nWant: a UUID\n Got: %s\n", got)
+			}
+			if header := w.Header().Get(handler.RequestIDHeader); got != header {
+				t.Errorf("\nWant: %s\n Got: %s\n", got, header)
+			}
+		})
+	}
+}
+
+func TestAccessLog_LogsTheResponse(t *testing.T) {
+	testCases := []struct {
+		name string
+		h    http.HandlerFunc
+		want handler.Response
+	}{
+		{
+			name: "body",
+			h:    func(w http.ResponseWriter, req *http.Request) { _, _ = io.WriteString(w, "Hello World!") },
+			want: handler.Response{Code: http.StatusOK, Size: 12},
+		},
+		{
+			name: "no body",
+			h:    func(w http.ResponseWriter, req *http.Request) {},
+			want: handler.Response{Code: http.StatusOK},
+		},
+		{
+			name: "error",
+			h: func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, "Bad Request")
+				handler.SetError(req, errors.New("invalid URL"))
+			},
+			want: handler.Response{Code: http.StatusBadRequest, Size: 11, Err: errors.New("invalid URL")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got handler.Response
+			log := func(req *http.Request, resp handler.Response) { got = resp }
+			h := handler.Chain(tc.h, handler.Timer, handler.AccessLog(log))
+
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+			if tc.want.Code != got.Code || tc.want.Size != got.Size {
+				t.Errorf("\nWant: %d %d\n Got: %d %d\n", tc.want.Code, tc.want.Size, got.Code, got.Size)
+			}
+			if (tc.want.Err == nil) != (got.Err == nil) || (got.Err != nil && tc.want.Err.Error() != got.Err.Error()) {
+				t.Errorf("\nWant: %v\n Got: %v\n", tc.want.Err, got.Err)
+			}
+			if got.Latency <= 0 {
+				t.Errorf("\nWant: latency\n Got: %s\n", got.Latency)
+			}
+		})
+	}
+}
+
+func TestAccessLog_KeepsTheFlusher(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Errorf("\nWant: http.Flusher\n Got: %T\n", w)
+		}
+	})
+
+	log := func(req *http.Request, resp handler.Response) {}
+	handler.AccessLog(log)(h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestLatency_WhenNoTimer_ReturnsZero(t *testing.T) {
+	if got := handler.Latency(httptest.NewRequest("GET", "/", nil)); got != 0 {
+		t.Errorf("\nWant: 0\n Got: %s\n", got)
+	}
+}
+
+func TestRecover_RendersThePanicAsAnError(t *testing.T) {
+	var got handler.Response
+	log := func(req *http.Request, resp handler.Response) { got = resp }
+	render := func(w http.ResponseWriter, req *http.Request, err error) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, "Internal Server Error")
+	}
+	h := handler.Chain(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { panic("boom") }),
+		handler.AccessLog(log), handler.Recover(render))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error" {
+		t.Errorf("\nWant: 500 Internal Server Error\n Got: %d %s\n", w.Code, w.Body.String())
+	}
+	if got.Code != http.StatusInternalServerError || got.Err == nil || got.Err.Error() != "panic: boom" {
+		t.Errorf("\nWant: 500 panic: boom\n Got: %d %v\n", got.Code, got.Err)
+	}
+}
+
+func TestRecover_WhenTheHeaderIsWritten_LeavesTheResponse(t *testing.T) {
+	rendered := false
+	render := func(w http.ResponseWriter, req *http.Request, err error) { rendered = true }
+	h := handler.Recover(render)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if rendered || w.Code != http.StatusAccepted {
+		t.Errorf("\nWant: 202 and not rendered\n Got: %d rendered=%t\n", w.Code, rendered)
+	}
+}
@@ -0,0 +1,192 @@
+// Package mail sends multipart text and HTML messages over SMTP:
+//
+//      c := mail.Config{Host: "smtp.example.com", Port: 587, StartTLS: true}
+//      err := mail.Send(ctx, c, msg)
+//
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Config defines the SMTP server
+type Config struct {
+	Host     string
+	Port     int
+	Username string // no AUTH when empty
+	Password string
+
+	// StartTLS requires the server to upgrade the connection with STARTTLS
+	StartTLS bool
+
+	// TLSConfig is used by STARTTLS; nil uses the system roots and Host as the server name
+	TLSConfig *tls.Config
+}
+
+// String returns the config without the password
+func (c Config) String() string {
+	return fmt.Sprintf("{Host:%s Port:%d Username:%s StartTLS:%t}", c.Host, c.Port, c.Username, c.StartTLS)
+}
+
+// Message is a multipart/alternative message with a text and a HTML part
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    []byte
+	HTML    []byte
+	Date    time.Time // time.Now() when zero
+}
+
+// Bytes returns the message in the Internet Message Format, RFC 5322
+func (m Message) Bytes() ([]byte, error) {
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := writePart(w, "text/plain; charset=utf-8", m.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(w, "text/html; charset=utf-8", m.HTML); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "w.Close() failed")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n", w.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// Send sends the message to its recipients
+//
+// Note:
+//
+//        The connection is closed when ctx is done. AUTH PLAIN is only
+//        sent over TLS or to localhost, see smtp.PlainAuth.
+//
+func Send(ctx context.Context, c Config, m Message) error {
+	if len(m.To) == 0 {
+		return errors.New("mail has no recipients")
+	}
+	msg, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "dial %s failed", addr)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return errors.Wrap(err, "conn.SetDeadline() failed")
+		}
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		return errors.Wrap(err, "smtp.NewClient() failed")
+	}
+	defer client.Close()
+
+	if c.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(c.tlsConfig()); err != nil {
+			return errors.Wrap(err, "STARTTLS failed")
+		}
+	}
+
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return errors.Wrap(err, "AUTH failed")
+		}
+	}
+
+	if err := client.Mail(address(m.From)); err != nil {
+		return errors.Wrap(err, "MAIL FROM failed")
+	}
+	for _, to := range m.To {
+		if err := client.Rcpt(address(to)); err != nil {
+			return errors.Wrapf(err, "RCPT TO %s failed", to)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "DATA failed")
+	}
+	if _, err := w.Write(msg); err != nil {
+		return errors.Wrap(err, "write message failed")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "DATA failed")
+	}
+
+	return errors.Wrap(client.Quit(), "QUIT failed")
+}
+
+//
+// PRIVATE
+//
+
+func (c Config) tlsConfig() *tls.Config {
+	if c.TLSConfig != nil {
+		return c.TLSConfig
+	}
+	return &tls.Config{ServerName: c.Host}
+}
+
+func writePart(w *multipart.Writer, contentType string, content []byte) error {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return errors.Wrap(err, "w.CreatePart() failed")
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write(content); err != nil {
+		return errors.Wrap(err, "quoted-printable write failed")
+	}
+	return errors.Wrap(qp.Close(), "quoted-printable close failed")
+}
+
+// address returns the address of e.g: Mockingbird <mockingbird@example.com>
+func address(s string) string {
+	if i := strings.LastIndex(s, "<"); i >= 0 {
+		return strings.TrimSuffix(s[i+1:], ">")
+	}
+	return strings.TrimSpace(s)
+}
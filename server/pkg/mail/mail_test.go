@@ -0,0 +1,142 @@
+package mail_test
+
+import (
+	"context"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/mail"
+	"github.com/unders/mockingbird/server/pkg/mail/mailtest"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+var message = mail.Message{
+	From:    "Mockingbird <mockingbird@example.com>",
+	To:      []string{"qa@example.com", "Ops <ops@example.com>"},
+	Subject: "[mockingbird] all:test failed – 1m30s",
+	Text:    []byte("all:test failed\n"),
+	HTML:    []byte("<h1>all:test failed</h1>\n"),
+	Date:    time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+}
+
+func TestSend_WithAuth_SendsMultipartMessage(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+	srv.RequireAuth("mockingbird", "secret")
+
+	c := mail.Config{Host: srv.Host(), Port: srv.Port(), Username: "mockingbird", Password: "secret"}
+	testdata.AssertNil(t, mail.Send(context.Background(), c, message))
+
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("\nWant: 1 message\n Got: %d\n", len(msgs))
+	}
+	got := msgs[0]
+	if want := "mockingbird@example.com"; want != got.From {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got.From)
+	}
+	if want := "qa@example.com ops@example.com"; want != strings.Join(got.To, " ") {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, strings.Join(got.To, " "))
+	}
+
+	m, err := netmail.ReadMessage(strings.NewReader(got.Data))
+	testdata.AssertNil(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	testdata.AssertNil(t, err)
+	if want := message.Subject; want != subject {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, subject)
+	}
+	if want, got := "Wed, 02 Jan 2019 03:04:05 +0000", m.Header.Get("Date"); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	testdata.AssertNil(t, err)
+	if want := "multipart/alternative"; want != mediaType {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, mediaType)
+	}
+
+	r := multipart.NewReader(m.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{contentType: "text/plain; charset=utf-8", body: string(message.Text)},
+		{contentType: "text/html; charset=utf-8", body: string(message.HTML)},
+	} {
+		part, err := r.NextPart()
+		testdata.AssertNil(t, err)
+		b, err := ioutil.ReadAll(part)
+		testdata.AssertNil(t, err)
+
+		if got := part.Header.Get("Content-Type"); want.contentType != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", want.contentType, got)
+		}
+		if got := string(b); want.body != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", want.body, got)
+		}
+	}
+}
+
+func TestSend_WithStartTLS_SendsOverTLS(t *testing.T) {
+	srv := mailtest.NewTLSServer()
+	defer srv.Close()
+
+	c := mail.Config{Host: srv.Host(), Port: srv.Port(), StartTLS: true, TLSConfig: srv.ClientTLSConfig()}
+	testdata.AssertNil(t, mail.Send(context.Background(), c, message))
+
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("\nWant: 1 message\n Got: %d\n", len(msgs))
+	}
+	testdata.AssertTrue(t, msgs[0].TLS)
+}
+
+func TestSend_WhenItFails_ReturnsError(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+	srv.RequireAuth("mockingbird", "secret")
+
+	testCases := []struct {
+		name string
+		c    mail.Config
+		m    mail.Message
+	}{
+		{
+			name: "STARTTLS is not supported",
+			c:    mail.Config{Host: srv.Host(), Port: srv.Port(), StartTLS: true, Username: "mockingbird", Password: "secret"},
+			m:    message,
+		},
+		{
+			name: "wrong password",
+			c:    mail.Config{Host: srv.Host(), Port: srv.Port(), Username: "mockingbird", Password: "wrong"},
+			m:    message,
+		},
+		{
+			name: "no recipients",
+			c:    mail.Config{Host: srv.Host(), Port: srv.Port(), Username: "mockingbird", Password: "secret"},
+			m:    mail.Message{From: message.From, Subject: message.Subject},
+		},
+	}
+
+	for _, tc := range testCases {
+		if err := mail.Send(context.Background(), tc.c, tc.m); err == nil {
+			t.Errorf("%s\nWant: error\n Got: nil\n", tc.name)
+		}
+	}
+	if got := len(srv.Messages()); got != 0 {
+		t.Errorf("\nWant: 0 messages\n Got: %d\n", got)
+	}
+}
+
+func TestConfig_String_HidesPassword(t *testing.T) {
+	c := mail.Config{Host: "smtp.example.com", Port: 587, Username: "mockingbird", Password: "secret", StartTLS: true}
+
+	want := "{Host:smtp.example.com Port:587 Username:mockingbird StartTLS:true}"
+	if got := c.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
@@ -0,0 +1,292 @@
+// Package mailtest provides an in-process SMTP stand-in for tests.
+//
+// It understands the commands that net/smtp sends:
+//
+//      EHLO  HELO  STARTTLS  AUTH PLAIN  MAIL  RCPT  DATA  RSET  NOOP  QUIT
+//
+package mailtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a received message
+type Message struct {
+	From string
+	To   []string
+	Data string
+	TLS  bool // true if it was sent after STARTTLS
+}
+
+// Server is an in-process SMTP server
+type Server struct {
+	listener net.Listener
+	tls      *tls.Config // nil when STARTTLS is not supported
+	roots    *x509.CertPool
+
+	mu       sync.Mutex
+	username string // AUTH PLAIN is required when it is set
+	password string
+	messages []Message
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts a Server on a random local port
+//
+// Usage:
+//
+//         srv := mailtest.NewServer()
+//         defer srv.Close()
+//
+func NewServer() *Server {
+	return start(nil, nil)
+}
+
+// NewTLSServer starts a Server that supports STARTTLS with a self-signed certificate
+func NewTLSServer() *Server {
+	cert, roots, err := selfSigned()
+	if err != nil {
+		panic(fmt.Sprintf("mailtest: failed to create a certificate: %v", err))
+	}
+	return start(&tls.Config{Certificates: []tls.Certificate{cert}}, roots)
+}
+
+// RequireAuth requires AUTH PLAIN with the username and password before MAIL
+func (s *Server) RequireAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username, s.password = username, password
+}
+
+// Host returns the host of the server
+func (s *Server) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port of the server
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Addr returns host:port of the server
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Host(), strconv.Itoa(s.Port()))
+}
+
+// ClientTLSConfig returns a TLS config that trusts the certificate of the server
+func (s *Server) ClientTLSConfig() *tls.Config {
+	return &tls.Config{RootCAs: s.roots, ServerName: s.Host()}
+}
+
+// Messages returns the received messages
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+//
+// PRIVATE
+//
+
+func start(tlsConfig *tls.Config, roots *x509.CertPool) *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("mailtest: failed to listen: %v", err))
+	}
+
+	s := &Server{listener: l, tls: tlsConfig, roots: roots}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.session(conn)
+		}()
+	}
+}
+
+// session handles the commands of a connection until QUIT
+func (s *Server) session(conn net.Conn) {
+	defer func() { conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) { _ = tp.PrintfLine("%d %s", code, msg) }
+
+	s.mu.Lock()
+	username, password := s.username, s.password
+	s.mu.Unlock()
+
+	var (
+		msg    Message
+		authed = username == ""
+	)
+	reply(220, "mailtest ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ext := []string{"250-mailtest", "250-AUTH PLAIN"}
+			if s.tls != nil && !msg.TLS {
+				ext = append(ext, "250-STARTTLS")
+			}
+			for _, e := range ext {
+				_ = tp.PrintfLine("%s", e)
+			}
+			reply(250, "8BITMIME")
+		case "HELO", "NOOP":
+			reply(250, "OK")
+		case "STARTTLS":
+			if s.tls == nil || msg.TLS {
+				reply(502, "STARTTLS not supported")
+				continue
+			}
+			reply(220, "Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg = Message{TLS: true}
+		case "AUTH":
+			authed = auth(arg, username, password)
+			if !authed {
+				reply(535, "Authentication failed")
+				continue
+			}
+			reply(235, "Authenticated")
+		case "MAIL":
+			if !authed {
+				reply(530, "Authentication required")
+				continue
+			}
+			msg.From = trimAddress(arg, "FROM:")
+			reply(250, "OK")
+		case "RCPT":
+			msg.To = append(msg.To, trimAddress(arg, "TO:"))
+			reply(250, "OK")
+		case "DATA":
+			reply(354, "End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{TLS: msg.TLS}
+			reply(250, "OK")
+		case "RSET":
+			msg = Message{TLS: msg.TLS}
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			reply(502, "Command not implemented")
+		}
+	}
+}
+
+// auth returns true if arg is PLAIN with the username and password
+func auth(arg, username, password string) bool {
+	fields := strings.Fields(arg)
+	if len(fields) != 2 || strings.ToUpper(fields[0]) != "PLAIN" {
+		return false
+	}
+
+	b, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(b), "\x00")
+	return len(parts) == 3 && parts[1] == username && parts[2] == password
+}
+
+// trimAddress returns the address of e.g: FROM:<mockingbird@example.com>
+func trimAddress(arg, prefix string) string {
+	arg = strings.TrimSpace(arg)
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	if i := strings.Index(arg, " "); i >= 0 {
+		arg = arg[:i]
+	}
+	return strings.Trim(arg, "<>")
+}
+
+// selfSigned returns a certificate for 127.0.0.1 and a pool that trusts it
+func selfSigned() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"mailtest"}},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, roots, nil
+}
@@ -0,0 +1,186 @@
+// Package metrics exports OpenCensus views in the Prometheus text format:
+//
+//      e := metrics.NewExporter("mockingbird")
+//      view.RegisterExporter(e)
+//      http.Handle("/metrics", e)
+//
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter keeps the latest data of each view and serves it in the Prometheus text format
+//
+// Note:
+//
+//        A view is shown after its first reporting period, see
+//        view.SetReportingPeriod, and is as old as the latest one.
+//        Count is a counter, Distribution a histogram, LastValue a
+//        gauge and Sum is untyped, since a sum can decrease.
+//
+type Exporter struct {
+	namespace string
+
+	sync.Mutex
+	data map[string]*view.Data // by metric name
+}
+
+// NewExporter returns an Exporter; namespace is the prefix of the metric names
+func NewExporter(namespace string) *Exporter {
+	return &Exporter{namespace: namespace, data: map[string]*view.Data{}}
+}
+
+// Verifies that Exporter implements view.Exporter interface
+var _ view.Exporter = &Exporter{}
+
+// ExportView implements view.Exporter
+func (e *Exporter) ExportView(d *view.Data) {
+	e.Lock()
+	defer e.Unlock()
+	e.data[e.name(d.View.Name)] = d
+}
+
+// ServeHTTP writes the metrics sorted by name
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	e.Lock()
+	names := make([]string, 0, len(e.data))
+	data := make(map[string]*view.Data, len(e.data))
+	for name, d := range e.data {
+		names = append(names, name)
+		data[name] = d
+	}
+	e.Unlock()
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", ContentType)
+	b := bufio.NewWriter(w)
+	for _, name := range names {
+		writeMetric(b, name, data[name])
+	}
+	_ = b.Flush()
+}
+
+//
+// PRIVATE
+//
+
+// name returns the metric name of a view, e.g: opencensus.io/http/server/latency
+// is mockingbird_opencensus_io_http_server_latency
+func (e *Exporter) name(view string) string {
+	if e.namespace == "" {
+		return sanitize(view)
+	}
+	return sanitize(e.namespace + "_" + view)
+}
+
+func writeMetric(w *bufio.Writer, name string, d *view.Data) {
+	if d.View.Description != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(d.View.Description))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType(d.View.Aggregation))
+
+	rows := append([]*view.Row(nil), d.Rows...)
+	sort.Slice(rows, func(i, j int) bool {
+		return labels(rows[i].Tags, "", "") < labels(rows[j].Tags, "", "")
+	})
+
+	for _, row := range rows {
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			fmt.Fprintf(w, "%s%s %d\n", name, labels(row.Tags, "", ""), data.Value)
+		case *view.SumData:
+			fmt.Fprintf(w, "%s%s %s\n", name, labels(row.Tags, "", ""), formatFloat(data.Value))
+		case *view.LastValueData:
+			fmt.Fprintf(w, "%s%s %s\n", name, labels(row.Tags, "", ""), formatFloat(data.Value))
+		case *view.DistributionData:
+			var cumulative int64
+			for i, bound := range d.View.Aggregation.Buckets {
+				if i < len(data.CountPerBucket) {
+					cumulative += data.CountPerBucket[i]
+				}
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(row.Tags, "le", formatFloat(bound)), cumulative)
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels(row.Tags, "le", "+Inf"), data.Count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, labels(row.Tags, "", ""), formatFloat(data.Sum()))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, labels(row.Tags, "", ""), data.Count)
+		}
+	}
+}
+
+func metricType(a *view.Aggregation) string {
+	switch a.Type {
+	case view.AggTypeCount:
+		return "counter"
+	case view.AggTypeDistribution:
+		return "histogram"
+	case view.AggTypeLastValue:
+		return "gauge"
+	default:
+		return "untyped"
+	}
+}
+
+// labels returns the label set of the tags, e.g: {state="failed",suite="all:test"};
+// the extra label is added last when its name is set
+func labels(tags []tag.Tag, extraName, extraValue string) string {
+	pairs := make([]string, 0, len(tags)+1)
+	for _, t := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", sanitize(t.Key.Name()), escapeLabel(t.Value)))
+	}
+	sort.Strings(pairs)
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraName, escapeLabel(extraValue)))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sanitize replaces the characters that are not allowed in a metric or label name with _
+func sanitize(s string) string {
+	name := []rune(s)
+	for i, r := range name {
+		valid := r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || (i > 0 && '0' <= r && r <= '9')
+		if !valid {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
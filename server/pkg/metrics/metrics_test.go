@@ -0,0 +1,105 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/unders/mockingbird/server/pkg/metrics"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestExporter_ServeHTTP_WritesTextFormat(t *testing.T) {
+	suite, err := tag.NewKey("suite")
+	testdata.AssertNil(t, err)
+	state, err := tag.NewKey("state")
+	testdata.AssertNil(t, err)
+
+	runs := stats.Int64("test/runs", "", stats.UnitDimensionless)
+	runTime := stats.Float64("test/run_time", "", "s")
+	depth := stats.Int64("test/queue_depth", "", stats.UnitDimensionless)
+
+	e := metrics.NewExporter("mockingbird")
+	e.ExportView(&view.Data{
+		View: &view.View{
+			Name:        "test_suite_runs_total",
+			Description: "Finished test suite runs",
+			TagKeys:     []tag.Key{suite, state},
+			Measure:     runs,
+			Aggregation: view.Count(),
+		},
+		Rows: []*view.Row{
+			{Tags: []tag.Tag{{Key: suite, Value: "google:test"}, {Key: state, Value: "successful"}}, Data: &view.CountData{Value: 7}},
+			{Tags: []tag.Tag{{Key: suite, Value: "all:test"}, {Key: state, Value: "failed"}}, Data: &view.CountData{Value: 2}},
+		},
+	})
+	e.ExportView(&view.Data{
+		View: &view.View{
+			Name:        "test_suite_run_time_seconds",
+			TagKeys:     []tag.Key{suite},
+			Measure:     runTime,
+			Aggregation: view.Distribution(10, 60),
+		},
+		Rows: []*view.Row{
+			{
+				Tags: []tag.Tag{{Key: suite, Value: "all:test"}},
+				Data: &view.DistributionData{Count: 3, Mean: 30, CountPerBucket: []int64{1, 1, 1}},
+			},
+		},
+	})
+	e.ExportView(&view.Data{
+		View: &view.View{
+			Name:        "opencensus.io/queue/depth",
+			Description: "Queued \"test suites\"\nnow",
+			Measure:     depth,
+			Aggregation: view.LastValue(),
+		},
+		Rows: []*view.Row{{Data: &view.LastValueData{Value: 4}}},
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if want, got := metrics.ContentType, w.Header().Get("Content-Type"); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	want := `# HELP mockingbird_opencensus_io_queue_depth Queued "test suites"\nnow
+# TYPE mockingbird_opencensus_io_queue_depth gauge
+mockingbird_opencensus_io_queue_depth 4
+# TYPE mockingbird_test_suite_run_time_seconds histogram
+mockingbird_test_suite_run_time_seconds_bucket{suite="all:test",le="10"} 1
+mockingbird_test_suite_run_time_seconds_bucket{suite="all:test",le="60"} 2
+mockingbird_test_suite_run_time_seconds_bucket{suite="all:test",le="+Inf"} 3
+mockingbird_test_suite_run_time_seconds_sum{suite="all:test"} 90
+mockingbird_test_suite_run_time_seconds_count{suite="all:test"} 3
+# HELP mockingbird_test_suite_runs_total Finished test suite runs
+# TYPE mockingbird_test_suite_runs_total counter
+mockingbird_test_suite_runs_total{state="failed",suite="all:test"} 2
+mockingbird_test_suite_runs_total{state="successful",suite="google:test"} 7
+`
+	if got := w.Body.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestExporter_ExportView_KeepsTheLatestData(t *testing.T) {
+	runs := stats.Int64("test/latest_runs", "", stats.UnitDimensionless)
+	v := &view.View{Name: "runs_total", Measure: runs, Aggregation: view.Count()}
+
+	e := metrics.NewExporter("")
+	e.ExportView(&view.Data{View: v, Rows: []*view.Row{{Data: &view.CountData{Value: 1}}}})
+	e.ExportView(&view.Data{View: v, Rows: []*view.Row{{Data: &view.CountData{Value: 5}}}})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	want := "# TYPE runs_total counter\nruns_total 5\n"
+	if got := w.Body.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
@@ -0,0 +1,250 @@
+// Package s3 is a small client for the S3 REST protocol.
+//
+// It supports the object operations needed to use a bucket as a simple
+// database (put, get and list), signed with AWS Signature Version 4, and
+// works against AWS S3 and S3-compatible servers.
+//
+package s3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/unders/mockingbird/server/pkg/errs"
+)
+
+const service = "s3"
+
+// Config defines the required settings for the Client
+type Config struct {
+	// Endpoint, e.g: https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	Endpoint string
+	Region   string
+	Bucket   string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// PathStyle addresses the bucket as {endpoint}/{bucket}/{key}
+	// instead of {bucket}.{endpoint-host}/{key}
+	PathStyle bool
+}
+
+// String returns the config without credentials
+func (c Config) String() string {
+	const format = "{Endpoint:%s Region:%s Bucket:%s PathStyle:%t}"
+	return fmt.Sprintf(format, c.Endpoint, c.Region, c.Bucket, c.PathStyle)
+}
+
+// Client talks to an S3 bucket
+type Client struct {
+	config   Config
+	endpoint *url.URL
+
+	HTTP *http.Client
+	Now  func() time.Time
+}
+
+// New returns a Client for the bucket given by c
+func New(c Config) (*Client, error) {
+	if c.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.Region)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "url.Parse(%s) failed", endpoint)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+
+	return &Client{
+		config:   c,
+		endpoint: u,
+		HTTP:     &http.Client{Timeout: 30 * time.Second},
+		Now:      time.Now,
+	}, nil
+}
+
+// Put stores body under key
+func (c *Client) Put(key string, body []byte) error {
+	req, err := c.newRequest(http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return errors.Wrapf(err, "PUT %s failed", key)
+	}
+	return resp.Body.Close()
+}
+
+// Get returns the object stored under key
+func (c *Client) Get(key string) ([]byte, error) {
+	req, err := c.newRequest(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GET %s failed", key)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	return b, errors.Wrapf(err, "GET %s read body failed", key)
+}
+
+// List returns at most max keys with the given prefix that sort after marker
+func (c *Client) List(prefix, marker string, max int) ([]string, error) {
+	q := url.Values{}
+	q.Set("prefix", prefix)
+	if marker != "" {
+		q.Set("marker", marker)
+	}
+	if max > 0 {
+		q.Set("max-keys", strconv.Itoa(max))
+	}
+
+	req, err := c.newRequest(http.MethodGet, "", q, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "list %s failed", prefix)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	result := listBucketResult{}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.Wrap(err, "decode ListBucketResult failed")
+	}
+
+	keys := make([]string, 0, len(result.Contents))
+	for _, o := range result.Contents {
+		keys = append(keys, o.Key)
+	}
+	return keys, nil
+}
+
+// URL returns the URL to key
+func (c *Client) URL(key string) string {
+	return c.objectURL(key).String()
+}
+
+//
+// PRIVATE
+//
+
+type listBucketResult struct {
+	IsTruncated bool
+	Contents    []struct {
+		Key string
+	}
+}
+
+type errorResponse struct {
+	Code    string
+	Message string
+}
+
+func (c *Client) objectURL(key string) *url.URL {
+	u := *c.endpoint
+	segments := []string{}
+	if c.config.PathStyle {
+		segments = append(segments, c.config.Bucket)
+	} else {
+		u.Host = c.config.Bucket + "." + u.Host
+	}
+	if key != "" {
+		segments = append(segments, strings.Split(key, "/")...)
+	}
+
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = escape(s)
+	}
+
+	base := strings.TrimSuffix(u.Path, "/")
+	u.Path = base + "/" + strings.Join(segments, "/")
+	u.RawPath = base + "/" + strings.Join(escaped, "/")
+	return &u
+}
+
+func (c *Client) newRequest(method, key string, q url.Values, body []byte) (*http.Request, error) {
+	u := c.objectURL(key)
+	if q != nil {
+		u.RawQuery = q.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, u.String(), r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "http.NewRequest(%s, %s) failed", method, u)
+	}
+
+	payloadHash := emptyHash
+	if body != nil {
+		payloadHash = hashHex(body)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.config.SessionToken)
+	}
+
+	sign(req, payloadHash, c.config.Region, service,
+		c.config.AccessKeyID, c.config.SecretAccessKey, c.Now())
+
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	e := errorResponse{}
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = xml.Unmarshal(b, &e)
+
+	if resp.StatusCode == http.StatusNotFound && e.Code != "NoSuchBucket" {
+		msg := fmt.Sprintf("%s %s not found: %s", req.Method, req.URL.Path, e.Code)
+		return nil, errs.NotFound(msg)
+	}
+
+	return nil, errors.Errorf("%s %s failed: %d %s %s", req.Method, req.URL.Path, resp.StatusCode, e.Code, e.Message)
+}
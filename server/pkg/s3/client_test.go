@@ -0,0 +1,65 @@
+package s3_test
+
+import (
+	"testing"
+
+	"github.com/unders/mockingbird/server/pkg/errs"
+	"github.com/unders/mockingbird/server/pkg/s3"
+	"github.com/unders/mockingbird/server/pkg/s3/s3test"
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+func TestClient_PutGetList(t *testing.T) {
+	srv := s3test.NewServer("results")
+	defer srv.Close()
+
+	c := newClient(t, srv.URL, "results")
+
+	for _, key := range []string{"test-3.json", "test-1.json", "stats.json", "test-2.json"} {
+		testdata.AssertNil(t, c.Put(key, []byte(key)))
+	}
+
+	b, err := c.Get("test-1.json")
+	testdata.AssertNil(t, err)
+	if want, got := "test-1.json", string(b); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+
+	_, err = c.Get("missing.json")
+	testdata.AssertTrue(t, errs.IsNotFound(err))
+
+	keys, err := c.List("test-", "test-1.json", 1)
+	testdata.AssertNil(t, err)
+	if len(keys) != 1 || keys[0] != "test-2.json" {
+		t.Errorf("\nWant: [test-2.json]\n Got: %v\n", keys)
+	}
+
+	if want, got := srv.URL+"/results/stats.json", c.URL("stats.json"); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestClient_WhenBucketIsMissing_ReturnsError(t *testing.T) {
+	srv := s3test.NewServer("results")
+	defer srv.Close()
+
+	c := newClient(t, srv.URL, "other")
+
+	_, err := c.Get("stats.json")
+	testdata.AssertErr(t, err)
+	testdata.AssertTrue(t, !errs.IsNotFound(err))
+}
+
+func newClient(t *testing.T, endpoint, bucket string) *s3.Client {
+	t.Helper()
+
+	c, err := s3.New(s3.Config{
+		Endpoint:        endpoint,
+		Bucket:          bucket,
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	})
+	testdata.AssertNil(t, err)
+	return c
+}
@@ -0,0 +1,159 @@
+// Package s3test provides an in-memory S3 stand-in for tests.
+//
+// It only understands path-style requests for a single bucket:
+//
+//      PUT  /{bucket}/{key}
+//      GET  /{bucket}/{key}
+//      GET  /{bucket}?prefix=&marker=&max-keys=
+//
+package s3test
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is an in-memory S3 server
+type Server struct {
+	*httptest.Server
+	Bucket string
+
+	mu      sync.RWMutex
+	objects map[string][]byte
+	// Requests records the method and path of each request
+	Requests []string
+}
+
+// NewServer starts a Server for bucket
+//
+// Usage:
+//
+//         srv := s3test.NewServer("results")
+//         defer srv.Close()
+//
+func NewServer(bucket string) *Server {
+	s := &Server{Bucket: bucket, objects: map[string][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Object returns the stored object for key
+func (s *Server) Object(key string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.objects[key]
+	return b, ok
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name `xml:"ListBucketResult"`
+	Name        string
+	Prefix      string
+	Marker      string
+	MaxKeys     int
+	IsTruncated bool
+	Contents    []content
+}
+
+type content struct {
+	Key  string
+	Size int
+}
+
+type errorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string
+	Message string
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.Requests = append(s.Requests, r.Method+" "+r.URL.RequestURI())
+	s.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		writeError(w, http.StatusForbidden, "AccessDenied", "missing signature")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if parts[0] != s.Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+
+	key := ""
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		s.mu.Lock()
+		s.objects[key] = b
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && key != "":
+		b, ok := s.Object(key)
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "key not found")
+			return
+		}
+		_, _ = w.Write(b)
+	case r.Method == http.MethodGet:
+		s.list(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	prefix := q.Get("prefix")
+	marker := q.Get("marker")
+	max := 1000
+	if v, err := strconv.Atoi(q.Get("max-keys")); err == nil {
+		max = v
+	}
+
+	s.mu.RLock()
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) && k > marker {
+			keys = append(keys, k)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Strings(keys)
+
+	result := listBucketResult{Name: s.Bucket, Prefix: prefix, Marker: marker, MaxKeys: max}
+	if len(keys) > max {
+		keys = keys[:max]
+		result.IsTruncated = true
+	}
+	for _, k := range keys {
+		b, _ := s.Object(k)
+		result.Contents = append(result.Contents, content{Key: k, Size: len(b)})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, code int, s3Code, msg string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	_ = xml.NewEncoder(w).Encode(errorResponse{Code: s3Code, Message: msg})
+}
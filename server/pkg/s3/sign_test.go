@@ -0,0 +1,44 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/testdata"
+)
+
+// The get-vanilla case from the AWS Signature Version 4 test suite
+func TestSign_GetVanilla(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	testdata.AssertNil(t, err)
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	sign(req, emptyHash, "us-east-1", "service",
+		"AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"test-01.json", "test-01.json"},
+		{"a b", "a%20b"},
+		{"a/b", "a%2Fb"},
+		{"~_.", "~_."},
+	}
+
+	for _, test := range tests {
+		if got := escape(test.in); test.want != got {
+			t.Errorf("\nWant: %s\n Got: %s\n", test.want, got)
+		}
+	}
+}
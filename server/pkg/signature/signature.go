@@ -0,0 +1,74 @@
+// Package signature signs webhook payloads and the unix time they were
+// sent at with HMAC-SHA256, so a captured request can not be replayed
+// after the Tolerance:
+//
+//      X-Mockingbird-Timestamp: 1546398245
+//      X-Mockingbird-Signature: sha256=HMAC-SHA256(secret, "1546398245." + payload)
+//
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the HTTP header of the signature
+const Header = "X-Mockingbird-Signature"
+
+// TimestampHeader is the HTTP header of the unix time the payload was signed at
+const TimestampHeader = "X-Mockingbird-Timestamp"
+
+// Tolerance is how far the timestamp of a valid signature may be from the current time
+const Tolerance = 5 * time.Minute
+
+const prefix = "sha256="
+
+// Timestamp returns the timestamp of t, the unix time in seconds
+func Timestamp(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// Sign returns the signature of the timestamp and the payload
+//
+// Usage:
+//
+//         timestamp := signature.Timestamp(time.Now())
+//         req.Header.Set(signature.TimestampHeader, timestamp)
+//         req.Header.Set(signature.Header, signature.Sign(secret, timestamp, payload))
+//
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	return prefix + hex.EncodeToString(sum(secret, timestamp, payload))
+}
+
+// Valid returns true if signature is the signature of the timestamp and the
+// payload, and the timestamp is within the Tolerance of now
+func Valid(secret, payload []byte, timestamp, signature string, now time.Time) bool {
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > Tolerance || d < -Tolerance {
+		return false
+	}
+
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, sum(secret, timestamp, payload))
+}
+
+func sum(secret []byte, timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
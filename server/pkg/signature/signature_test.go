@@ -0,0 +1,58 @@
+package signature_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unders/mockingbird/server/pkg/signature"
+)
+
+var (
+	secret    = []byte("secret")
+	payload   = []byte(`{"service":"api"}`)
+	timestamp = "1546398245"
+	now       = time.Unix(1546398245, 0)
+)
+
+func TestSign(t *testing.T) {
+	// printf '%s' '1546398245.{"service":"api"}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=f1911da29a9f4e13a9ef54bc82926bb8eaaab13038e549df481f2c95bc7488fc"
+	if got := signature.Sign(secret, timestamp, payload); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	if got := signature.Timestamp(now); timestamp != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", timestamp, got)
+	}
+}
+
+func TestValid(t *testing.T) {
+	valid := signature.Sign(secret, timestamp, payload)
+
+	testCases := []struct {
+		name      string
+		timestamp string
+		signature string
+		now       time.Time
+		want      bool
+	}{
+		{name: "valid", timestamp: timestamp, signature: valid, now: now, want: true},
+		{name: "within the tolerance", timestamp: timestamp, signature: valid, now: now.Add(signature.Tolerance), want: true},
+		{name: "replayed after the tolerance", timestamp: timestamp, signature: valid, now: now.Add(signature.Tolerance + time.Second)},
+		{name: "from the future", timestamp: timestamp, signature: valid, now: now.Add(-signature.Tolerance - time.Second)},
+		{name: "changed timestamp", timestamp: "1546398246", signature: valid, now: now},
+		{name: "no timestamp", timestamp: "", signature: valid, now: now},
+		{name: "wrong secret", timestamp: timestamp, signature: signature.Sign([]byte("guess"), timestamp, payload), now: now},
+		{name: "no prefix", timestamp: timestamp, signature: "f1911da29a9f4e13a9ef54bc82926bb8eaaab13038e549df481f2c95bc7488fc", now: now},
+		{name: "not hex", timestamp: timestamp, signature: "sha256=not-hex", now: now},
+		{name: "no signature", timestamp: timestamp, signature: "", now: now},
+	}
+
+	for _, tc := range testCases {
+		if got := signature.Valid(secret, payload, tc.timestamp, tc.signature, tc.now); tc.want != got {
+			t.Errorf("%s\nWant: %t\n Got: %t\n", tc.name, tc.want, got)
+		}
+	}
+}
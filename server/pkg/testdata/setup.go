@@ -12,7 +12,3 @@ import "flag"
 //          go test github.com/unders/mockingbird/pkg/html -update
 //
 var Update = flag.Bool("update", false, "update golden files")
-
-func init() {
-	flag.Parse()
-}
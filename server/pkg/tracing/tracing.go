@@ -0,0 +1,116 @@
+// Package tracing exports OpenCensus spans as JSON and passes the trace
+// context on to child processes:
+//
+//      trace.RegisterExporter(tracing.NewJSONExporter(os.Stdout))
+//      cmd.Env = append(os.Environ(), tracing.Env(span.SpanContext())...)
+//
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// JSONExporter writes each span as a line of JSON, e.g: to stdout for local use
+type JSONExporter struct {
+	sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONExporter returns a JSONExporter that writes to w
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{enc: json.NewEncoder(w)}
+}
+
+// Verifies that JSONExporter implements trace.Exporter interface
+var _ trace.Exporter = &JSONExporter{}
+
+// ExportSpan implements trace.Exporter
+//
+// Usage:
+//
+//        {"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7",
+//         "name":"mockingbird.run","start":"2019-01-02T03:04:05Z","duration_ms":90000,
+//         "attributes":{"mockingbird.test_suite":"all:test"},"status":{"code":2,"message":"failed"}}
+//
+func (e *JSONExporter) ExportSpan(s *trace.SpanData) {
+	span := jsonSpan{
+		TraceID:    s.TraceID.String(),
+		SpanID:     s.SpanID.String(),
+		Name:       s.Name,
+		Start:      s.StartTime.UTC(),
+		End:        s.EndTime.UTC(),
+		DurationMS: s.EndTime.Sub(s.StartTime).Seconds() * 1000,
+		Attributes: s.Attributes,
+		Status:     jsonStatus{Code: s.Code, Message: s.Message},
+	}
+	if s.ParentSpanID != (trace.SpanID{}) {
+		span.ParentSpanID = s.ParentSpanID.String()
+	}
+	for _, a := range s.Annotations {
+		span.Annotations = append(span.Annotations, jsonAnnotation{Time: a.Time.UTC(), Message: a.Message, Attributes: a.Attributes})
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	_ = e.enc.Encode(span)
+}
+
+// Env returns the environment variables that pass the span context on to a
+// child process, in the W3C Trace Context and the B3 formats
+//
+// Usage:
+//
+//        TRACEPARENT=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+//        B3=4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1
+//        X_B3_TRACEID=4bf92f3577b34da6a3ce929d0e0e4736
+//        X_B3_SPANID=00f067aa0ba902b7
+//        X_B3_SAMPLED=1
+//
+func Env(sc trace.SpanContext) []string {
+	flags, sampled := "00", "0"
+	if sc.IsSampled() {
+		flags, sampled = "01", "1"
+	}
+
+	return []string{
+		fmt.Sprintf("TRACEPARENT=00-%s-%s-%s", sc.TraceID, sc.SpanID, flags),
+		fmt.Sprintf("B3=%s-%s-%s", sc.TraceID, sc.SpanID, sampled),
+		fmt.Sprintf("X_B3_TRACEID=%s", sc.TraceID),
+		fmt.Sprintf("X_B3_SPANID=%s", sc.SpanID),
+		fmt.Sprintf("X_B3_SAMPLED=%s", sampled),
+	}
+}
+
+//
+// PRIVATE
+//
+
+type jsonSpan struct {
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Name         string                 `json:"name"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	DurationMS   float64                `json:"duration_ms"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Annotations  []jsonAnnotation       `json:"annotations,omitempty"`
+	Status       jsonStatus             `json:"status"`
+}
+
+type jsonAnnotation struct {
+	Time       time.Time              `json:"time"`
+	Message    string                 `json:"message"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+type jsonStatus struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,86 @@
+package tracing_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/unders/mockingbird/server/pkg/tracing"
+)
+
+var spanContext = trace.SpanContext{
+	TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+	SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+}
+
+func TestEnv(t *testing.T) {
+	sampled := spanContext
+	sampled.TraceOptions = 1
+
+	testCases := []struct {
+		sc   trace.SpanContext
+		want []string
+	}{
+		{
+			sc: sampled,
+			want: []string{
+				"TRACEPARENT=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"B3=4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+				"X_B3_TRACEID=4bf92f3577b34da6a3ce929d0e0e4736",
+				"X_B3_SPANID=00f067aa0ba902b7",
+				"X_B3_SAMPLED=1",
+			},
+		},
+		{
+			sc: spanContext,
+			want: []string{
+				"TRACEPARENT=00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+				"B3=4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0",
+				"X_B3_TRACEID=4bf92f3577b34da6a3ce929d0e0e4736",
+				"X_B3_SPANID=00f067aa0ba902b7",
+				"X_B3_SAMPLED=0",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		if got := tracing.Env(tc.sc); !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("\nWant: %v\n Got: %v\n", tc.want, got)
+		}
+	}
+}
+
+func TestJSONExporter_ExportSpan_WritesALinePerSpan(t *testing.T) {
+	start := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	e := tracing.NewJSONExporter(&buf)
+	e.ExportSpan(&trace.SpanData{
+		SpanContext: spanContext,
+		Name:        "mockingbird.run",
+		StartTime:   start,
+		EndTime:     start.Add(90 * time.Second),
+		Attributes:  map[string]interface{}{"mockingbird.test_suite": "all:test"},
+		Status:      trace.Status{Code: trace.StatusCodeUnknown, Message: "failed"},
+	})
+	e.ExportSpan(&trace.SpanData{
+		SpanContext:  spanContext,
+		ParentSpanID: trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		Name:         "mockingbird.queue",
+		StartTime:    start,
+		EndTime:      start.Add(1500 * time.Millisecond),
+	})
+
+	want := `{"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7",` +
+		`"name":"mockingbird.run","start":"2019-01-02T03:04:05Z","end":"2019-01-02T03:05:35Z","duration_ms":90000,` +
+		`"attributes":{"mockingbird.test_suite":"all:test"},"status":{"code":2,"message":"failed"}}` + "\n" +
+		`{"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","parent_span_id":"0102030405060708",` +
+		`"name":"mockingbird.queue","start":"2019-01-02T03:04:05Z","end":"2019-01-02T03:04:06.5Z","duration_ms":1500,` +
+		`"status":{"code":0}}` + "\n"
+	if got := buf.String(); want != got {
+		t.Errorf("\nWant: %s\n Got: %s\n", want, got)
+	}
+}
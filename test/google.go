@@ -3,6 +3,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/magefile/mage/mg"
 	"github.com/magefile/mage/sh"
 )
@@ -11,5 +13,15 @@ type Google mg.Namespace
 
 // Test tests the google service
 func (Google) Test() error {
-	return sh.RunV("go", "test", "-count=1", "github.com/unders/mockingbird/test/service/google/...")
+	return sh.RunV("go", goTestArgs("github.com/unders/mockingbird/test/service/google/...")...)
+}
+
+// goTestArgs returns the go test arguments for pkg; when mockingbird
+// runs the test suite, MOCKINGBIRD_TEST_JSON=1 adds the -json flag
+func goTestArgs(pkg string) []string {
+	args := []string{"test", "-count=1"}
+	if os.Getenv("MOCKINGBIRD_TEST_JSON") == "1" {
+		args = append(args, "-json")
+	}
+	return append(args, pkg)
 }